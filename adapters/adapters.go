@@ -0,0 +1,10 @@
+package adapters
+
+import "github.com/axiomhq/axiom-go/axiom"
+
+// ErrorHandler is called by an adapter when a batch of events failed to
+// ingest, either completely (err is not nil and status is nil) or partially
+// (status carries all failures). It is passed the affected batch which is nil
+// for batches replayed from a spool. Adapters report failures to stderr, unless
+// an ErrorHandler is configured.
+type ErrorHandler func(err error, status *axiom.IngestStatus, events []axiom.Event)
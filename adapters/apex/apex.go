@@ -2,14 +2,18 @@ package apex
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/apex/log"
 
+	"github.com/axiomhq/axiom-go/adapters"
 	"github.com/axiomhq/axiom-go/axiom"
 )
 
@@ -54,8 +58,28 @@ func SetDataset(datasetName string) Option {
 	}
 }
 
+// SetErrorHandler specifies a function that is called when a batch of events
+// failed to ingest, instead of reporting the failure to stderr.
+func SetErrorHandler(handler adapters.ErrorHandler) Option {
+	return func(h *Handler) error {
+		h.errorHandler = handler
+		return nil
+	}
+}
+
+// SetFallbackWriter specifies a writer that events dropped because the queue
+// is full are written to as JSON, one event per line, e.g. `os.Stderr`. Only
+// takes effect in combination with an overflow policy that drops events.
+func SetFallbackWriter(w io.Writer) Option {
+	return func(h *Handler) error {
+		h.fallbackWriter = w
+		return nil
+	}
+}
+
 // SetIngestOptions specifies the ingestion options to use for ingesting the
-// logs.
+// logs. Their `Compression` configures how logs are compressed and defaults to
+// the one configured on the client.
 func SetIngestOptions(opts axiom.IngestOptions) Option {
 	return func(h *Handler) error {
 		h.ingestOptions = opts
@@ -63,18 +87,55 @@ func SetIngestOptions(opts axiom.IngestOptions) Option {
 	}
 }
 
+// SetOverflowPolicy specifies how the handler behaves when its queue is full
+// because events are logged faster than they can be ingested. Defaults to
+// `axiom.OverflowBlock` which blocks logging calls until there is room in the
+// queue. The other policies never block but drop events, which are counted by
+// `Dropped()` and written to the fallback writer, if one is set.
+func SetOverflowPolicy(policy axiom.OverflowPolicy) Option {
+	return func(h *Handler) error {
+		h.overflowPolicy = policy
+		return nil
+	}
+}
+
+// SetQueueSize specifies the maximum number of events held in memory while
+// waiting to be ingested. It is raised to the batch size of 1024, if lower.
+// Defaults to 4096.
+func SetQueueSize(n int) Option {
+	return func(h *Handler) error {
+		h.queueSize = n
+		return nil
+	}
+}
+
+// SetSpool specifies a spool that batches which failed to ingest because of a
+// temporary error are written to. Spooled batches are replayed once the Axiom
+// deployment is reachable again.
+func SetSpool(spool *axiom.Spool) Option {
+	return func(h *Handler) error {
+		h.spool = spool
+		return nil
+	}
+}
+
 // Handler implements a `log.Handler` used for shipping logs to Axiom.
 type Handler struct {
 	client      *axiom.Client
 	datasetName string
 
-	clientOptions []axiom.Option
-	ingestOptions axiom.IngestOptions
+	clientOptions  []axiom.Option
+	errorHandler   adapters.ErrorHandler
+	ingestOptions  axiom.IngestOptions
+	overflowPolicy axiom.OverflowPolicy
+	queueSize      int
+	spool          *axiom.Spool
 
-	eventCh   chan axiom.Event
-	cancel    context.CancelFunc
-	closeCh   chan struct{}
-	closeOnce sync.Once
+	ingester *axiom.Ingester
+
+	fallbackWriter io.Writer
+	fallbackMtx    sync.Mutex
+	failed         uint64
 }
 
 // New creates a new `Handler` configured to ingest logs to the Axiom deployment
@@ -91,10 +152,7 @@ type Handler struct {
 // A handler needs to be closed properly to make sure all logs are sent by
 // calling `Close()`.
 func New(options ...Option) (*Handler, error) {
-	handler := &Handler{
-		eventCh: make(chan axiom.Event, 1),
-		closeCh: make(chan struct{}),
-	}
+	handler := &Handler{}
 
 	// Apply supplied options.
 	for _, option := range options {
@@ -119,10 +177,21 @@ func New(options ...Option) (*Handler, error) {
 		}
 	}
 
-	// Run background scheduler.
-	var ctx context.Context
-	ctx, handler.cancel = context.WithCancel(context.Background())
-	go handler.run(ctx, handler.closeCh)
+	// Create the ingester which batches the events and sends them in the
+	// background.
+	var err error
+	if handler.ingester, err = axiom.NewIngester(handler.client, handler.datasetName,
+		axiom.SetIngestOptions(handler.ingestOptions),
+		axiom.SetBatchSize(batchSize),
+		axiom.SetFlushInterval(sendInterval),
+		axiom.SetErrorHandler(handler.handleError),
+		axiom.SetSpool(handler.spool),
+		axiom.SetQueueSize(handler.queueSize),
+		axiom.SetOverflowPolicy(handler.overflowPolicy),
+		axiom.SetDropHandler(handler.handleDrop),
+	); err != nil {
+		return nil, err
+	}
 
 	return handler, nil
 }
@@ -130,11 +199,10 @@ func New(options ...Option) (*Handler, error) {
 // Close the handler and make sure all events are flushed. Closing the handler
 // renders it unusable for further use.
 func (h *Handler) Close() {
-	h.closeOnce.Do(func() {
-		close(h.eventCh)
-		h.cancel()
-		<-h.closeCh
-	})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_ = h.ingester.Close(ctx)
 }
 
 // HandleLog implements `log.Handler`.
@@ -151,60 +219,47 @@ func (h *Handler) HandleLog(entry *log.Entry) error {
 	event["severity"] = entry.Level.String()
 	event["message"] = entry.Message
 
-	h.eventCh <- event
-
-	return nil
+	return h.ingester.Ingest(context.Background(), event)
 }
 
-func (h *Handler) run(ctx context.Context, closeCh chan struct{}) {
-	defer close(closeCh)
-
-	t := time.NewTicker(sendInterval)
-	defer t.Stop()
-
-	events := make([]axiom.Event, 0, batchSize)
+// Dropped returns the number of events dropped because the queue was full.
+func (h *Handler) Dropped() uint64 {
+	return h.ingester.Dropped()
+}
 
-	defer func() {
-		flushCtx, flushCancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer flushCancel()
-		h.ingest(flushCtx, events)
-	}()
+// Failed returns the number of events that failed to ingest.
+func (h *Handler) Failed() uint64 {
+	return atomic.LoadUint64(&h.failed)
+}
 
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-t.C:
-			if len(events) == 0 {
-				continue
-			}
-		case event, ok := <-h.eventCh:
-			if !ok {
-				continue
-			}
+func (h *Handler) handleDrop(event axiom.Event) {
+	if h.fallbackWriter == nil {
+		return
+	}
 
-			events = append(events, event)
+	b, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
 
-			if len(events) < batchSize {
-				continue
-			}
-		}
+	h.fallbackMtx.Lock()
+	defer h.fallbackMtx.Unlock()
 
-		h.ingest(ctx, events)
+	_, _ = h.fallbackWriter.Write(append(b, '\n'))
+}
 
-		// Clear batch buffer.
-		// TODO(lukasmalkmus): In the future we might want to implement some
-		// kind of backoff and retry mechanism.
-		events = make([]axiom.Event, 0, batchSize)
+func (h *Handler) handleError(err error, res *axiom.IngestStatus, events []axiom.Event) {
+	if err != nil {
+		atomic.AddUint64(&h.failed, uint64(len(events)))
+	} else if res.Failed > 0 {
+		atomic.AddUint64(&h.failed, res.Failed)
 	}
-}
 
-func (h *Handler) ingest(ctx context.Context, events []axiom.Event) {
-	if len(events) == 0 {
+	if h.errorHandler != nil {
+		h.errorHandler(err, res, events)
 		return
 	}
 
-	res, err := h.client.Datasets.IngestEvents(ctx, h.datasetName, h.ingestOptions, events...)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "failed to ingest batch of %d events: %s\n", len(events), err)
 	} else if res.Failed > 0 {
@@ -2,6 +2,7 @@ package apex
 
 import (
 	"bufio"
+	"bytes"
 	"compress/gzip"
 	"encoding/json"
 	"fmt"
@@ -62,7 +63,7 @@ func TestHandler(t *testing.T) {
 		_, _ = w.Write([]byte("{}"))
 	}
 
-	logger, teardown := setup(t, hf)
+	logger, _, teardown := setup(t, hf)
 	defer teardown()
 
 	logger.
@@ -90,7 +91,7 @@ func TestHandler_FlushFullBatch(t *testing.T) {
 		_, _ = w.Write([]byte("{}"))
 	}
 
-	logger, teardown := setup(t, hf)
+	logger, _, teardown := setup(t, hf)
 	defer teardown()
 
 	for i := 0; i <= 1024; i++ {
@@ -110,11 +111,124 @@ func TestHandler_FlushFullBatch(t *testing.T) {
 	assert.EqualValues(t, 1025, atomic.LoadUint64(&lines))
 }
 
+func TestHandler_OverflowPolicy(t *testing.T) {
+	const total = 3000
+
+	var lines uint64
+	release := make(chan struct{})
+	hf := func(w http.ResponseWriter, r *http.Request) {
+		<-release
+
+		gzr, err := gzip.NewReader(r.Body)
+		require.NoError(t, err)
+
+		s := bufio.NewScanner(gzr)
+		for s.Scan() {
+			atomic.AddUint64(&lines, 1)
+		}
+		assert.NoError(t, s.Err())
+
+		_, _ = w.Write([]byte("{}"))
+	}
+
+	var fallback bytes.Buffer
+	logger, handler, teardown := setup(t, hf,
+		SetOverflowPolicy(axiom.OverflowDropNewest),
+		SetQueueSize(1024),
+		SetFallbackWriter(&fallback),
+	)
+	defer teardown()
+
+	// The server blocks, so logging must not.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < total; i++ {
+			logger.Info("my message")
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("logging blocked")
+	}
+
+	close(release)
+	handler.Close()
+
+	dropped := handler.Dropped()
+	assert.NotZero(t, dropped)
+	assert.EqualValues(t, total, atomic.LoadUint64(&lines)+dropped)
+	assert.EqualValues(t, dropped, bytes.Count(fallback.Bytes(), []byte("\n")))
+	assert.Zero(t, handler.Failed())
+}
+
+func TestHandler_Failed(t *testing.T) {
+	hf := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+
+	logger, handler, teardown := setup(t, hf)
+	defer teardown()
+
+	for i := 0; i < 10; i++ {
+		logger.Info("my message")
+	}
+
+	handler.Close()
+
+	assert.EqualValues(t, 10, handler.Failed())
+	assert.Zero(t, handler.Dropped())
+}
+
+func TestHandler_ErrorHandler(t *testing.T) {
+	hf := func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = fmt.Fprint(w, `{
+			"ingested": 0,
+			"failed": 2,
+			"failures": [
+				{"timestamp": "2022-01-01T00:00:00Z", "error": "first error"},
+				{"timestamp": "2022-01-01T00:00:01Z", "error": "second error"}
+			]
+		}`)
+	}
+
+	var (
+		calls  uint64
+		err    error
+		status *axiom.IngestStatus
+		events []axiom.Event
+	)
+	logger, handler, teardown := setup(t, hf, SetErrorHandler(func(e error, s *axiom.IngestStatus, b []axiom.Event) {
+		atomic.AddUint64(&calls, 1)
+		err, status, events = e, s, b
+	}))
+	defer teardown()
+
+	logger.Info("first")
+	logger.Info("second")
+
+	handler.Close()
+
+	require.EqualValues(t, 1, atomic.LoadUint64(&calls))
+	assert.NoError(t, err)
+	if assert.NotNil(t, status) && assert.Len(t, status.Failures, 2) {
+		assert.Equal(t, "first error", status.Failures[0].Error)
+		assert.Equal(t, "second error", status.Failures[1].Error)
+	}
+	if assert.Len(t, events, 2) {
+		assert.Equal(t, "first", events[0]["message"])
+		assert.Equal(t, "second", events[1]["message"])
+	}
+	assert.EqualValues(t, 2, handler.Failed())
+}
+
 // setup sets up a test HTTP server along with a apex logger that is
 // configured to talk to that test server through an Axiom handler. Tests should
 // pass a handler function which provides the response for the API method being
 // tested.
-func setup(t *testing.T, h http.HandlerFunc) (*log.Logger, func()) {
+func setup(t *testing.T, h http.HandlerFunc, options ...Option) (*log.Logger, *Handler, func()) {
 	t.Helper()
 
 	srv := httptest.NewServer(h)
@@ -126,10 +240,10 @@ func setup(t *testing.T, h http.HandlerFunc) (*log.Logger, func()) {
 	)
 	require.NoError(t, err)
 
-	handler, err := New(
+	handler, err := New(append([]Option{
 		SetClient(client),
 		SetDataset("test"),
-	)
+	}, options...)...)
 	require.NoError(t, err)
 
 	logger := &log.Logger{
@@ -137,7 +251,7 @@ func setup(t *testing.T, h http.HandlerFunc) (*log.Logger, func()) {
 		Level:   log.InfoLevel,
 	}
 
-	return logger, func() { handler.Close(); srv.Close() }
+	return logger, handler, func() { handler.Close(); srv.Close() }
 }
 
 // JSONEqExp is like assert.JSONEq() but excludes the given fields.
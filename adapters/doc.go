@@ -1,5 +1,5 @@
 // Package adapters provides packages which implement integration into well
-// known Go logging libraries. It also provides a test harness that can be used
-// to easily test these and other adapters against a real world Axiom
-// deployment.
+// known Go logging libraries. It holds the types shared by these adapters, like
+// the ErrorHandler, and also provides a test harness that can be used to easily
+// test these and other adapters against a real world Axiom deployment.
 package adapters
@@ -2,14 +2,18 @@ package logrus
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/sirupsen/logrus"
 
+	"github.com/axiomhq/axiom-go/adapters"
 	"github.com/axiomhq/axiom-go/axiom"
 )
 
@@ -54,8 +58,28 @@ func SetDataset(datasetName string) Option {
 	}
 }
 
+// SetErrorHandler specifies a function that is called when a batch of events
+// failed to ingest, instead of reporting the failure to stderr.
+func SetErrorHandler(handler adapters.ErrorHandler) Option {
+	return func(h *Hook) error {
+		h.errorHandler = handler
+		return nil
+	}
+}
+
+// SetFallbackWriter specifies a writer that events dropped because the queue
+// is full are written to as JSON, one event per line, e.g. `os.Stderr`. Only
+// takes effect in combination with an overflow policy that drops events.
+func SetFallbackWriter(w io.Writer) Option {
+	return func(h *Hook) error {
+		h.fallbackWriter = w
+		return nil
+	}
+}
+
 // SetIngestOptions specifies the ingestion options to use for ingesting the
-// logs.
+// logs. Their `Compression` configures how logs are compressed and defaults to
+// the one configured on the client.
 func SetIngestOptions(opts axiom.IngestOptions) Option {
 	return func(h *Hook) error {
 		h.ingestOptions = opts
@@ -72,19 +96,56 @@ func SetLevels(levels ...logrus.Level) Option {
 	}
 }
 
+// SetOverflowPolicy specifies how the hook behaves when its queue is full
+// because events are logged faster than they can be ingested. Defaults to
+// `axiom.OverflowBlock` which blocks logging calls until there is room in the
+// queue. The other policies never block but drop events, which are counted by
+// `Dropped()` and written to the fallback writer, if one is set.
+func SetOverflowPolicy(policy axiom.OverflowPolicy) Option {
+	return func(h *Hook) error {
+		h.overflowPolicy = policy
+		return nil
+	}
+}
+
+// SetQueueSize specifies the maximum number of events held in memory while
+// waiting to be ingested. It is raised to the batch size of 1024, if lower.
+// Defaults to 4096.
+func SetQueueSize(n int) Option {
+	return func(h *Hook) error {
+		h.queueSize = n
+		return nil
+	}
+}
+
+// SetSpool specifies a spool that batches which failed to ingest because of a
+// temporary error are written to. Spooled batches are replayed once the Axiom
+// deployment is reachable again.
+func SetSpool(spool *axiom.Spool) Option {
+	return func(h *Hook) error {
+		h.spool = spool
+		return nil
+	}
+}
+
 // Hook implements a `logrus.Hook` used for shipping logs to Axiom.
 type Hook struct {
 	client      *axiom.Client
 	datasetName string
 
-	clientOptions []axiom.Option
-	ingestOptions axiom.IngestOptions
-	levels        []logrus.Level
+	clientOptions  []axiom.Option
+	errorHandler   adapters.ErrorHandler
+	ingestOptions  axiom.IngestOptions
+	overflowPolicy axiom.OverflowPolicy
+	queueSize      int
+	spool          *axiom.Spool
+	levels         []logrus.Level
 
-	eventCh   chan axiom.Event
-	cancel    context.CancelFunc
-	closeCh   chan struct{}
-	closeOnce sync.Once
+	ingester *axiom.Ingester
+
+	fallbackWriter io.Writer
+	fallbackMtx    sync.Mutex
+	failed         uint64
 }
 
 // New creates a new `Hook` configured to ingest logs to the Axiom deployment
@@ -103,9 +164,6 @@ type Hook struct {
 func New(options ...Option) (*Hook, error) {
 	hook := &Hook{
 		levels: logrus.AllLevels,
-
-		eventCh: make(chan axiom.Event, 1),
-		closeCh: make(chan struct{}),
 	}
 
 	// Apply supplied options.
@@ -131,10 +189,21 @@ func New(options ...Option) (*Hook, error) {
 		}
 	}
 
-	// Run background scheduler.
-	var ctx context.Context
-	ctx, hook.cancel = context.WithCancel(context.Background())
-	go hook.run(ctx, hook.closeCh)
+	// Create the ingester which batches the events and sends them in the
+	// background.
+	var err error
+	if hook.ingester, err = axiom.NewIngester(hook.client, hook.datasetName,
+		axiom.SetIngestOptions(hook.ingestOptions),
+		axiom.SetBatchSize(batchSize),
+		axiom.SetFlushInterval(sendInterval),
+		axiom.SetErrorHandler(hook.handleError),
+		axiom.SetSpool(hook.spool),
+		axiom.SetQueueSize(hook.queueSize),
+		axiom.SetOverflowPolicy(hook.overflowPolicy),
+		axiom.SetDropHandler(hook.handleDrop),
+	); err != nil {
+		return nil, err
+	}
 
 	return hook, nil
 }
@@ -143,11 +212,10 @@ func New(options ...Option) (*Hook, error) {
 // registered with `logrus.RegisterExitHandler(h.Close)`. Closing the hook
 // renders it unusable for further use.
 func (h *Hook) Close() {
-	h.closeOnce.Do(func() {
-		close(h.eventCh)
-		h.cancel()
-		<-h.closeCh
-	})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_ = h.ingester.Close(ctx)
 }
 
 // Levels implements `logrus.Hook`.
@@ -169,60 +237,47 @@ func (h *Hook) Fire(entry *logrus.Entry) error {
 	event["severity"] = entry.Level.String()
 	event["message"] = entry.Message
 
-	h.eventCh <- event
-
-	return nil
+	return h.ingester.Ingest(context.Background(), event)
 }
 
-func (h *Hook) run(ctx context.Context, closeCh chan struct{}) {
-	defer close(closeCh)
-
-	t := time.NewTicker(sendInterval)
-	defer t.Stop()
-
-	events := make([]axiom.Event, 0, batchSize)
+// Dropped returns the number of events dropped because the queue was full.
+func (h *Hook) Dropped() uint64 {
+	return h.ingester.Dropped()
+}
 
-	defer func() {
-		flushCtx, flushCancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer flushCancel()
-		h.ingest(flushCtx, events)
-	}()
+// Failed returns the number of events that failed to ingest.
+func (h *Hook) Failed() uint64 {
+	return atomic.LoadUint64(&h.failed)
+}
 
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-t.C:
-			if len(events) == 0 {
-				continue
-			}
-		case event, ok := <-h.eventCh:
-			if !ok {
-				continue
-			}
+func (h *Hook) handleDrop(event axiom.Event) {
+	if h.fallbackWriter == nil {
+		return
+	}
 
-			events = append(events, event)
+	b, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
 
-			if len(events) < batchSize {
-				continue
-			}
-		}
+	h.fallbackMtx.Lock()
+	defer h.fallbackMtx.Unlock()
 
-		h.ingest(ctx, events)
+	_, _ = h.fallbackWriter.Write(append(b, '\n'))
+}
 
-		// Clear batch buffer.
-		// TODO(lukasmalkmus): In the future we might want to implement some
-		// kind of backoff and retry mechanism.
-		events = make([]axiom.Event, 0, batchSize)
+func (h *Hook) handleError(err error, res *axiom.IngestStatus, events []axiom.Event) {
+	if err != nil {
+		atomic.AddUint64(&h.failed, uint64(len(events)))
+	} else if res.Failed > 0 {
+		atomic.AddUint64(&h.failed, res.Failed)
 	}
-}
 
-func (h *Hook) ingest(ctx context.Context, events []axiom.Event) {
-	if len(events) == 0 {
+	if h.errorHandler != nil {
+		h.errorHandler(err, res, events)
 		return
 	}
 
-	res, err := h.client.Datasets.IngestEvents(ctx, h.datasetName, h.ingestOptions, events...)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "failed to ingest batch of %d events: %s\n", len(events), err)
 	} else if res.Failed > 0 {
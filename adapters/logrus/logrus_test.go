@@ -2,6 +2,7 @@ package logrus
 
 import (
 	"bufio"
+	"bytes"
 	"compress/gzip"
 	"fmt"
 	"io"
@@ -62,7 +63,7 @@ func TestHook(t *testing.T) {
 		_, _ = w.Write([]byte("{}"))
 	}
 
-	logger, teardown := setup(t, hf)
+	logger, _, teardown := setup(t, hf)
 	defer teardown()
 
 	logger.
@@ -91,7 +92,7 @@ func TestHook_FlushFullBatch(t *testing.T) {
 		_, _ = w.Write([]byte("{}"))
 	}
 
-	logger, teardown := setup(t, hf)
+	logger, _, teardown := setup(t, hf)
 	defer teardown()
 
 	for i := 0; i <= 1024; i++ {
@@ -111,11 +112,124 @@ func TestHook_FlushFullBatch(t *testing.T) {
 	assert.EqualValues(t, 1025, atomic.LoadUint64(&lines))
 }
 
+func TestHook_OverflowPolicy(t *testing.T) {
+	const total = 3000
+
+	var lines uint64
+	release := make(chan struct{})
+	hf := func(w http.ResponseWriter, r *http.Request) {
+		<-release
+
+		gzr, err := gzip.NewReader(r.Body)
+		require.NoError(t, err)
+
+		s := bufio.NewScanner(gzr)
+		for s.Scan() {
+			atomic.AddUint64(&lines, 1)
+		}
+		assert.NoError(t, s.Err())
+
+		_, _ = w.Write([]byte("{}"))
+	}
+
+	var fallback bytes.Buffer
+	logger, hook, teardown := setup(t, hf,
+		SetOverflowPolicy(axiom.OverflowDropNewest),
+		SetQueueSize(1024),
+		SetFallbackWriter(&fallback),
+	)
+	defer teardown()
+
+	// The server blocks, so logging must not.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < total; i++ {
+			logger.Info("my message")
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("logging blocked")
+	}
+
+	close(release)
+	hook.Close()
+
+	dropped := hook.Dropped()
+	assert.NotZero(t, dropped)
+	assert.EqualValues(t, total, atomic.LoadUint64(&lines)+dropped)
+	assert.EqualValues(t, dropped, bytes.Count(fallback.Bytes(), []byte("\n")))
+	assert.Zero(t, hook.Failed())
+}
+
+func TestHook_Failed(t *testing.T) {
+	hf := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+
+	logger, hook, teardown := setup(t, hf)
+	defer teardown()
+
+	for i := 0; i < 10; i++ {
+		logger.Info("my message")
+	}
+
+	hook.Close()
+
+	assert.EqualValues(t, 10, hook.Failed())
+	assert.Zero(t, hook.Dropped())
+}
+
+func TestHook_ErrorHandler(t *testing.T) {
+	hf := func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = fmt.Fprint(w, `{
+			"ingested": 0,
+			"failed": 2,
+			"failures": [
+				{"timestamp": "2022-01-01T00:00:00Z", "error": "first error"},
+				{"timestamp": "2022-01-01T00:00:01Z", "error": "second error"}
+			]
+		}`)
+	}
+
+	var (
+		calls  uint64
+		err    error
+		status *axiom.IngestStatus
+		events []axiom.Event
+	)
+	logger, hook, teardown := setup(t, hf, SetErrorHandler(func(e error, s *axiom.IngestStatus, b []axiom.Event) {
+		atomic.AddUint64(&calls, 1)
+		err, status, events = e, s, b
+	}))
+	defer teardown()
+
+	logger.Info("first")
+	logger.Info("second")
+
+	hook.Close()
+
+	require.EqualValues(t, 1, atomic.LoadUint64(&calls))
+	assert.NoError(t, err)
+	if assert.NotNil(t, status) && assert.Len(t, status.Failures, 2) {
+		assert.Equal(t, "first error", status.Failures[0].Error)
+		assert.Equal(t, "second error", status.Failures[1].Error)
+	}
+	if assert.Len(t, events, 2) {
+		assert.Equal(t, "first", events[0]["message"])
+		assert.Equal(t, "second", events[1]["message"])
+	}
+	assert.EqualValues(t, 2, hook.Failed())
+}
+
 // setup sets up a test HTTP server along with a logrus logger that is
 // configured to talk to that test server through an Axiom hook. Tests should
 // pass a handler function which provides the response for the API method being
 // tested.
-func setup(t *testing.T, h http.HandlerFunc) (*logrus.Logger, func()) {
+func setup(t *testing.T, h http.HandlerFunc, options ...Option) (*logrus.Logger, *Hook, func()) {
 	t.Helper()
 
 	srv := httptest.NewServer(h)
@@ -127,10 +241,10 @@ func setup(t *testing.T, h http.HandlerFunc) (*logrus.Logger, func()) {
 	)
 	require.NoError(t, err)
 
-	hook, err := New(
+	hook, err := New(append([]Option{
 		SetClient(client),
 		SetDataset("test"),
-	)
+	}, options...)...)
 	require.NoError(t, err)
 
 	logger := logrus.New()
@@ -139,5 +253,5 @@ func setup(t *testing.T, h http.HandlerFunc) (*logrus.Logger, func()) {
 	// We don't want output in tests.
 	logger.Out = ioutil.Discard
 
-	return logger, func() { hook.Close(); srv.Close() }
+	return logger, hook, func() { hook.Close(); srv.Close() }
 }
@@ -0,0 +1,238 @@
+package otel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/axiomhq/axiom-go/axiom"
+)
+
+var _ sdktrace.SpanExporter = (*SpanExporter)(nil)
+
+// ErrMissingDatasetName is raised when a dataset name is not provided. Set it
+// manually using the SetDataset option or export `AXIOM_DATASET`.
+var ErrMissingDatasetName = errors.New("missing dataset name")
+
+// An Option modifies the behaviour of the Axiom span exporter.
+type Option func(*SpanExporter) error
+
+// SetClient specifies the Axiom client to use for ingesting the spans.
+func SetClient(client *axiom.Client) Option {
+	return func(e *SpanExporter) error {
+		e.client = client
+		return nil
+	}
+}
+
+// SetClientOptions specifies the Axiom client options to pass to
+// `axiom.NewClient()`. `axiom.NewClient()` is only called if no client was
+// specified by the `SetClient` option.
+func SetClientOptions(options ...axiom.Option) Option {
+	return func(e *SpanExporter) error {
+		e.clientOptions = options
+		return nil
+	}
+}
+
+// SetDataset specifies the dataset to ingest the spans into. Can also be
+// specified using the `AXIOM_DATASET` environment variable.
+func SetDataset(datasetName string) Option {
+	return func(e *SpanExporter) error {
+		e.datasetName = datasetName
+		return nil
+	}
+}
+
+// SetIngestOptions specifies the ingestion options to use for ingesting the
+// spans. Their `Compression` configures how spans are compressed and defaults
+// to the one configured on the client.
+func SetIngestOptions(opts axiom.IngestOptions) Option {
+	return func(e *SpanExporter) error {
+		e.ingestOptions = opts
+		return nil
+	}
+}
+
+// SpanExporter implements a `trace.SpanExporter` of the OpenTelemetry SDK used
+// for shipping spans to Axiom. Each span is converted into an event:
+//
+//	{
+//		"_time": "2022-01-01T00:00:00.000000000Z", // Start time of the span
+//		"end_time": "2022-01-01T00:00:00.100000000Z",
+//		"duration": 100000000, // In nanoseconds
+//		"name": "GET /",
+//		"kind": "server",
+//		"trace_id": "...",
+//		"span_id": "...",
+//		"parent_span_id": "...", // Omitted for root spans
+//		"status": {"code": "Error", "description": "..."},
+//		"attributes": {"http.method": "GET"},
+//		"resource": {"service.name": "my-service"},
+//		"scope": {"name": "my-library", "version": "v1.0.0"},
+//		"events": [{"name": "...", "time": "...", "attributes": {}}],
+//		"links": [{"trace_id": "...", "span_id": "...", "attributes": {}}]
+//	}
+//
+// Every batch of spans handed to the exporter, usually by a
+// `trace.BatchSpanProcessor`, is ingested with a single call to
+// `axiom.DatasetsService.IngestEvents()`.
+type SpanExporter struct {
+	client      *axiom.Client
+	datasetName string
+
+	clientOptions []axiom.Option
+	ingestOptions axiom.IngestOptions
+
+	stopMu  sync.RWMutex
+	stopped bool
+}
+
+// NewSpanExporter creates a new `SpanExporter` configured to ingest spans to
+// the Axiom deployment and dataset as specified by the environment. Refer to
+// `axiom.NewClient()` for more details on how configuring the Axiom deployment
+// works or pass the `SetClient()` option to pass a custom client or
+// `SetClientOptions()` to control the Axiom client creation. To specify the
+// dataset set `AXIOM_DATASET` or use the `SetDataset()` option.
+//
+// An API token with `axiom.CanIngest` permission is sufficient enough.
+//
+// Additional options can be supplied to configure the `SpanExporter`.
+//
+// Log records are shipped by the `LogExporter` of the separate
+// `github.com/axiomhq/axiom-go/adapters/otel/otellog` module, as the
+// OpenTelemetry logs SDK for Go requires Go 1.21 or greater.
+func NewSpanExporter(options ...Option) (*SpanExporter, error) {
+	exporter := &SpanExporter{}
+
+	// Apply supplied options.
+	for _, option := range options {
+		if err := option(exporter); err != nil {
+			return nil, err
+		}
+	}
+
+	// Create client, if not set.
+	if exporter.client == nil {
+		var err error
+		if exporter.client, err = axiom.NewClient(exporter.clientOptions...); err != nil {
+			return nil, err
+		}
+	}
+
+	// When the dataset name is not set, use `AXIOM_DATASET`.
+	if exporter.datasetName == "" {
+		exporter.datasetName = os.Getenv("AXIOM_DATASET")
+		if exporter.datasetName == "" {
+			return nil, ErrMissingDatasetName
+		}
+	}
+
+	return exporter, nil
+}
+
+// ExportSpans implements `trace.SpanExporter`. It ingests the given spans and
+// returns an error, if the request fails or any of the spans failed to ingest.
+func (e *SpanExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	e.stopMu.RLock()
+	stopped := e.stopped
+	e.stopMu.RUnlock()
+
+	if stopped || len(spans) == 0 {
+		return nil
+	}
+
+	events := make([]axiom.Event, len(spans))
+	for i, span := range spans {
+		events[i] = spanToEvent(span)
+	}
+
+	res, err := e.client.Datasets.IngestEvents(ctx, e.datasetName, e.ingestOptions, events...)
+	if err != nil {
+		return fmt.Errorf("failed to ingest batch of %d spans: %w", len(spans), err)
+	} else if res.Failed > 0 && len(res.Failures) > 0 {
+		return fmt.Errorf("%d of %d spans failed to ingest: %s",
+			res.Failed, len(spans), res.Failures[0].Error)
+	} else if res.Failed > 0 {
+		return fmt.Errorf("%d of %d spans failed to ingest", res.Failed, len(spans))
+	}
+
+	return nil
+}
+
+// Shutdown implements `trace.SpanExporter`. Spans exported after the exporter
+// has been shut down are discarded.
+func (e *SpanExporter) Shutdown(ctx context.Context) error {
+	e.stopMu.Lock()
+	e.stopped = true
+	e.stopMu.Unlock()
+
+	return ctx.Err()
+}
+
+func spanToEvent(span sdktrace.ReadOnlySpan) axiom.Event {
+	event := axiom.Event{
+		axiom.TimestampField: span.StartTime().Format(time.RFC3339Nano),
+
+		"end_time": span.EndTime().Format(time.RFC3339Nano),
+		"duration": span.EndTime().Sub(span.StartTime()).Nanoseconds(),
+		"name":     span.Name(),
+		"kind":     span.SpanKind().String(),
+		"trace_id": span.SpanContext().TraceID().String(),
+		"span_id":  span.SpanContext().SpanID().String(),
+		"status": map[string]interface{}{
+			"code":        span.Status().Code.String(),
+			"description": span.Status().Description,
+		},
+		"attributes": attributesToMap(span.Attributes()),
+		"resource":   attributesToMap(span.Resource().Attributes()),
+		"scope": map[string]interface{}{
+			"name":    span.InstrumentationLibrary().Name,
+			"version": span.InstrumentationLibrary().Version,
+		},
+	}
+
+	if parent := span.Parent(); parent.HasSpanID() {
+		event["parent_span_id"] = parent.SpanID().String()
+	}
+
+	if spanEvents := span.Events(); len(spanEvents) > 0 {
+		events := make([]map[string]interface{}, len(spanEvents))
+		for i, ev := range spanEvents {
+			events[i] = map[string]interface{}{
+				"name":       ev.Name,
+				"time":       ev.Time.Format(time.RFC3339Nano),
+				"attributes": attributesToMap(ev.Attributes),
+			}
+		}
+		event["events"] = events
+	}
+
+	if spanLinks := span.Links(); len(spanLinks) > 0 {
+		links := make([]map[string]interface{}, len(spanLinks))
+		for i, link := range spanLinks {
+			links[i] = map[string]interface{}{
+				"trace_id":   link.SpanContext.TraceID().String(),
+				"span_id":    link.SpanContext.SpanID().String(),
+				"attributes": attributesToMap(link.Attributes),
+			}
+		}
+		event["links"] = links
+	}
+
+	return event
+}
+
+func attributesToMap(attrs []attribute.KeyValue) map[string]interface{} {
+	m := make(map[string]interface{}, len(attrs))
+	for _, attr := range attrs {
+		m[string(attr.Key)] = attr.Value.AsInterface()
+	}
+	return m
+}
@@ -0,0 +1,36 @@
+package otel_test
+
+import (
+	"context"
+	"log"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	adapter "github.com/axiomhq/axiom-go/adapters/otel"
+)
+
+func Example() {
+	// Export `AXIOM_TOKEN`, `AXIOM_ORG_ID` (when using a personal token) and
+	// `AXIOM_DATASET` for Axiom Cloud.
+	// Export `AXIOM_URL`, `AXIOM_TOKEN` and `AXIOM_DATASET` for Axiom Selfhost.
+
+	// 1. Setup the Axiom span exporter.
+	exporter, err := adapter.NewSpanExporter()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// 2. Setup a tracer provider which batches the spans sent to the exporter.
+	tp := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter))
+
+	// 3. Have all spans flushed before the application exits.
+	defer func() {
+		if err := tp.Shutdown(context.Background()); err != nil {
+			log.Print(err)
+		}
+	}()
+
+	// 4. Trace ⚡
+	_, span := tp.Tracer("example").Start(context.Background(), "This is awesome!")
+	span.End()
+}
@@ -0,0 +1,40 @@
+//go:build integration
+// +build integration
+
+package otel_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/axiomhq/axiom-go/adapters"
+	adapter "github.com/axiomhq/axiom-go/adapters/otel"
+	"github.com/axiomhq/axiom-go/axiom"
+)
+
+func Test(t *testing.T) {
+	adapters.TestAdapter(t, "otel", func(ctx context.Context, dataset string, client *axiom.Client) {
+		exporter, err := adapter.NewSpanExporter(
+			adapter.SetClient(client),
+			adapter.SetDataset(dataset),
+		)
+		require.NoError(t, err)
+
+		tp := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter))
+		defer func() {
+			require.NoError(t, tp.Shutdown(ctx))
+		}()
+
+		tracer := tp.Tracer("axiom-go-adapter-test")
+
+		spanCtx, span := tracer.Start(ctx, "parent", trace.WithAttributes(attribute.String("mood", "hyped")))
+		_, child := tracer.Start(spanCtx, "child", trace.WithAttributes(attribute.String("mood", "worried")))
+		child.End()
+		span.End()
+	})
+}
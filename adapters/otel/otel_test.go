@@ -0,0 +1,168 @@
+package otel
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/axiomhq/axiom-go/axiom"
+	"github.com/axiomhq/axiom-go/axiom/axiomtest"
+)
+
+// TestNewSpanExporter makes sure NewSpanExporter() picks up the
+// `AXIOM_DATASET` environment variable.
+func TestNewSpanExporter(t *testing.T) {
+	os.Clearenv()
+
+	os.Setenv("AXIOM_TOKEN", "xaat-test")
+	os.Setenv("AXIOM_ORG_ID", "123")
+
+	exporter, err := NewSpanExporter()
+	require.ErrorIs(t, err, ErrMissingDatasetName)
+	require.Nil(t, exporter)
+
+	os.Setenv("AXIOM_DATASET", "test")
+
+	exporter, err = NewSpanExporter()
+	require.NoError(t, err)
+	require.NotNil(t, exporter)
+
+	assert.Equal(t, "test", exporter.datasetName)
+}
+
+func TestSpanExporter(t *testing.T) {
+	srv := axiomtest.NewServer()
+	defer srv.Close()
+
+	srv.CreateDataset("test")
+
+	client, err := srv.Client()
+	require.NoError(t, err)
+
+	exporter, err := NewSpanExporter(
+		SetClient(client),
+		SetDataset("test"),
+	)
+	require.NoError(t, err)
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSyncer(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", "my-service"))),
+	)
+	tracer := tp.Tracer("my-library")
+
+	start := time.Now().Add(-time.Minute).Truncate(time.Millisecond)
+
+	ctx, parent := tracer.Start(context.Background(), "parent",
+		trace.WithTimestamp(start),
+		trace.WithSpanKind(trace.SpanKindServer),
+	)
+	_, child := tracer.Start(ctx, "child",
+		trace.WithTimestamp(start.Add(time.Second)),
+		trace.WithAttributes(attribute.String("key", "value"), attribute.Int("count", 42)),
+	)
+	child.AddEvent("something happened", trace.WithAttributes(attribute.Bool("ok", false)))
+	child.SetStatus(codes.Error, "it broke")
+	child.End(trace.WithTimestamp(start.Add(2 * time.Second)))
+	parent.End(trace.WithTimestamp(start.Add(3 * time.Second)))
+
+	require.NoError(t, tp.Shutdown(context.Background()))
+
+	events := srv.Events("test")
+	require.Len(t, events, 2)
+
+	// Events are returned in ingestion order.
+	childEvent, parentEvent := events[0], events[1]
+
+	traceID := parent.SpanContext().TraceID().String()
+
+	assert.Equal(t, "parent", parentEvent["name"])
+	assert.Equal(t, "server", parentEvent["kind"])
+	assert.True(t, start.Equal(parentEvent[axiom.TimestampField].(time.Time)))
+	assert.EqualValues(t, 3*time.Second, parentEvent["duration"])
+	assert.Equal(t, traceID, parentEvent["trace_id"])
+	assert.Equal(t, parent.SpanContext().SpanID().String(), parentEvent["span_id"])
+	assert.NotContains(t, parentEvent, "parent_span_id")
+	assert.Equal(t, map[string]interface{}{"service.name": "my-service"}, parentEvent["resource"])
+	assert.Equal(t, map[string]interface{}{"name": "my-library", "version": ""}, parentEvent["scope"])
+
+	assert.Equal(t, "child", childEvent["name"])
+	assert.Equal(t, "internal", childEvent["kind"])
+	assert.True(t, start.Add(time.Second).Equal(childEvent[axiom.TimestampField].(time.Time)))
+	assert.Equal(t, traceID, childEvent["trace_id"])
+	assert.Equal(t, parent.SpanContext().SpanID().String(), childEvent["parent_span_id"])
+	assert.Equal(t, map[string]interface{}{"code": "Error", "description": "it broke"}, childEvent["status"])
+	assert.Equal(t, map[string]interface{}{"key": "value", "count": float64(42)}, childEvent["attributes"])
+	if assert.Len(t, childEvent["events"], 1) {
+		ev := childEvent["events"].([]interface{})[0].(map[string]interface{})
+		assert.Equal(t, "something happened", ev["name"])
+		assert.Equal(t, map[string]interface{}{"ok": false}, ev["attributes"])
+	}
+}
+
+func TestSpanExporter_Error(t *testing.T) {
+	srv := axiomtest.NewServer(axiomtest.SetEventValidator(func(string, axiom.Event) error {
+		return errors.New("invalid span")
+	}))
+	defer srv.Close()
+
+	srv.CreateDataset("test")
+
+	client, err := srv.Client()
+	require.NoError(t, err)
+
+	exporter, err := NewSpanExporter(
+		SetClient(client),
+		SetDataset("test"),
+	)
+	require.NoError(t, err)
+
+	tp := sdktrace.NewTracerProvider()
+	_, span := tp.Tracer("test").Start(context.Background(), "span")
+	span.End()
+
+	spans := []sdktrace.ReadOnlySpan{span.(sdktrace.ReadOnlySpan)}
+
+	err = exporter.ExportSpans(context.Background(), spans)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "1 of 1 spans failed to ingest: invalid span")
+	}
+
+	// Spans are discarded after shutdown.
+	require.NoError(t, exporter.Shutdown(context.Background()))
+	assert.NoError(t, exporter.ExportSpans(context.Background(), spans))
+}
+
+func TestSpanExporter_ErrorWithoutFailures(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"ingested":0,"failed":1}`))
+	}))
+	defer srv.Close()
+
+	exporter, err := NewSpanExporter(
+		SetClientOptions(axiom.SetNoEnv(), axiom.SetURL(srv.URL), axiom.SetAccessToken("xaat-test")),
+		SetDataset("test"),
+	)
+	require.NoError(t, err)
+
+	tp := sdktrace.NewTracerProvider()
+	_, span := tp.Tracer("test").Start(context.Background(), "span")
+	span.End()
+
+	// The server might not report any details about the failure.
+	err = exporter.ExportSpans(context.Background(), []sdktrace.ReadOnlySpan{span.(sdktrace.ReadOnlySpan)})
+	assert.EqualError(t, err, "1 of 1 spans failed to ingest")
+}
@@ -0,0 +1,4 @@
+// Package otellog provides an exporter for the OpenTelemetry logs SDK. It is a
+// separate module, as the logs SDK requires Go 1.21 or greater and a more
+// recent version of OpenTelemetry than the rest of Axiom Go.
+package otellog
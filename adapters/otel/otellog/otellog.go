@@ -0,0 +1,260 @@
+package otellog
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+
+	"github.com/axiomhq/axiom-go/axiom"
+)
+
+var _ sdklog.Exporter = (*LogExporter)(nil)
+
+// ErrMissingDatasetName is raised when a dataset name is not provided. Set it
+// manually using the SetDataset option or export `AXIOM_DATASET`.
+var ErrMissingDatasetName = errors.New("missing dataset name")
+
+// An Option modifies the behaviour of the Axiom log exporter.
+type Option func(*LogExporter) error
+
+// SetClient specifies the Axiom client to use for ingesting the logs.
+func SetClient(client *axiom.Client) Option {
+	return func(e *LogExporter) error {
+		e.client = client
+		return nil
+	}
+}
+
+// SetClientOptions specifies the Axiom client options to pass to
+// `axiom.NewClient()`. `axiom.NewClient()` is only called if no client was
+// specified by the `SetClient` option.
+func SetClientOptions(options ...axiom.Option) Option {
+	return func(e *LogExporter) error {
+		e.clientOptions = options
+		return nil
+	}
+}
+
+// SetDataset specifies the dataset to ingest the logs into. Can also be
+// specified using the `AXIOM_DATASET` environment variable.
+func SetDataset(datasetName string) Option {
+	return func(e *LogExporter) error {
+		e.datasetName = datasetName
+		return nil
+	}
+}
+
+// SetIngestOptions specifies the ingestion options to use for ingesting the
+// logs. Their `Compression` configures how logs are compressed and defaults to
+// the one configured on the client.
+func SetIngestOptions(opts axiom.IngestOptions) Option {
+	return func(e *LogExporter) error {
+		e.ingestOptions = opts
+		return nil
+	}
+}
+
+// LogExporter implements a `log.Exporter` of the OpenTelemetry logs SDK used
+// for shipping log records to Axiom. Each record is converted into an event:
+//
+//	{
+//		"_time": "2022-01-01T00:00:00.000000000Z", // Observed time, if unset
+//		"observed_time": "2022-01-01T00:00:00.000000000Z",
+//		"severity": "INFO", // Severity text, if set
+//		"severity_number": 9,
+//		"body": "GET / took 100ms",
+//		"trace_id": "...", // Omitted for records outside of a span
+//		"span_id": "...", // Omitted for records outside of a span
+//		"attributes": {"http.method": "GET"},
+//		"resource": {"service.name": "my-service"},
+//		"scope": {"name": "my-library", "version": "v1.0.0"}
+//	}
+//
+// Every batch of records handed to the exporter, usually by a
+// `log.BatchProcessor`, is ingested with a single call to
+// `axiom.DatasetsService.IngestEvents()`.
+type LogExporter struct {
+	client      *axiom.Client
+	datasetName string
+
+	clientOptions []axiom.Option
+	ingestOptions axiom.IngestOptions
+
+	stopMu  sync.RWMutex
+	stopped bool
+}
+
+// NewLogExporter creates a new `LogExporter` configured to ingest logs to the
+// Axiom deployment and dataset as specified by the environment. Refer to
+// `axiom.NewClient()` for more details on how configuring the Axiom deployment
+// works or pass the `SetClient()` option to pass a custom client or
+// `SetClientOptions()` to control the Axiom client creation. To specify the
+// dataset set `AXIOM_DATASET` or use the `SetDataset()` option.
+//
+// An API token with `axiom.CanIngest` permission is sufficient enough.
+//
+// Additional options can be supplied to configure the `LogExporter`.
+func NewLogExporter(options ...Option) (*LogExporter, error) {
+	exporter := &LogExporter{}
+
+	// Apply supplied options.
+	for _, option := range options {
+		if err := option(exporter); err != nil {
+			return nil, err
+		}
+	}
+
+	// Create client, if not set.
+	if exporter.client == nil {
+		var err error
+		if exporter.client, err = axiom.NewClient(exporter.clientOptions...); err != nil {
+			return nil, err
+		}
+	}
+
+	// When the dataset name is not set, use `AXIOM_DATASET`.
+	if exporter.datasetName == "" {
+		exporter.datasetName = os.Getenv("AXIOM_DATASET")
+		if exporter.datasetName == "" {
+			return nil, ErrMissingDatasetName
+		}
+	}
+
+	return exporter, nil
+}
+
+// Export implements `log.Exporter`. It ingests the given records and returns
+// an error, if the request fails or any of the records failed to ingest.
+func (e *LogExporter) Export(ctx context.Context, records []sdklog.Record) error {
+	e.stopMu.RLock()
+	stopped := e.stopped
+	e.stopMu.RUnlock()
+
+	if stopped || len(records) == 0 {
+		return nil
+	}
+
+	events := make([]axiom.Event, len(records))
+	for i := range records {
+		events[i] = recordToEvent(&records[i])
+	}
+
+	res, err := e.client.Datasets.IngestEvents(ctx, e.datasetName, e.ingestOptions, events...)
+	if err != nil {
+		return fmt.Errorf("failed to ingest batch of %d logs: %w", len(records), err)
+	} else if res.Failed > 0 && len(res.Failures) > 0 {
+		return fmt.Errorf("%d of %d logs failed to ingest: %s",
+			res.Failed, len(records), res.Failures[0].Error)
+	} else if res.Failed > 0 {
+		return fmt.Errorf("%d of %d logs failed to ingest", res.Failed, len(records))
+	}
+
+	return nil
+}
+
+// Shutdown implements `log.Exporter`. Records exported after the exporter has
+// been shut down are discarded.
+func (e *LogExporter) Shutdown(ctx context.Context) error {
+	e.stopMu.Lock()
+	e.stopped = true
+	e.stopMu.Unlock()
+
+	return ctx.Err()
+}
+
+// ForceFlush implements `log.Exporter`. Records are ingested as soon as they
+// are exported, so there is nothing to flush.
+func (e *LogExporter) ForceFlush(ctx context.Context) error {
+	return ctx.Err()
+}
+
+func recordToEvent(record *sdklog.Record) axiom.Event {
+	timestamp := record.Timestamp()
+	if timestamp.IsZero() {
+		timestamp = record.ObservedTimestamp()
+	}
+
+	severity := record.SeverityText()
+	if severity == "" && record.Severity() != log.SeverityUndefined {
+		severity = record.Severity().String()
+	}
+
+	attributes := make(map[string]interface{}, record.AttributesLen())
+	record.WalkAttributes(func(kv log.KeyValue) bool {
+		attributes[kv.Key] = valueToInterface(kv.Value)
+		return true
+	})
+
+	resource := record.Resource()
+	scope := record.InstrumentationScope()
+
+	event := axiom.Event{
+		axiom.TimestampField: timestamp.Format(time.RFC3339Nano),
+
+		"observed_time":   record.ObservedTimestamp().Format(time.RFC3339Nano),
+		"severity":        severity,
+		"severity_number": int(record.Severity()),
+		"body":            valueToInterface(record.Body()),
+		"attributes":      attributes,
+		"resource":        attributesToMap(resource.Attributes()),
+		"scope": map[string]interface{}{
+			"name":    scope.Name,
+			"version": scope.Version,
+		},
+	}
+
+	if traceID := record.TraceID(); traceID.IsValid() {
+		event["trace_id"] = traceID.String()
+	}
+	if spanID := record.SpanID(); spanID.IsValid() {
+		event["span_id"] = spanID.String()
+	}
+
+	return event
+}
+
+func valueToInterface(v log.Value) interface{} {
+	switch v.Kind() {
+	case log.KindBool:
+		return v.AsBool()
+	case log.KindFloat64:
+		return v.AsFloat64()
+	case log.KindInt64:
+		return v.AsInt64()
+	case log.KindString:
+		return v.AsString()
+	case log.KindBytes:
+		return base64.StdEncoding.EncodeToString(v.AsBytes())
+	case log.KindSlice:
+		values := v.AsSlice()
+		s := make([]interface{}, len(values))
+		for i, value := range values {
+			s[i] = valueToInterface(value)
+		}
+		return s
+	case log.KindMap:
+		kvs := v.AsMap()
+		m := make(map[string]interface{}, len(kvs))
+		for _, kv := range kvs {
+			m[kv.Key] = valueToInterface(kv.Value)
+		}
+		return m
+	}
+	return nil
+}
+
+func attributesToMap(attrs []attribute.KeyValue) map[string]interface{} {
+	m := make(map[string]interface{}, len(attrs))
+	for _, attr := range attrs {
+		m[string(attr.Key)] = attr.Value.AsInterface()
+	}
+	return m
+}
@@ -0,0 +1,40 @@
+package otellog_test
+
+import (
+	"context"
+	"log"
+
+	otellog "go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+
+	adapter "github.com/axiomhq/axiom-go/adapters/otel/otellog"
+)
+
+func Example() {
+	// Export `AXIOM_TOKEN`, `AXIOM_ORG_ID` (when using a personal token) and
+	// `AXIOM_DATASET` for Axiom Cloud.
+	// Export `AXIOM_URL`, `AXIOM_TOKEN` and `AXIOM_DATASET` for Axiom Selfhost.
+
+	// 1. Setup the Axiom log exporter.
+	exporter, err := adapter.NewLogExporter()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// 2. Setup a logger provider which batches the records sent to the
+	// exporter.
+	lp := sdklog.NewLoggerProvider(sdklog.WithProcessor(sdklog.NewBatchProcessor(exporter)))
+
+	// 3. Have all records flushed before the application exits.
+	defer func() {
+		if err := lp.Shutdown(context.Background()); err != nil {
+			log.Print(err)
+		}
+	}()
+
+	// 4. Log ⚡
+	var record otellog.Record
+	record.SetSeverity(otellog.SeverityInfo)
+	record.SetBody(otellog.StringValue("This is awesome!"))
+	lp.Logger("example").Emit(context.Background(), record)
+}
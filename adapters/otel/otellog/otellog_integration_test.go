@@ -0,0 +1,46 @@
+//go:build integration
+// +build integration
+
+package otellog_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+
+	"github.com/axiomhq/axiom-go/adapters"
+	adapter "github.com/axiomhq/axiom-go/adapters/otel/otellog"
+	"github.com/axiomhq/axiom-go/axiom"
+)
+
+func Test(t *testing.T) {
+	adapters.TestAdapter(t, "otellog", func(ctx context.Context, dataset string, client *axiom.Client) {
+		exporter, err := adapter.NewLogExporter(
+			adapter.SetClient(client),
+			adapter.SetDataset(dataset),
+		)
+		require.NoError(t, err)
+
+		lp := sdklog.NewLoggerProvider(sdklog.WithProcessor(sdklog.NewBatchProcessor(exporter)))
+		defer func() {
+			require.NoError(t, lp.Shutdown(ctx))
+		}()
+
+		logger := lp.Logger("axiom-go-adapter-test")
+
+		var info log.Record
+		info.SetSeverity(log.SeverityInfo)
+		info.SetBody(log.StringValue("This is awesome!"))
+		info.AddAttributes(log.String("mood", "hyped"))
+		logger.Emit(ctx, info)
+
+		var warn log.Record
+		warn.SetSeverity(log.SeverityWarn)
+		warn.SetBody(log.StringValue("This is no that awesome..."))
+		warn.AddAttributes(log.String("mood", "worried"))
+		logger.Emit(ctx, warn)
+	})
+}
@@ -0,0 +1,146 @@
+package otellog
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/axiomhq/axiom-go/axiom"
+	"github.com/axiomhq/axiom-go/axiom/axiomtest"
+)
+
+// TestNewLogExporter makes sure NewLogExporter() picks up the `AXIOM_DATASET`
+// environment variable.
+func TestNewLogExporter(t *testing.T) {
+	os.Clearenv()
+
+	os.Setenv("AXIOM_TOKEN", "xaat-test")
+	os.Setenv("AXIOM_ORG_ID", "123")
+
+	exporter, err := NewLogExporter()
+	require.ErrorIs(t, err, ErrMissingDatasetName)
+	require.Nil(t, exporter)
+
+	os.Setenv("AXIOM_DATASET", "test")
+
+	exporter, err = NewLogExporter()
+	require.NoError(t, err)
+	require.NotNil(t, exporter)
+
+	assert.Equal(t, "test", exporter.datasetName)
+}
+
+func TestLogExporter(t *testing.T) {
+	srv := axiomtest.NewServer()
+	defer srv.Close()
+
+	srv.CreateDataset("test")
+
+	client, err := srv.Client()
+	require.NoError(t, err)
+
+	exporter, err := NewLogExporter(
+		SetClient(client),
+		SetDataset("test"),
+	)
+	require.NoError(t, err)
+
+	lp := sdklog.NewLoggerProvider(
+		sdklog.WithProcessor(sdklog.NewSimpleProcessor(exporter)),
+		sdklog.WithResource(resource.NewSchemaless(attribute.String("service.name", "my-service"))),
+	)
+	logger := lp.Logger("my-library")
+
+	now := time.Now().Add(-time.Minute).Truncate(time.Millisecond)
+
+	spanCtx := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{1},
+		SpanID:  trace.SpanID{2},
+	})
+	ctx := trace.ContextWithSpanContext(context.Background(), spanCtx)
+
+	var record log.Record
+	record.SetTimestamp(now)
+	record.SetSeverity(log.SeverityWarn)
+	record.SetBody(log.StringValue("my message"))
+	record.AddAttributes(
+		log.String("key", "value"),
+		log.Int("count", 42),
+		log.Map("nested", log.Bool("ok", false)),
+	)
+	logger.Emit(ctx, record)
+
+	var plain log.Record
+	plain.SetSeverityText("custom")
+	plain.SetBody(log.StringValue("no span"))
+	logger.Emit(context.Background(), plain)
+
+	require.NoError(t, lp.Shutdown(context.Background()))
+
+	events := srv.Events("test")
+	require.Len(t, events, 2)
+
+	event := events[0]
+	assert.True(t, now.Equal(event[axiom.TimestampField].(time.Time)))
+	assert.Equal(t, "WARN", event["severity"])
+	assert.EqualValues(t, log.SeverityWarn, event["severity_number"])
+	assert.Equal(t, "my message", event["body"])
+	assert.Equal(t, spanCtx.TraceID().String(), event["trace_id"])
+	assert.Equal(t, spanCtx.SpanID().String(), event["span_id"])
+	assert.Equal(t, map[string]interface{}{
+		"key":    "value",
+		"count":  float64(42),
+		"nested": map[string]interface{}{"ok": false},
+	}, event["attributes"])
+	assert.Equal(t, map[string]interface{}{"service.name": "my-service"}, event["resource"])
+	assert.Equal(t, map[string]interface{}{"name": "my-library", "version": ""}, event["scope"])
+
+	// Records without a timestamp use the observed timestamp.
+	event = events[1]
+	assert.Equal(t, event["observed_time"], event[axiom.TimestampField].(time.Time).Format(time.RFC3339Nano))
+	assert.Equal(t, "custom", event["severity"])
+	assert.Equal(t, "no span", event["body"])
+	assert.NotContains(t, event, "trace_id")
+	assert.NotContains(t, event, "span_id")
+}
+
+func TestLogExporter_Error(t *testing.T) {
+	srv := axiomtest.NewServer(axiomtest.SetEventValidator(func(string, axiom.Event) error {
+		return errors.New("invalid log")
+	}))
+	defer srv.Close()
+
+	srv.CreateDataset("test")
+
+	client, err := srv.Client()
+	require.NoError(t, err)
+
+	exporter, err := NewLogExporter(
+		SetClient(client),
+		SetDataset("test"),
+	)
+	require.NoError(t, err)
+
+	var record sdklog.Record
+	record.SetBody(log.StringValue("my message"))
+	records := []sdklog.Record{record}
+
+	err = exporter.Export(context.Background(), records)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "1 of 1 logs failed to ingest: invalid log")
+	}
+
+	// Records are discarded after shutdown.
+	require.NoError(t, exporter.Shutdown(context.Background()))
+	assert.NoError(t, exporter.Export(context.Background(), records))
+}
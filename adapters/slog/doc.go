@@ -0,0 +1,3 @@
+// Package slog provides an adapter for the `log/slog` package of the standard
+// library. It requires Go 1.21 or greater.
+package slog
@@ -0,0 +1,315 @@
+//go:build go1.21
+// +build go1.21
+
+package slog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/axiomhq/axiom-go/adapters"
+	"github.com/axiomhq/axiom-go/axiom"
+)
+
+var _ slog.Handler = (*Handler)(nil)
+
+const (
+	batchSize    = 1024
+	sendInterval = time.Second
+)
+
+// ErrMissingDatasetName is raised when a dataset name is not provided. Set it
+// manually using the SetDataset option or export `AXIOM_DATASET`.
+var ErrMissingDatasetName = errors.New("missing dataset name")
+
+// An Option modifies the behaviour of the Axiom handler.
+type Option func(*Handler) error
+
+// SetClient specifies the Axiom client to use for ingesting the logs.
+func SetClient(client *axiom.Client) Option {
+	return func(h *Handler) error {
+		h.client = client
+		return nil
+	}
+}
+
+// SetClientOptions specifies the Axiom client options to pass to
+// `axiom.NewClient()`. `axiom.NewClient()` is only called if no client was
+// specified by the `SetClient` option.
+func SetClientOptions(options ...axiom.Option) Option {
+	return func(h *Handler) error {
+		h.clientOptions = options
+		return nil
+	}
+}
+
+// SetDataset specifies the dataset to ingest the logs into. Can also be
+// specified using the `AXIOM_DATASET` environment variable.
+func SetDataset(datasetName string) Option {
+	return func(h *Handler) error {
+		h.datasetName = datasetName
+		return nil
+	}
+}
+
+// SetErrorHandler specifies a function that is called when a batch of events
+// failed to ingest, instead of reporting the failure to stderr.
+func SetErrorHandler(handler adapters.ErrorHandler) Option {
+	return func(h *Handler) error {
+		h.errorHandler = handler
+		return nil
+	}
+}
+
+// SetIngestOptions specifies the ingestion options to use for ingesting the
+// logs. Their `Compression` configures how logs are compressed and defaults to
+// the one configured on the client.
+func SetIngestOptions(opts axiom.IngestOptions) Option {
+	return func(h *Handler) error {
+		h.ingestOptions = opts
+		return nil
+	}
+}
+
+// SetLevel specifies the minimum level of the records the Axiom handler ships
+// to Axiom. Defaults to `slog.LevelInfo`.
+func SetLevel(level slog.Leveler) Option {
+	return func(h *Handler) error {
+		h.level = level
+		return nil
+	}
+}
+
+// SetSpool specifies a spool that batches which failed to ingest because of a
+// temporary error are written to. Spooled batches are replayed once the Axiom
+// deployment is reachable again.
+func SetSpool(spool *axiom.Spool) Option {
+	return func(h *Handler) error {
+		h.spool = spool
+		return nil
+	}
+}
+
+// Handler implements a `slog.Handler` used for shipping logs to Axiom. The
+// attributes of a record are set as fields of an event, attributes in groups
+// are nested under the name of the group. The time, level and message of a
+// record are set as `_time`, `severity` and `message` fields, just like the
+// other adapters do.
+type Handler struct {
+	client      *axiom.Client
+	datasetName string
+
+	clientOptions []axiom.Option
+	errorHandler  adapters.ErrorHandler
+	ingestOptions axiom.IngestOptions
+	level         slog.Leveler
+	spool         *axiom.Spool
+
+	ingester *axiom.Ingester
+
+	// goas are the groups and attributes added by `WithGroup()` and
+	// `WithAttrs()`, in the order they were added.
+	goas []groupOrAttrs
+}
+
+// groupOrAttrs holds either the name of a group or a list of attributes.
+type groupOrAttrs struct {
+	group string
+	attrs []slog.Attr
+}
+
+// New creates a new `Handler` configured to ingest logs to the Axiom deployment
+// and dataset as specified by the environment. Refer to `axiom.NewClient()` for
+// more details on how configuring the Axiom deployment works or pass the
+// `SetClient()` option to pass a custom client or `SetClientOptions()` to
+// control the Axiom client creation. To specify the dataset set `AXIOM_DATASET`
+// or use the `SetDataset()` option.
+//
+// An API token with `axiom.CanIngest` permission is sufficient enough.
+//
+// Additional options can be supplied to configure the `Handler`.
+//
+// A handler needs to be closed properly to make sure all logs are sent by
+// calling `Close()`. Handlers derived from it using `WithAttrs()` or
+// `WithGroup()` share its resources and don't need to be closed.
+func New(options ...Option) (*Handler, error) {
+	handler := &Handler{
+		level: slog.LevelInfo,
+	}
+
+	// Apply supplied options.
+	for _, option := range options {
+		if err := option(handler); err != nil {
+			return nil, err
+		}
+	}
+
+	// Create client, if not set.
+	if handler.client == nil {
+		var err error
+		if handler.client, err = axiom.NewClient(handler.clientOptions...); err != nil {
+			return nil, err
+		}
+	}
+
+	// When the dataset name is not set, use `AXIOM_DATASET`.
+	if handler.datasetName == "" {
+		handler.datasetName = os.Getenv("AXIOM_DATASET")
+		if handler.datasetName == "" {
+			return nil, ErrMissingDatasetName
+		}
+	}
+
+	// Create the ingester which batches the events and sends them in the
+	// background.
+	var err error
+	if handler.ingester, err = axiom.NewIngester(handler.client, handler.datasetName,
+		axiom.SetIngestOptions(handler.ingestOptions),
+		axiom.SetBatchSize(batchSize),
+		axiom.SetFlushInterval(sendInterval),
+		axiom.SetErrorHandler(handler.handleError),
+		axiom.SetSpool(handler.spool),
+	); err != nil {
+		return nil, err
+	}
+
+	return handler, nil
+}
+
+// Close the handler and make sure all events are flushed. Closing the handler
+// renders it and all handlers derived from it unusable for further use.
+func (h *Handler) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return h.ingester.Close(ctx)
+}
+
+// Enabled implements `slog.Handler`.
+func (h *Handler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+// Handle implements `slog.Handler`.
+func (h *Handler) Handle(_ context.Context, r slog.Record) error {
+	// Collect the attributes of the record and wrap them in the groups and
+	// attributes of the handler, from the innermost to the outermost one.
+	fields := make(map[string]any, r.NumAttrs())
+	r.Attrs(func(attr slog.Attr) bool {
+		addAttr(fields, attr)
+		return true
+	})
+	for i := len(h.goas) - 1; i >= 0; i-- {
+		goa := h.goas[i]
+		if goa.group != "" {
+			// Groups without attributes are omitted.
+			if len(fields) > 0 {
+				fields = map[string]any{goa.group: fields}
+			}
+			continue
+		}
+
+		outer := make(map[string]any, len(goa.attrs)+len(fields))
+		for _, attr := range goa.attrs {
+			addAttr(outer, attr)
+		}
+		for k, v := range fields {
+			outer[k] = v
+		}
+		fields = outer
+	}
+
+	event := axiom.Event(fields)
+
+	// Set timestamp, severity and actual message.
+	if !r.Time.IsZero() {
+		event[axiom.TimestampField] = r.Time.Format(time.RFC3339Nano)
+	}
+	event["severity"] = strings.ToLower(r.Level.String())
+	event["message"] = r.Message
+
+	return h.ingester.Ingest(context.Background(), event)
+}
+
+// WithAttrs implements `slog.Handler`.
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	return h.with(groupOrAttrs{attrs: attrs})
+}
+
+// WithGroup implements `slog.Handler`.
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return h.with(groupOrAttrs{group: name})
+}
+
+func (h *Handler) with(goa groupOrAttrs) *Handler {
+	h2 := *h
+	h2.goas = make([]groupOrAttrs, len(h.goas)+1)
+	copy(h2.goas, h.goas)
+	h2.goas[len(h.goas)] = goa
+	return &h2
+}
+
+// addAttr adds the given attribute to the given fields. Groups are added as
+// nested fields, empty attributes and groups are omitted.
+func addAttr(fields map[string]any, attr slog.Attr) {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return
+	}
+
+	switch attr.Value.Kind() {
+	case slog.KindGroup:
+		attrs := attr.Value.Group()
+		if len(attrs) == 0 {
+			return
+		}
+
+		// Attributes of groups without a name are inlined.
+		group := fields
+		if attr.Key != "" {
+			group = make(map[string]any, len(attrs))
+		}
+		for _, groupAttr := range attrs {
+			addAttr(group, groupAttr)
+		}
+		if attr.Key != "" && len(group) > 0 {
+			fields[attr.Key] = group
+		}
+	case slog.KindTime:
+		fields[attr.Key] = attr.Value.Time().Format(time.RFC3339Nano)
+	case slog.KindAny:
+		if err, ok := attr.Value.Any().(error); ok {
+			fields[attr.Key] = err.Error()
+		} else {
+			fields[attr.Key] = attr.Value.Any()
+		}
+	default:
+		fields[attr.Key] = attr.Value.Any()
+	}
+}
+
+func (h *Handler) handleError(err error, res *axiom.IngestStatus, events []axiom.Event) {
+	if h.errorHandler != nil {
+		h.errorHandler(err, res, events)
+		return
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to ingest batch of %d events: %s\n", len(events), err)
+	} else if res.Failed > 0 {
+		// Best effort on notifying the user about the ingest failure.
+		fmt.Fprintf(os.Stderr, "event at %s failed to ingest: %s\n",
+			res.Failures[0].Timestamp, res.Failures[0].Error)
+	}
+}
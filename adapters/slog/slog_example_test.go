@@ -0,0 +1,38 @@
+//go:build go1.21
+// +build go1.21
+
+package slog_test
+
+import (
+	"log"
+	"log/slog"
+
+	adapter "github.com/axiomhq/axiom-go/adapters/slog"
+)
+
+func Example() {
+	// Export `AXIOM_TOKEN`, `AXIOM_ORG_ID` (when using a personal token) and
+	// `AXIOM_DATASET` for Axiom Cloud.
+	// Export `AXIOM_URL`, `AXIOM_TOKEN` and `AXIOM_DATASET` for Axiom Selfhost.
+
+	// 1. Setup the Axiom handler for slog.
+	handler, err := adapter.New()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// 2. Have all logs flushed before the application exits.
+	defer func() {
+		if err := handler.Close(); err != nil {
+			log.Print(err)
+		}
+	}()
+
+	// 3. Create a logger that uses the Axiom handler.
+	logger := slog.New(handler)
+
+	// 4. Log ⚡
+	logger.Info("This is awesome!", "mood", "hyped")
+	logger.Warn("This is no that awesome...", "mood", "worried")
+	logger.Error("This is rather bad.", "mood", "depressed")
+}
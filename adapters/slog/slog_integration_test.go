@@ -0,0 +1,36 @@
+//go:build integration && go1.21
+// +build integration,go1.21
+
+package slog_test
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/axiomhq/axiom-go/adapters"
+	adapter "github.com/axiomhq/axiom-go/adapters/slog"
+	"github.com/axiomhq/axiom-go/axiom"
+)
+
+func Test(t *testing.T) {
+	adapters.TestAdapter(t, "slog", func(_ context.Context, dataset string, client *axiom.Client) {
+		handler, err := adapter.New(
+			adapter.SetClient(client),
+			adapter.SetDataset(dataset),
+		)
+		require.NoError(t, err)
+
+		defer func() {
+			require.NoError(t, handler.Close())
+		}()
+
+		logger := slog.New(handler)
+
+		logger.Info("This is awesome!", "mood", "hyped")
+		logger.Warn("This is no that awesome...", "mood", "worried")
+		logger.Error("This is rather bad.", "mood", "depressed")
+	})
+}
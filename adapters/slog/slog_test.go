@@ -0,0 +1,248 @@
+//go:build go1.21
+// +build go1.21
+
+package slog
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/axiomhq/axiom-go/axiom"
+)
+
+// TestNew makes sure New() picks up the `AXIOM_DATASET` environment variable.
+func TestNew(t *testing.T) {
+	os.Clearenv()
+
+	os.Setenv("AXIOM_TOKEN", "xaat-test")
+	os.Setenv("AXIOM_ORG_ID", "123")
+
+	handler, err := New()
+	require.ErrorIs(t, err, ErrMissingDatasetName)
+	require.Nil(t, handler)
+
+	os.Setenv("AXIOM_DATASET", "test")
+
+	handler, err = New()
+	require.NoError(t, err)
+	require.NotNil(t, handler)
+	require.NoError(t, handler.Close())
+
+	assert.Equal(t, "test", handler.datasetName)
+}
+
+func TestHandler(t *testing.T) {
+	now := time.Now()
+
+	handler, events := setup(t)
+
+	logger := slog.New(handler)
+	logger.Info("my message", "key", "value", "count", 42)
+
+	require.NoError(t, handler.Close())
+
+	if assert.Len(t, *events, 1) {
+		assert.Equal(t, map[string]any{
+			axiom.TimestampField: (*events)[0][axiom.TimestampField],
+			"severity":           "info",
+			"message":            "my message",
+			"key":                "value",
+			"count":              float64(42),
+		}, (*events)[0])
+
+		ts, err := time.Parse(time.RFC3339Nano, (*events)[0][axiom.TimestampField].(string))
+		require.NoError(t, err)
+		assert.WithinDuration(t, now, ts, time.Second)
+	}
+}
+
+func TestHandler_Groups(t *testing.T) {
+	handler, events := setup(t)
+
+	logger := slog.New(handler).
+		With("service", "test").
+		WithGroup("request").
+		With("method", "GET").
+		WithGroup("empty")
+
+	logger.Warn("my message",
+		slog.Group("user", "id", 1, "name", "alice"),
+		slog.Group("", "inlined", true),
+		slog.Group("nothing"),
+		slog.Any("err", errors.New("oops")),
+		slog.Time("at", time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)),
+		slog.Attr{},
+	)
+	logger.Warn("without attributes")
+
+	require.NoError(t, handler.Close())
+
+	require.Len(t, *events, 2)
+	for _, event := range *events {
+		delete(event, axiom.TimestampField)
+	}
+
+	assert.Equal(t, map[string]any{
+		"severity": "warn",
+		"message":  "my message",
+		"service":  "test",
+		"request": map[string]any{
+			"method": "GET",
+			"empty": map[string]any{
+				"user": map[string]any{
+					"id":   float64(1),
+					"name": "alice",
+				},
+				"inlined": true,
+				"err":     "oops",
+				"at":      "2022-01-02T03:04:05Z",
+			},
+		},
+	}, (*events)[0])
+
+	// Groups without attributes are omitted.
+	assert.Equal(t, map[string]any{
+		"severity": "warn",
+		"message":  "without attributes",
+		"service":  "test",
+		"request": map[string]any{
+			"method": "GET",
+		},
+	}, (*events)[1])
+}
+
+func TestHandler_Level(t *testing.T) {
+	handler, events := setup(t)
+
+	var level slog.LevelVar
+	handler.level = &level
+
+	logger := slog.New(handler)
+	logger.Debug("dropped")
+	logger.Info("shipped")
+
+	level.Set(slog.LevelDebug)
+	logger.Debug("shipped as well")
+
+	require.NoError(t, handler.Close())
+
+	if assert.Len(t, *events, 2) {
+		assert.Equal(t, "shipped", (*events)[0]["message"])
+		assert.Equal(t, "shipped as well", (*events)[1]["message"])
+		assert.Equal(t, "debug", (*events)[1]["severity"])
+	}
+}
+
+func TestHandler_ErrorHandler(t *testing.T) {
+	hf := func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{
+			"ingested": 0,
+			"failed": 2,
+			"failures": [
+				{"timestamp": "2022-01-01T00:00:00Z", "error": "first error"},
+				{"timestamp": "2022-01-01T00:00:01Z", "error": "second error"}
+			]
+		}`))
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(hf))
+	defer srv.Close()
+
+	client, err := axiom.NewClient(
+		axiom.SetURL(srv.URL),
+		axiom.SetAccessToken("xaat-test"),
+		axiom.SetClient(srv.Client()),
+	)
+	require.NoError(t, err)
+
+	var (
+		calls  int
+		status *axiom.IngestStatus
+		events []axiom.Event
+	)
+	handler, err := New(
+		SetClient(client),
+		SetDataset("test"),
+		SetErrorHandler(func(e error, s *axiom.IngestStatus, b []axiom.Event) {
+			calls++
+			err, status, events = e, s, b
+		}),
+	)
+	require.NoError(t, err)
+
+	logger := slog.New(handler)
+	logger.Info("first")
+	logger.Info("second")
+
+	require.NoError(t, handler.Close())
+
+	require.Equal(t, 1, calls)
+	assert.NoError(t, err)
+	if assert.NotNil(t, status) && assert.Len(t, status.Failures, 2) {
+		assert.Equal(t, "first error", status.Failures[0].Error)
+		assert.Equal(t, "second error", status.Failures[1].Error)
+	}
+	if assert.Len(t, events, 2) {
+		assert.Equal(t, "first", events[0]["message"])
+		assert.Equal(t, "second", events[1]["message"])
+	}
+}
+
+// setup sets up a test HTTP server along with an Axiom handler that is
+// configured to talk to that test server. The events received by the server
+// are recorded and can be inspected once the handler has been closed.
+func setup(t *testing.T) (*Handler, *[]map[string]any) {
+	t.Helper()
+
+	var (
+		mu     sync.Mutex
+		events []map[string]any
+	)
+	hf := func(w http.ResponseWriter, r *http.Request) {
+		gzr, err := gzip.NewReader(r.Body)
+		require.NoError(t, err)
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		s := bufio.NewScanner(gzr)
+		for s.Scan() {
+			var event map[string]any
+			assert.NoError(t, json.Unmarshal(s.Bytes(), &event))
+			events = append(events, event)
+		}
+		assert.NoError(t, s.Err())
+
+		_, _ = w.Write([]byte("{}"))
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(hf))
+	t.Cleanup(srv.Close)
+
+	client, err := axiom.NewClient(
+		axiom.SetURL(srv.URL),
+		axiom.SetAccessToken("xaat-test"),
+		axiom.SetClient(srv.Client()),
+	)
+	require.NoError(t, err)
+
+	handler, err := New(
+		SetClient(client),
+		SetDataset("test"),
+	)
+	require.NoError(t, err)
+
+	return handler, &events
+}
@@ -3,6 +3,7 @@ package zap
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
@@ -12,6 +13,7 @@ import (
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 
+	"github.com/axiomhq/axiom-go/adapters"
 	"github.com/axiomhq/axiom-go/axiom"
 )
 
@@ -34,9 +36,24 @@ var encoderConfig = zapcore.EncoderConfig{
 	EncodeCaller:   zapcore.ShortCallerEncoder,
 }
 
-// ErrMissingDatasetName is raised when a dataset name is not provided. Set it
-// manually using the SetDataset option or export `AXIOM_DATASET`.
-var ErrMissingDatasetName = errors.New("missing dataset name")
+const (
+	defaultFlushInterval = time.Second
+	defaultFlushSize     = 1 << 20  // 1 MiB
+	defaultMaxBufferSize = 16 << 20 // 16 MiB
+)
+
+var (
+	// ErrMissingDatasetName is raised when a dataset name is not provided. Set
+	// it manually using the SetDataset option or export `AXIOM_DATASET`.
+	ErrMissingDatasetName = errors.New("missing dataset name")
+
+	// ErrBufferFull is returned when a log line is written to a WriteSyncer
+	// whose buffer has reached its maximum size. The log line is discarded.
+	// This happens when logs are written faster than they can be ingested or
+	// while the Axiom deployment is unreachable. Raise the limit using the
+	// SetMaxBufferSize option.
+	ErrBufferFull = errors.New("buffer full")
+)
 
 // An Option modifies the behaviour of the Axiom WriteSyncer.
 type Option func(*WriteSyncer) error
@@ -68,8 +85,50 @@ func SetDataset(datasetName string) Option {
 	}
 }
 
+// SetErrorHandler specifies a function that is called when a batch of logs
+// failed to ingest. It is passed the logs as events. Logs that failed to
+// ingest because of a temporary error are kept and retried, but each of them is
+// only passed to the handler once, even if it fails again. The handler isn't
+// called for a retry that only includes logs it has already been passed.
+// Failures of background flushes are reported to stderr, unless an error
+// handler is configured.
+func SetErrorHandler(handler adapters.ErrorHandler) Option {
+	return func(ws *WriteSyncer) error {
+		ws.errorHandler = handler
+		return nil
+	}
+}
+
+// SetFlushInterval specifies the interval at which buffered logs are flushed
+// in the background. Logs that failed to ingest because of a temporary error
+// are retried at the same interval. A value of zero disables interval based
+// flushing. Defaults to one second.
+func SetFlushInterval(interval time.Duration) Option {
+	return func(ws *WriteSyncer) error {
+		if interval < 0 {
+			return fmt.Errorf("invalid flush interval %s", interval)
+		}
+		ws.flushInterval = interval
+		return nil
+	}
+}
+
+// SetFlushSize specifies the size in bytes the buffered logs must reach to be
+// flushed in the background, without waiting for the flush interval to pass.
+// A value of zero disables size based flushing. Defaults to 1 MiB.
+func SetFlushSize(size int) Option {
+	return func(ws *WriteSyncer) error {
+		if size < 0 {
+			return fmt.Errorf("invalid flush size %d", size)
+		}
+		ws.flushSize = size
+		return nil
+	}
+}
+
 // SetIngestOptions specifies the ingestion options to use for ingesting the
-// logs.
+// logs. Their `Compression` configures how logs are compressed and defaults to
+// the one configured on the client.
 func SetIngestOptions(opts axiom.IngestOptions) Option {
 	return func(ws *WriteSyncer) error {
 		ws.ingestOptions = opts
@@ -86,18 +145,64 @@ func SetLevelEnabler(levelEnabler zapcore.LevelEnabler) Option {
 	}
 }
 
+// SetMaxBufferSize specifies the maximum size in bytes of the buffered logs,
+// including the ones currently being ingested. Log lines written to a full
+// buffer are discarded and `ErrBufferFull` is returned, which zap reports to
+// the error output of the logger. A value of zero disables the limit. Defaults
+// to 16 MiB.
+func SetMaxBufferSize(size int) Option {
+	return func(ws *WriteSyncer) error {
+		if size < 0 {
+			return fmt.Errorf("invalid maximum buffer size %d", size)
+		}
+		ws.maxBufferSize = size
+		return nil
+	}
+}
+
+// SetSpool specifies a spool that the buffered logs are written to when they
+// fail to ingest because of a temporary error, instead of keeping them in the
+// buffer. Spooled logs are replayed on the next successful flush.
+func SetSpool(spool *axiom.Spool) Option {
+	return func(ws *WriteSyncer) error {
+		ws.spool = spool
+		return nil
+	}
+}
+
 // WriteSyncer implements a `zapcore.WriteSyncer` used for shipping logs to
-// Axiom.
+// Axiom. Log lines are buffered and flushed in the background, once the flush
+// interval has passed or the buffer reached the flush size. Logs that failed to
+// ingest because of a temporary error (see `axiom.IsTemporary()`) are kept in
+// the buffer and retried with the next flush, unless a spool is configured.
+// Logs rejected with a permanent error, e.g. because of an invalid token, are
+// dropped. The size of the buffer is capped.
 type WriteSyncer struct {
 	client      *axiom.Client
 	datasetName string
 
 	clientOptions []axiom.Option
+	errorHandler  adapters.ErrorHandler
 	ingestOptions axiom.IngestOptions
 	levelEnabler  zapcore.LevelEnabler
+	spool         *axiom.Spool
+
+	flushInterval time.Duration
+	flushSize     int
+	maxBufferSize int
+
+	// flushMtx serializes flushes and guards reported, the number of leading
+	// bytes of the kept logs that have already been passed to the error
+	// handler.
+	flushMtx sync.Mutex
+	reported int
 
-	buf    bytes.Buffer
-	bufMtx sync.Mutex
+	// bufMtx guards the fields below.
+	bufMtx    sync.Mutex
+	buf       *bytes.Buffer
+	inflight  int
+	timer     *time.Timer
+	scheduled bool
 }
 
 // New creates a new `zapcore.Core` configured to ingest logs to the Axiom
@@ -110,11 +215,31 @@ type WriteSyncer struct {
 // An API token with `axiom.CanIngest` permission is sufficient enough.
 //
 // Additional options can be supplied to configure the `zapcore.Core`.
+//
+// Logs are flushed in the background. Call `Sync()` on the logger before the
+// application exits to flush the remaining logs and wait for them to be sent.
 func New(options ...Option) (zapcore.Core, error) {
+	ws, err := newWriteSyncer(options...)
+	if err != nil {
+		return nil, err
+	}
+
+	enc := zapcore.NewJSONEncoder(encoderConfig)
+
+	return zapcore.NewCore(enc, ws, ws.levelEnabler), nil
+}
+
+func newWriteSyncer(options ...Option) (*WriteSyncer, error) {
 	ws := &WriteSyncer{
 		levelEnabler: zap.LevelEnablerFunc(func(lvl zapcore.Level) bool {
 			return true
 		}),
+
+		flushInterval: defaultFlushInterval,
+		flushSize:     defaultFlushSize,
+		maxBufferSize: defaultMaxBufferSize,
+
+		buf: new(bytes.Buffer),
 	}
 
 	// Apply supplied options.
@@ -139,48 +264,192 @@ func New(options ...Option) (zapcore.Core, error) {
 		}
 	}
 
-	enc := zapcore.NewJSONEncoder(encoderConfig)
-
-	return zapcore.NewCore(enc, ws, ws.levelEnabler), nil
+	return ws, nil
 }
 
-// Write implements `zapcore.WriteSyncer`.
+// Write implements `zapcore.WriteSyncer`. It returns `ErrBufferFull`, if the
+// buffer has reached its maximum size.
 func (ws *WriteSyncer) Write(p []byte) (n int, err error) {
 	ws.bufMtx.Lock()
 	defer ws.bufMtx.Unlock()
 
-	return ws.buf.Write(p)
+	if ws.maxBufferSize > 0 && ws.inflight+ws.buf.Len()+len(p) > ws.maxBufferSize {
+		return 0, ErrBufferFull
+	}
+
+	if n, err = ws.buf.Write(p); err != nil {
+		return n, err
+	}
+
+	if ws.flushSize > 0 && ws.buf.Len() >= ws.flushSize {
+		ws.scheduleFlush(0)
+	} else if ws.flushInterval > 0 {
+		ws.scheduleFlush(ws.flushInterval)
+	}
+
+	return n, nil
 }
 
-// Sync implements `zapcore.WriteSyncer`.
+// Sync implements `zapcore.WriteSyncer`. It flushes the buffered logs and
+// waits for them to be sent.
 func (ws *WriteSyncer) Sync() error {
 	// Best effort context timeout. A `Sync()` should never take that long.
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
 
+	return ws.flush(ctx)
+}
+
+// scheduleFlush schedules a background flush after the given delay, unless one
+// is already scheduled. A delay of zero reschedules an already scheduled flush
+// to happen immediately. Must be called with bufMtx held.
+func (ws *WriteSyncer) scheduleFlush(d time.Duration) {
+	if ws.scheduled && d > 0 {
+		return
+	}
+	ws.scheduled = true
+
+	if ws.timer == nil {
+		ws.timer = time.AfterFunc(d, ws.backgroundFlush)
+	} else {
+		ws.timer.Reset(d)
+	}
+}
+
+// backgroundFlush flushes the buffered logs and reports errors to stderr, as
+// there is no one to return them to.
+func (ws *WriteSyncer) backgroundFlush() {
 	ws.bufMtx.Lock()
-	defer ws.bufMtx.Unlock()
+	ws.scheduled = false
+	ws.bufMtx.Unlock()
 
-	if ws.buf.Len() == 0 {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	if err := ws.flush(ctx); err != nil && ws.errorHandler == nil {
+		fmt.Fprintf(os.Stderr, "failed to flush logs: %s\n", err)
+	}
+}
+
+// flush ingests the buffered logs. While they are ingested, new logs are
+// written to a fresh buffer. Logs that failed to ingest because of a temporary
+// error are either spooled or put back in front of the buffer and retried with
+// the next flush.
+func (ws *WriteSyncer) flush(ctx context.Context) error {
+	ws.flushMtx.Lock()
+	defer ws.flushMtx.Unlock()
+
+	ws.bufMtx.Lock()
+	data := ws.buf
+	if data.Len() == 0 {
+		ws.bufMtx.Unlock()
 		return nil
 	}
+	ws.buf = new(bytes.Buffer)
+	ws.inflight = data.Len()
+	ws.bufMtx.Unlock()
 
-	// Make sure to reset the buffer.
-	defer ws.buf.Reset()
+	keep, err := ws.ingest(ctx, data)
+	if !keep {
+		ws.reported = 0
+	}
 
-	r, err := axiom.GzipEncoder(&ws.buf)
-	if err != nil {
-		return err
+	ws.bufMtx.Lock()
+	defer ws.bufMtx.Unlock()
+
+	ws.inflight = 0
+	if keep {
+		_, _ = data.Write(ws.buf.Bytes())
+		ws.buf = data
+	}
+	if ws.buf.Len() > 0 && ws.flushInterval > 0 {
+		ws.scheduleFlush(ws.flushInterval)
 	}
 
-	res, err := ws.client.Datasets.Ingest(ctx, ws.datasetName, r, axiom.NDJSON, axiom.Gzip, ws.ingestOptions)
-	if err != nil {
-		return err
-	} else if res.Failed > 0 {
+	return err
+}
+
+// ingest ingests the given logs. It reports if the logs must be kept because
+// they failed to ingest because of a temporary error and were not spooled.
+// Logs rejected with a permanent error are dropped.
+func (ws *WriteSyncer) ingest(ctx context.Context, data *bytes.Buffer) (bool, error) {
+	// The logs are compressed as configured by the ingest options or the
+	// clients default.
+	res, err := ws.client.Datasets.IngestReader(ctx, ws.datasetName, bytes.NewReader(data.Bytes()), ws.ingestOptions)
+	if err != nil && !axiom.IsTemporary(err) {
+		// Retrying won't help, so keeping the logs would only fill up the
+		// buffer.
+		ws.handleError(err, nil, data.Bytes())
+		return false, err
+	} else if err != nil && ws.spool != nil {
+		if spoolErr := ws.spool.Append(bytes.NewReader(data.Bytes())); spoolErr != nil {
+			err = fmt.Errorf("%w (failed to spool logs: %s)", err, spoolErr)
+			ws.handleTemporaryError(err, data.Bytes())
+			return true, err
+		}
+		return false, nil
+	} else if err != nil {
+		ws.handleTemporaryError(err, data.Bytes())
+		return true, err
+	}
+
+	// Replay previously spooled logs now that ingestion works again. Only
+	// segments quarantined because of a permanent error are reported, as all
+	// others remain in the spool and are replayed again with the next flush.
+	if ws.spool != nil && ws.spool.Pending() {
+		replayRes, err := ws.spool.Replay(ctx, ws.client, ws.datasetName, ws.ingestOptions)
+		if errors.As(err, &axiom.QuarantineError{}) {
+			ws.handleError(err, nil, nil)
+		}
+		if replayRes != nil && replayRes.Failed > 0 {
+			ws.handleError(nil, replayRes, nil)
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+
+	if res.Failed > 0 {
+		ws.handleError(nil, res, data.Bytes())
+
 		// Best effort on notifying the user about the ingest failure.
-		return fmt.Errorf("event at %s failed to ingest: %s",
+		return false, fmt.Errorf("event at %s failed to ingest: %s",
 			res.Failures[0].Timestamp, res.Failures[0].Error)
 	}
 
-	return nil
+	return false, nil
+}
+
+// handleTemporaryError passes a temporary failure to the error handler. The
+// given logs are kept and retried, so only the ones that have not been passed
+// to the handler by a previous failure are passed along. If there are none, the
+// handler is not called.
+func (ws *WriteSyncer) handleTemporaryError(err error, data []byte) {
+	if ws.reported < len(data) {
+		ws.handleError(err, nil, data[ws.reported:])
+	}
+	ws.reported = len(data)
+}
+
+// handleError passes the given failure to the error handler, if one is set.
+// The given logs are decoded into events on a best effort basis.
+func (ws *WriteSyncer) handleError(err error, res *axiom.IngestStatus, data []byte) {
+	if ws.errorHandler == nil {
+		return
+	}
+
+	var events []axiom.Event
+	if data != nil {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		for {
+			var event axiom.Event
+			if dec.Decode(&event) != nil {
+				break
+			}
+			events = append(events, event)
+		}
+	}
+
+	ws.errorHandler(err, res, events)
 }
@@ -1,15 +1,20 @@
 package zap
 
 import (
+	"bufio"
 	"compress/gzip"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/klauspost/compress/zstd"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
@@ -67,10 +72,484 @@ func TestCore(t *testing.T) {
 	assert.True(t, hasRun)
 }
 
+func TestCore_Spool(t *testing.T) {
+	var (
+		fail     = true
+		received []string
+	)
+	hf := func(w http.ResponseWriter, r *http.Request) {
+		if fail {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		gzr, err := gzip.NewReader(r.Body)
+		require.NoError(t, err)
+
+		s := bufio.NewScanner(gzr)
+		for s.Scan() {
+			var event axiom.Event
+			require.NoError(t, json.Unmarshal(s.Bytes(), &event))
+			received = append(received, event["msg"].(string))
+		}
+		assert.NoError(t, s.Err())
+
+		_, _ = w.Write([]byte("{}"))
+	}
+
+	spool, err := axiom.OpenSpool(t.TempDir())
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, spool.Close())
+	}()
+
+	logger, teardown := setup(t, hf, SetSpool(spool))
+	defer teardown()
+
+	logger.Info("first")
+	require.NoError(t, logger.Sync())
+	assert.True(t, spool.Pending())
+
+	fail = false
+
+	logger.Info("second")
+	require.NoError(t, logger.Sync())
+	assert.False(t, spool.Pending())
+
+	assert.Equal(t, []string{"second", "first"}, received)
+}
+
+func TestCore_SpoolPermanentError(t *testing.T) {
+	var forbidden uint32 = 1
+	hf := func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadUint32(&forbidden) == 1 {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_, _ = w.Write([]byte("{}"))
+	}
+
+	spool, err := axiom.OpenSpool(t.TempDir())
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, spool.Close())
+	}()
+
+	logger, teardown := setup(t, hf, SetSpool(spool))
+	defer teardown()
+
+	logger.Info("my message")
+	require.Error(t, logger.Sync())
+
+	// Logs rejected permanently are not worth spooling.
+	assert.False(t, spool.Pending())
+
+	atomic.StoreUint32(&forbidden, 0)
+}
+
+func TestCore_FlushInterval(t *testing.T) {
+	rec := &recorder{}
+
+	logger, teardown := setup(t, rec.handle, SetFlushInterval(10*time.Millisecond))
+	defer teardown()
+
+	logger.Info("my message")
+
+	// No call to `Sync()` required.
+	assert.Eventually(t, func() bool {
+		return len(rec.messages()) == 1
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestCore_FlushSize(t *testing.T) {
+	rec := &recorder{}
+
+	logger, teardown := setup(t, rec.handle,
+		SetFlushInterval(0),
+		SetFlushSize(1),
+	)
+	defer teardown()
+
+	logger.Info("my message")
+
+	// No call to `Sync()` required.
+	assert.Eventually(t, func() bool {
+		return len(rec.messages()) == 1
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestCore_KeepOnError(t *testing.T) {
+	rec := &recorder{fail: true}
+
+	logger, teardown := setup(t, rec.handle, SetFlushInterval(0))
+	defer teardown()
+
+	logger.Info("first")
+	require.Error(t, logger.Sync())
+
+	rec.setFail(false)
+
+	logger.Info("second")
+	require.NoError(t, logger.Sync())
+
+	assert.Equal(t, []string{"first", "second"}, rec.messages())
+}
+
+func TestWriteSyncer_DropOnPermanentError(t *testing.T) {
+	rec := &recorder{}
+
+	var forbidden uint32 = 1
+	hf := func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadUint32(&forbidden) == 1 {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		rec.handle(w, r)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(hf))
+	defer srv.Close()
+
+	client, err := axiom.NewClient(
+		axiom.SetNoEnv(),
+		axiom.SetURL(srv.URL),
+		axiom.SetAccessToken("xaat-test"),
+		axiom.SetClient(srv.Client()),
+	)
+	require.NoError(t, err)
+
+	line := []byte(`{"msg":"my message"}` + "\n")
+
+	ws, err := newWriteSyncer(
+		SetClient(client),
+		SetDataset("test"),
+		SetFlushInterval(0),
+		SetFlushSize(0),
+		SetMaxBufferSize(2*len(line)),
+	)
+	require.NoError(t, err)
+
+	// Logs rejected permanently are dropped, so the buffer never fills up.
+	for i := 0; i < 5; i++ {
+		_, err = ws.Write(line)
+		require.NoError(t, err)
+		require.EqualError(t, ws.Sync(), "API error 403: Forbidden")
+		assert.Zero(t, ws.buf.Len())
+	}
+
+	atomic.StoreUint32(&forbidden, 0)
+
+	_, err = ws.Write(line)
+	require.NoError(t, err)
+	require.NoError(t, ws.Sync())
+
+	assert.Equal(t, []string{"my message"}, rec.messages())
+}
+
+func TestCore_ErrorHandler(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		errs   []error
+		status []*axiom.IngestStatus
+		events [][]axiom.Event
+	)
+	handler := func(err error, res *axiom.IngestStatus, batch []axiom.Event) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		errs = append(errs, err)
+		status = append(status, res)
+		events = append(events, batch)
+	}
+
+	t.Run("request failure", func(t *testing.T) {
+		errs, status, events = nil, nil, nil
+
+		rec := &recorder{fail: true}
+
+		logger, teardown := setup(t, rec.handle,
+			SetFlushInterval(0),
+			SetErrorHandler(handler),
+		)
+		defer teardown()
+
+		logger.Info("my message")
+		require.Error(t, logger.Sync())
+
+		rec.setFail(false)
+
+		if assert.Len(t, errs, 1) {
+			assert.Error(t, errs[0])
+			assert.Nil(t, status[0])
+			if assert.Len(t, events[0], 1) {
+				assert.Equal(t, "my message", events[0][0]["msg"])
+			}
+		}
+	})
+
+	t.Run("repeated flushes", func(t *testing.T) {
+		errs, status, events = nil, nil, nil
+
+		rec := &recorder{fail: true}
+
+		logger, teardown := setup(t, rec.handle,
+			SetFlushInterval(0),
+			SetErrorHandler(handler),
+		)
+		defer teardown()
+
+		logger.Info("first")
+		require.Error(t, logger.Sync())
+
+		// Kept logs that have already been reported are not reported again.
+		require.Error(t, logger.Sync())
+		require.Error(t, logger.Sync())
+
+		logger.Info("second")
+		require.Error(t, logger.Sync())
+
+		rec.setFail(false)
+		require.NoError(t, logger.Sync())
+
+		assert.Equal(t, []string{"first", "second"}, rec.messages())
+		if assert.Len(t, events, 2) {
+			if assert.Len(t, events[0], 1) {
+				assert.Equal(t, "first", events[0][0]["msg"])
+			}
+			if assert.Len(t, events[1], 1) {
+				assert.Equal(t, "second", events[1][0]["msg"])
+			}
+		}
+	})
+
+	t.Run("partial failure", func(t *testing.T) {
+		errs, status, events = nil, nil, nil
+
+		hf := func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = fmt.Fprint(w, `{
+				"ingested": 0,
+				"failed": 2,
+				"failures": [
+					{"timestamp": "2022-01-01T00:00:00Z", "error": "first error"},
+					{"timestamp": "2022-01-01T00:00:01Z", "error": "second error"}
+				]
+			}`)
+		}
+
+		logger, teardown := setup(t, hf,
+			SetFlushInterval(0),
+			SetErrorHandler(handler),
+		)
+		defer teardown()
+
+		logger.Info("first")
+		logger.Info("second")
+		require.Error(t, logger.Sync())
+
+		if assert.Len(t, errs, 1) {
+			assert.NoError(t, errs[0])
+			if assert.NotNil(t, status[0]) && assert.Len(t, status[0].Failures, 2) {
+				assert.Equal(t, "first error", status[0].Failures[0].Error)
+				assert.Equal(t, "second error", status[0].Failures[1].Error)
+			}
+			assert.Len(t, events[0], 2)
+		}
+	})
+}
+
+func TestWriteSyncer_MaxBufferSize(t *testing.T) {
+	ws, err := newWriteSyncer(
+		SetClientOptions(axiom.SetNoEnv(), axiom.SetAccessToken("xaat-test"), axiom.SetOrgID("123")),
+		SetDataset("test"),
+		SetFlushInterval(0),
+		SetFlushSize(0),
+		SetMaxBufferSize(10),
+	)
+	require.NoError(t, err)
+
+	n, err := ws.Write([]byte("12345678\n"))
+	require.NoError(t, err)
+	assert.Equal(t, 9, n)
+
+	n, err = ws.Write([]byte("12\n"))
+	assert.ErrorIs(t, err, ErrBufferFull)
+	assert.Zero(t, n)
+
+	assert.Equal(t, 9, ws.buf.Len())
+}
+
+func TestNew_InvalidOptions(t *testing.T) {
+	tests := map[string]Option{
+		"invalid flush interval -1s":     SetFlushInterval(-time.Second),
+		"invalid flush size -1":          SetFlushSize(-1),
+		"invalid maximum buffer size -1": SetMaxBufferSize(-1),
+	}
+	for exp, option := range tests {
+		_, err := New(
+			SetClientOptions(axiom.SetNoEnv(), axiom.SetAccessToken("xaat-test"), axiom.SetOrgID("123")),
+			SetDataset("test"),
+			option,
+		)
+		assert.EqualError(t, err, exp)
+	}
+}
+
+func TestCore_Compression(t *testing.T) {
+	tests := []struct {
+		name         string
+		compression  axiom.Compression
+		wantEncoding string
+	}{
+		{
+			name:         "default",
+			wantEncoding: "gzip",
+		},
+		{
+			name:         "zstd",
+			compression:  axiom.Compression{Encoding: axiom.Zstd, Concurrency: 1},
+			wantEncoding: "zstd",
+		},
+		{
+			name:        "below minimum size",
+			compression: axiom.Compression{Encoding: axiom.Zstd, MinSize: 1 << 20},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var received []string
+			hf := func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, tt.wantEncoding, r.Header.Get("Content-Encoding"))
+
+				var body io.Reader = r.Body
+				switch tt.wantEncoding {
+				case "gzip":
+					gzr, err := gzip.NewReader(r.Body)
+					require.NoError(t, err)
+					body = gzr
+				case "zstd":
+					zr, err := zstd.NewReader(r.Body)
+					require.NoError(t, err)
+					defer zr.Close()
+					body = zr
+				}
+
+				s := bufio.NewScanner(body)
+				for s.Scan() {
+					var event axiom.Event
+					require.NoError(t, json.Unmarshal(s.Bytes(), &event))
+					received = append(received, event["msg"].(string))
+				}
+				assert.NoError(t, s.Err())
+
+				_, _ = w.Write([]byte("{}"))
+			}
+
+			logger, teardown := setup(t, hf, SetIngestOptions(axiom.IngestOptions{
+				Compression: tt.compression,
+			}))
+			defer teardown()
+
+			logger.Info("my message")
+			require.NoError(t, logger.Sync())
+
+			assert.Equal(t, []string{"my message"}, received)
+		})
+	}
+}
+
+func TestCore_ClientCompression(t *testing.T) {
+	var received []string
+	hf := func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "zstd", r.Header.Get("Content-Encoding"))
+
+		zr, err := zstd.NewReader(r.Body)
+		require.NoError(t, err)
+		defer zr.Close()
+
+		s := bufio.NewScanner(zr)
+		for s.Scan() {
+			var event axiom.Event
+			require.NoError(t, json.Unmarshal(s.Bytes(), &event))
+			received = append(received, event["msg"].(string))
+		}
+		assert.NoError(t, s.Err())
+
+		_, _ = w.Write([]byte("{}"))
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(hf))
+	defer srv.Close()
+
+	client, err := axiom.NewClient(
+		axiom.SetNoEnv(),
+		axiom.SetURL(srv.URL),
+		axiom.SetAccessToken("xaat-test"),
+		axiom.SetClient(srv.Client()),
+		axiom.SetIngestCompression(axiom.Compression{Encoding: axiom.Zstd, Concurrency: 1}),
+	)
+	require.NoError(t, err)
+
+	core, err := New(SetClient(client), SetDataset("test"))
+	require.NoError(t, err)
+
+	logger := zap.New(core)
+	logger.Info("my message")
+	require.NoError(t, logger.Sync())
+
+	assert.Equal(t, []string{"my message"}, received)
+}
+
+// recorder records the messages of the logs received by a test HTTP server.
+// It can be told to fail requests.
+type recorder struct {
+	mu       sync.Mutex
+	fail     bool
+	received []string
+}
+
+func (rec *recorder) handle(w http.ResponseWriter, r *http.Request) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	if rec.fail {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	gzr, err := gzip.NewReader(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	s := bufio.NewScanner(gzr)
+	for s.Scan() {
+		var event axiom.Event
+		if json.Unmarshal(s.Bytes(), &event) == nil {
+			rec.received = append(rec.received, event["msg"].(string))
+		}
+	}
+
+	_, _ = w.Write([]byte("{}"))
+}
+
+func (rec *recorder) setFail(fail bool) {
+	rec.mu.Lock()
+	rec.fail = fail
+	rec.mu.Unlock()
+}
+
+func (rec *recorder) messages() []string {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	return append([]string(nil), rec.received...)
+}
+
 // setup sets up a test HTTP server along with a zap logger that is configured
 // to talk to that test server through an Axiom WriteSyncer. Tests should pass a
 // handler function which provides the response for the API method being tested.
-func setup(t *testing.T, h http.HandlerFunc) (*zap.Logger, func()) {
+func setup(t *testing.T, h http.HandlerFunc, options ...Option) (*zap.Logger, func()) {
 	t.Helper()
 
 	srv := httptest.NewServer(h)
@@ -83,10 +562,10 @@ func setup(t *testing.T, h http.HandlerFunc) (*zap.Logger, func()) {
 	)
 	require.NoError(t, err)
 
-	core, err := New(
+	core, err := New(append([]Option{
 		SetClient(client),
 		SetDataset("test"),
-	)
+	}, options...)...)
 	require.NoError(t, err)
 
 	logger := zap.New(core)
@@ -0,0 +1,252 @@
+package zerolog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/axiomhq/axiom-go/adapters"
+	"github.com/axiomhq/axiom-go/axiom"
+)
+
+var _ zerolog.LevelWriter = (*Writer)(nil)
+
+const (
+	batchSize    = 1024
+	sendInterval = time.Second
+)
+
+// ErrMissingDatasetName is raised when a dataset name is not provided. Set it
+// manually using the SetDataset option or export `AXIOM_DATASET`.
+var ErrMissingDatasetName = errors.New("missing dataset name")
+
+// An Option modifies the behaviour of the Axiom writer.
+type Option func(*Writer) error
+
+// SetClient specifies the Axiom client to use for ingesting the logs.
+func SetClient(client *axiom.Client) Option {
+	return func(w *Writer) error {
+		w.client = client
+		return nil
+	}
+}
+
+// SetClientOptions specifies the Axiom client options to pass to
+// `axiom.NewClient()`. `axiom.NewClient()` is only called if no client was
+// specified by the `SetClient` option.
+func SetClientOptions(options ...axiom.Option) Option {
+	return func(w *Writer) error {
+		w.clientOptions = options
+		return nil
+	}
+}
+
+// SetDataset specifies the dataset to ingest the logs into. Can also be
+// specified using the `AXIOM_DATASET` environment variable.
+func SetDataset(datasetName string) Option {
+	return func(w *Writer) error {
+		w.datasetName = datasetName
+		return nil
+	}
+}
+
+// SetErrorHandler specifies a function that is called when a batch of events
+// failed to ingest, instead of reporting the failure to stderr.
+func SetErrorHandler(handler adapters.ErrorHandler) Option {
+	return func(w *Writer) error {
+		w.errorHandler = handler
+		return nil
+	}
+}
+
+// SetIngestOptions specifies the ingestion options to use for ingesting the
+// logs. Their `Compression` configures how logs are compressed and defaults to
+// the one configured on the client.
+func SetIngestOptions(opts axiom.IngestOptions) Option {
+	return func(w *Writer) error {
+		w.ingestOptions = opts
+		return nil
+	}
+}
+
+// SetSpool specifies a spool that batches which failed to ingest because of a
+// temporary error are written to. Spooled batches are replayed once the Axiom
+// deployment is reachable again.
+func SetSpool(spool *axiom.Spool) Option {
+	return func(w *Writer) error {
+		w.spool = spool
+		return nil
+	}
+}
+
+// Writer implements a `zerolog.LevelWriter` used for shipping logs to Axiom.
+// The JSON encoded log lines written by zerolog are converted into events:
+// The timestamp, level and message fields, as configured by
+// `zerolog.TimestampFieldName`, `zerolog.LevelFieldName` and
+// `zerolog.MessageFieldName`, are mapped to the `_time`, `severity` and
+// `message` fields, just like the other adapters do.
+type Writer struct {
+	client      *axiom.Client
+	datasetName string
+
+	clientOptions []axiom.Option
+	errorHandler  adapters.ErrorHandler
+	ingestOptions axiom.IngestOptions
+	spool         *axiom.Spool
+
+	ingester *axiom.Ingester
+}
+
+// New creates a new `Writer` configured to ingest logs to the Axiom deployment
+// and dataset as specified by the environment. Refer to `axiom.NewClient()` for
+// more details on how configuring the Axiom deployment works or pass the
+// `SetClient()` option to pass a custom client or `SetClientOptions()` to
+// control the Axiom client creation. To specify the dataset set `AXIOM_DATASET`
+// or use the `SetDataset()` option.
+//
+// An API token with `axiom.CanIngest` permission is sufficient enough.
+//
+// Additional options can be supplied to configure the `Writer`.
+//
+// A writer needs to be closed properly to make sure all logs are sent by
+// calling `Close()`.
+func New(options ...Option) (*Writer, error) {
+	writer := &Writer{}
+
+	// Apply supplied options.
+	for _, option := range options {
+		if err := option(writer); err != nil {
+			return nil, err
+		}
+	}
+
+	// Create client, if not set.
+	if writer.client == nil {
+		var err error
+		if writer.client, err = axiom.NewClient(writer.clientOptions...); err != nil {
+			return nil, err
+		}
+	}
+
+	// When the dataset name is not set, use `AXIOM_DATASET`.
+	if writer.datasetName == "" {
+		writer.datasetName = os.Getenv("AXIOM_DATASET")
+		if writer.datasetName == "" {
+			return nil, ErrMissingDatasetName
+		}
+	}
+
+	// Create the ingester which batches the events and sends them in the
+	// background.
+	var err error
+	if writer.ingester, err = axiom.NewIngester(writer.client, writer.datasetName,
+		axiom.SetIngestOptions(writer.ingestOptions),
+		axiom.SetBatchSize(batchSize),
+		axiom.SetFlushInterval(sendInterval),
+		axiom.SetErrorHandler(writer.handleError),
+		axiom.SetSpool(writer.spool),
+	); err != nil {
+		return nil, err
+	}
+
+	return writer, nil
+}
+
+// Close the writer and make sure all events are flushed. Closing the writer
+// renders it unusable for further use.
+func (w *Writer) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return w.ingester.Close(ctx)
+}
+
+// Write implements `io.Writer`.
+func (w *Writer) Write(p []byte) (int, error) {
+	return w.WriteLevel(zerolog.NoLevel, p)
+}
+
+// WriteLevel implements `zerolog.LevelWriter`. The given level is used as
+// severity of log lines that carry no level field.
+func (w *Writer) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	dec := json.NewDecoder(bytes.NewReader(p))
+	dec.UseNumber()
+
+	event := axiom.Event{}
+	if err := dec.Decode(&event); err != nil {
+		return 0, fmt.Errorf("failed to decode log line: %w", err)
+	}
+
+	// Set timestamp, severity and actual message.
+	if v, ok := event[zerolog.TimestampFieldName]; ok {
+		delete(event, zerolog.TimestampFieldName)
+		event[axiom.TimestampField] = convertTimestamp(v)
+	}
+	if v, ok := event[zerolog.LevelFieldName]; ok {
+		delete(event, zerolog.LevelFieldName)
+		event["severity"] = v
+	} else if level != zerolog.NoLevel {
+		event["severity"] = level.String()
+	}
+	if v, ok := event[zerolog.MessageFieldName]; ok {
+		delete(event, zerolog.MessageFieldName)
+		event["message"] = v
+	}
+
+	if err := w.ingester.Ingest(context.Background(), event); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// convertTimestamp converts the value of a zerolog timestamp field, formatted
+// according to `zerolog.TimeFieldFormat`, into an RFC 3339 timestamp. Values
+// that can't be converted are returned as is.
+func convertTimestamp(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		n, err := v.Int64()
+		if err != nil {
+			return v
+		}
+
+		var ts time.Time
+		switch zerolog.TimeFieldFormat {
+		case zerolog.TimeFormatUnix:
+			ts = time.Unix(n, 0)
+		case zerolog.TimeFormatUnixMs:
+			ts = time.Unix(0, n*int64(time.Millisecond))
+		case zerolog.TimeFormatUnixMicro:
+			ts = time.Unix(0, n*int64(time.Microsecond))
+		default:
+			return v
+		}
+		return ts.UTC().Format(time.RFC3339Nano)
+	case string:
+		if ts, err := time.Parse(zerolog.TimeFieldFormat, v); err == nil {
+			return ts.Format(time.RFC3339Nano)
+		}
+	}
+	return v
+}
+
+func (w *Writer) handleError(err error, res *axiom.IngestStatus, events []axiom.Event) {
+	if w.errorHandler != nil {
+		w.errorHandler(err, res, events)
+		return
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to ingest batch of %d events: %s\n", len(events), err)
+	} else if res.Failed > 0 {
+		// Best effort on notifying the user about the ingest failure.
+		fmt.Fprintf(os.Stderr, "event at %s failed to ingest: %s\n",
+			res.Failures[0].Timestamp, res.Failures[0].Error)
+	}
+}
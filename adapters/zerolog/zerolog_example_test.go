@@ -0,0 +1,36 @@
+package zerolog_test
+
+import (
+	"log"
+
+	"github.com/rs/zerolog"
+
+	adapter "github.com/axiomhq/axiom-go/adapters/zerolog"
+)
+
+func Example() {
+	// Export `AXIOM_TOKEN`, `AXIOM_ORG_ID` (when using a personal token) and
+	// `AXIOM_DATASET` for Axiom Cloud.
+	// Export `AXIOM_URL`, `AXIOM_TOKEN` and `AXIOM_DATASET` for Axiom Selfhost.
+
+	// 1. Setup the Axiom writer for zerolog.
+	writer, err := adapter.New()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// 2. Have all logs flushed before the application exits.
+	defer func() {
+		if err := writer.Close(); err != nil {
+			log.Print(err)
+		}
+	}()
+
+	// 3. Create a logger that writes to Axiom.
+	logger := zerolog.New(writer).With().Timestamp().Logger()
+
+	// 4. Log ⚡
+	logger.Info().Str("mood", "hyped").Msg("This is awesome!")
+	logger.Warn().Str("mood", "worried").Msg("This is no that awesome...")
+	logger.Error().Str("mood", "depressed").Msg("This is rather bad.")
+}
@@ -0,0 +1,36 @@
+//go:build integration
+// +build integration
+
+package zerolog_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+
+	"github.com/axiomhq/axiom-go/adapters"
+	adapter "github.com/axiomhq/axiom-go/adapters/zerolog"
+	"github.com/axiomhq/axiom-go/axiom"
+)
+
+func Test(t *testing.T) {
+	adapters.TestAdapter(t, "zerolog", func(_ context.Context, dataset string, client *axiom.Client) {
+		writer, err := adapter.New(
+			adapter.SetClient(client),
+			adapter.SetDataset(dataset),
+		)
+		require.NoError(t, err)
+
+		defer func() {
+			require.NoError(t, writer.Close())
+		}()
+
+		logger := zerolog.New(writer).With().Timestamp().Logger()
+
+		logger.Info().Str("mood", "hyped").Msg("This is awesome!")
+		logger.Warn().Str("mood", "worried").Msg("This is no that awesome...")
+		logger.Error().Str("mood", "depressed").Msg("This is rather bad.")
+	})
+}
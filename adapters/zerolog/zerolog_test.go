@@ -0,0 +1,236 @@
+package zerolog
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/axiomhq/axiom-go/axiom"
+)
+
+// TestNew makes sure New() picks up the `AXIOM_DATASET` environment variable.
+func TestNew(t *testing.T) {
+	os.Clearenv()
+
+	os.Setenv("AXIOM_TOKEN", "xaat-test")
+	os.Setenv("AXIOM_ORG_ID", "123")
+
+	writer, err := New()
+	require.ErrorIs(t, err, ErrMissingDatasetName)
+	require.Nil(t, writer)
+
+	os.Setenv("AXIOM_DATASET", "test")
+
+	writer, err = New()
+	require.NoError(t, err)
+	require.NotNil(t, writer)
+	require.NoError(t, writer.Close())
+
+	assert.Equal(t, "test", writer.datasetName)
+}
+
+func TestWriter(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+
+	exp := fmt.Sprintf(`{"_time":"%s","severity":"info","key":"value","message":"my message"}`,
+		now.Format(time.RFC3339Nano))
+
+	var hasRun uint64
+	hf := func(w http.ResponseWriter, r *http.Request) {
+		gzr, err := gzip.NewReader(r.Body)
+		require.NoError(t, err)
+
+		b, err := io.ReadAll(gzr)
+		assert.NoError(t, err)
+
+		JSONEqExp(t, exp, string(b), nil)
+
+		atomic.AddUint64(&hasRun, 1)
+
+		_, _ = w.Write([]byte("{}"))
+	}
+
+	logger, teardown := setup(t, hf)
+	defer teardown()
+
+	zerolog.TimestampFunc = func() time.Time { return now }
+	defer func() { zerolog.TimestampFunc = time.Now }()
+
+	logger.Info().
+		Str("key", "value").
+		Msg("my message")
+
+	// Wait for timer based writer flush.
+	time.Sleep(1250 * time.Millisecond)
+
+	assert.EqualValues(t, 1, atomic.LoadUint64(&hasRun))
+}
+
+func TestWriter_FlushFullBatch(t *testing.T) {
+	var lines uint64
+	hf := func(w http.ResponseWriter, r *http.Request) {
+		gzr, err := gzip.NewReader(r.Body)
+		require.NoError(t, err)
+
+		s := bufio.NewScanner(gzr)
+		for s.Scan() {
+			atomic.AddUint64(&lines, 1)
+		}
+		assert.NoError(t, s.Err())
+
+		_, _ = w.Write([]byte("{}"))
+	}
+
+	logger, teardown := setup(t, hf)
+	defer teardown()
+
+	for i := 0; i <= 1024; i++ {
+		logger.Info().Msg("my message")
+	}
+
+	// Let the server process.
+	time.Sleep(250 * time.Millisecond)
+
+	// Should have a full batch right away.
+	assert.EqualValues(t, 1024, atomic.LoadUint64(&lines))
+
+	// Wait for timer based writer flush.
+	time.Sleep(1250 * time.Millisecond)
+
+	// Should have received the last event.
+	assert.EqualValues(t, 1025, atomic.LoadUint64(&lines))
+}
+
+func TestWriter_ErrorHandler(t *testing.T) {
+	hf := func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = fmt.Fprint(w, `{
+			"ingested": 0,
+			"failed": 2,
+			"failures": [
+				{"timestamp": "2022-01-01T00:00:00Z", "error": "first error"},
+				{"timestamp": "2022-01-01T00:00:01Z", "error": "second error"}
+			]
+		}`)
+	}
+
+	var (
+		calls  uint64
+		err    error
+		status *axiom.IngestStatus
+		events []axiom.Event
+	)
+	logger, teardown := setup(t, hf, SetErrorHandler(func(e error, s *axiom.IngestStatus, b []axiom.Event) {
+		atomic.AddUint64(&calls, 1)
+		err, status, events = e, s, b
+	}))
+
+	logger.Info().Msg("first")
+	logger.Info().Msg("second")
+
+	teardown()
+
+	require.EqualValues(t, 1, atomic.LoadUint64(&calls))
+	assert.NoError(t, err)
+	if assert.NotNil(t, status) && assert.Len(t, status.Failures, 2) {
+		assert.Equal(t, "first error", status.Failures[0].Error)
+		assert.Equal(t, "second error", status.Failures[1].Error)
+	}
+	if assert.Len(t, events, 2) {
+		assert.Equal(t, "first", events[0]["message"])
+		assert.Equal(t, "second", events[1]["message"])
+	}
+}
+
+func TestConvertTimestamp(t *testing.T) {
+	defer func(format string) { zerolog.TimeFieldFormat = format }(zerolog.TimeFieldFormat)
+
+	ts := time.Date(2022, 1, 2, 3, 4, 5, 6000, time.UTC)
+
+	tests := []struct {
+		format string
+		value  interface{}
+		want   interface{}
+	}{
+		{time.RFC3339, ts.Format(time.RFC3339), "2022-01-02T03:04:05Z"},
+		{time.RFC3339Nano, ts.Format(time.RFC3339Nano), "2022-01-02T03:04:05.000006Z"},
+		{time.RFC3339, "not a time", "not a time"},
+		{zerolog.TimeFormatUnix, json.Number("1641092645"), "2022-01-02T03:04:05Z"},
+		{zerolog.TimeFormatUnixMs, json.Number("1641092645000"), "2022-01-02T03:04:05Z"},
+		{zerolog.TimeFormatUnixMicro, json.Number("1641092645000006"), "2022-01-02T03:04:05.000006Z"},
+		{time.RFC3339, json.Number("1641092645"), json.Number("1641092645")},
+		{zerolog.TimeFormatUnix, json.Number("1.5"), json.Number("1.5")},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s/%v", tt.format, tt.value), func(t *testing.T) {
+			zerolog.TimeFieldFormat = tt.format
+			assert.Equal(t, tt.want, convertTimestamp(tt.value))
+		})
+	}
+}
+
+// setup sets up a test HTTP server along with a zerolog logger that is
+// configured to talk to that test server through an Axiom writer. Tests should
+// pass a handler function which provides the response for the API method being
+// tested.
+func setup(t *testing.T, h http.HandlerFunc, options ...Option) (*zerolog.Logger, func()) {
+	t.Helper()
+
+	srv := httptest.NewServer(h)
+
+	client, err := axiom.NewClient(
+		axiom.SetURL(srv.URL),
+		axiom.SetAccessToken("xaat-test"),
+		axiom.SetClient(srv.Client()),
+	)
+	require.NoError(t, err)
+
+	writer, err := New(append([]Option{
+		SetClient(client),
+		SetDataset("test"),
+	}, options...)...)
+	require.NoError(t, err)
+
+	logger := zerolog.New(writer).With().Timestamp().Logger()
+
+	return &logger, func() { _ = writer.Close(); srv.Close() }
+}
+
+// JSONEqExp is like assert.JSONEq() but excludes the given fields.
+func JSONEqExp(t assert.TestingT, expected string, actual string, excludedFields []string, msgAndArgs ...interface{}) bool {
+	type tHelper interface {
+		Helper()
+	}
+
+	if h, ok := t.(tHelper); ok {
+		h.Helper()
+	}
+
+	var expectedJSONAsInterface, actualJSONAsInterface map[string]interface{}
+
+	if err := json.Unmarshal([]byte(expected), &expectedJSONAsInterface); err != nil {
+		return assert.Fail(t, fmt.Sprintf("Expected value ('%s') is not valid json.\nJSON parsing error: '%s'", expected, err.Error()), msgAndArgs...)
+	}
+
+	if err := json.Unmarshal([]byte(actual), &actualJSONAsInterface); err != nil {
+		return assert.Fail(t, fmt.Sprintf("Input ('%s') needs to be valid json.\nJSON parsing error: '%s'", actual, err.Error()), msgAndArgs...)
+	}
+
+	for _, excludedField := range excludedFields {
+		delete(expectedJSONAsInterface, excludedField)
+		delete(actualJSONAsInterface, excludedField)
+	}
+
+	return assert.Equal(t, expectedJSONAsInterface, actualJSONAsInterface, msgAndArgs...)
+}
@@ -1,6 +1,7 @@
 package apl
 
 import (
+	"encoding/json"
 	"net/url"
 )
 
@@ -11,12 +12,50 @@ type Format uint8
 
 // All available query formats.
 const (
-	Legacy Format = iota // legacy
+	Legacy  Format = iota // legacy
+	Tabular               // tabular
+
+	// UnknownFormat is the format of results whose format is not known to
+	// the client, e.g. because it was introduced by a later server version.
+	UnknownFormat // unknown
 )
 
+func formatFromString(s string) (f Format) {
+	switch s {
+	case Legacy.String():
+		f = Legacy
+	case Tabular.String():
+		f = Tabular
+	default:
+		f = UnknownFormat
+	}
+
+	return f
+}
+
 // EncodeValues implements `query.Encoder`. It is in place to encode the Format
 // into a string URL value because that's what the server expects.
 func (f Format) EncodeValues(key string, v *url.Values) error {
 	v.Set(key, f.String())
 	return nil
 }
+
+// MarshalJSON implements `json.Marshaler`. It is in place to marshal the Format
+// to its string representation because that's what the server expects.
+func (f Format) MarshalJSON() ([]byte, error) {
+	return json.Marshal(f.String())
+}
+
+// UnmarshalJSON implements `json.Unmarshaler`. It is in place to unmarshal the
+// Format from the string representation the server returns. Formats not known
+// to the client are unmarshalled as UnknownFormat.
+func (f *Format) UnmarshalJSON(b []byte) (err error) {
+	var s string
+	if err = json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+
+	*f = formatFromString(s)
+
+	return nil
+}
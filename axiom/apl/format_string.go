@@ -9,11 +9,13 @@ func _() {
 	// Re-run the stringer command to generate them again.
 	var x [1]struct{}
 	_ = x[Legacy-0]
+	_ = x[Tabular-1]
+	_ = x[UnknownFormat-2]
 }
 
-const _Format_name = "legacy"
+const _Format_name = "legacytabularunknown"
 
-var _Format_index = [...]uint8{0, 6}
+var _Format_index = [...]uint8{0, 6, 13, 20}
 
 func (i Format) String() string {
 	if i >= Format(len(_Format_index)-1) {
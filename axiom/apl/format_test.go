@@ -1,6 +1,7 @@
 package apl
 
 import (
+	"encoding/json"
 	"net/url"
 	"testing"
 
@@ -14,6 +15,8 @@ func TestFormat_EncodeValues(t *testing.T) {
 		exp   string
 	}{
 		{Legacy, "legacy"},
+		{Tabular, "tabular"},
+		{UnknownFormat, "unknown"},
 		// {0, "Format(0)"}, // HINT(lukasmalkmus): Maybe we want to sort this out by raising an error?
 	}
 	for _, tt := range tests {
@@ -31,11 +34,27 @@ func TestFormat_String(t *testing.T) {
 	// Check outer bounds.
 	// assert.Equal(t, Format(0).String(), "Format(0)")
 	// assert.Contains(t, (Legacy - 1).String(), "Format(")
-	assert.Contains(t, (Legacy + 1).String(), "Format(")
+	assert.Contains(t, (UnknownFormat + 1).String(), "Format(")
 
-	for c := Legacy; c <= Legacy; c++ {
+	for c := Legacy; c <= UnknownFormat; c++ {
 		s := c.String()
 		assert.NotEmpty(t, s)
 		assert.NotContains(t, s, "Format(")
 	}
 }
+
+func TestFormat_Unmarshal(t *testing.T) {
+	var act struct {
+		Format Format `json:"format"`
+	}
+	err := json.Unmarshal([]byte(`{ "format": "tabular" }`), &act)
+	require.NoError(t, err)
+
+	assert.Equal(t, Tabular, act.Format)
+
+	// Formats introduced by later server versions must not break decoding.
+	err = json.Unmarshal([]byte(`{ "format": "foo" }`), &act)
+	require.NoError(t, err)
+
+	assert.Equal(t, UnknownFormat, act.Format)
+}
@@ -20,6 +20,7 @@ type Options struct {
 	// any value for the `saveAsKind` query param. For user experience, we use a
 	// bool here instead of forcing the user to set the value to `query.APL`.
 	Save bool `url:"saveAsKind,omitempty"`
-	// Format specifies the format of the APL query. Defaults to Legacy.
+	// Format specifies the format of the APL query. Defaults to Legacy. Use
+	// Tabular to receive the result as typed tables.
 	Format Format `url:"format"`
 }
@@ -3,7 +3,9 @@ package apl
 import "github.com/axiomhq/axiom-go/axiom/query"
 
 // Result is the result of an APL query. It adds the APL query request alongside
-// the query result it created, making it a superset of `query.Result`
+// the query result it created, making it a superset of `query.Result`. Results
+// of queries using the Tabular format carry their data in Tables instead of the
+// matches and buckets of the `query.Result`.
 type Result struct {
 	*query.Result
 
@@ -11,4 +13,9 @@ type Result struct {
 	Request *query.Query `json:"request"`
 	// The datasets that were queried in order to create the result.
 	Datasets []string `json:"datasetNames"`
+	// Format of the result.
+	Format Format `json:"format"`
+	// Tables are the tables of the result. Only populated for the Tabular
+	// format.
+	Tables []Table `json:"tables"`
 }
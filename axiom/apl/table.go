@@ -0,0 +1,260 @@
+package apl
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/axiomhq/axiom-go/axiom/internal/scan"
+)
+
+// A FieldType describes the type of the values of a table column. A column can
+// hold values of multiple types in which case the types are combined.
+type FieldType uint16
+
+// All available field types.
+const (
+	TypeUnknown FieldType = 1 << iota
+	TypeString
+	TypeInteger
+	TypeFloat
+	TypeBoolean
+	TypeDatetime
+	TypeTimespan
+	TypeArray
+	TypeDictionary
+)
+
+var fieldTypeNames = []struct {
+	typ  FieldType
+	name string
+}{
+	{TypeUnknown, "unknown"},
+	{TypeString, "string"},
+	{TypeInteger, "integer"},
+	{TypeFloat, "float"},
+	{TypeBoolean, "boolean"},
+	{TypeDatetime, "datetime"},
+	{TypeTimespan, "timespan"},
+	{TypeArray, "array"},
+	{TypeDictionary, "dictionary"},
+}
+
+func fieldTypeFromString(s string) (ft FieldType, err error) {
+	for _, part := range strings.Split(s, "|") {
+		switch strings.ToLower(strings.TrimSpace(part)) {
+		case "unknown", "":
+			ft |= TypeUnknown
+		case "string":
+			ft |= TypeString
+		case "integer", "int", "long":
+			ft |= TypeInteger
+		case "float", "real", "double":
+			ft |= TypeFloat
+		case "boolean", "bool":
+			ft |= TypeBoolean
+		case "datetime", "timestamp":
+			ft |= TypeDatetime
+		case "timespan":
+			ft |= TypeTimespan
+		case "array":
+			ft |= TypeArray
+		case "dictionary", "object", "dynamic":
+			ft |= TypeDictionary
+		default:
+			return 0, fmt.Errorf("unknown field type %q", part)
+		}
+	}
+
+	return ft, nil
+}
+
+// Has returns true if the field type includes the given type.
+func (ft FieldType) Has(typ FieldType) bool {
+	return ft&typ != 0
+}
+
+// String returns the string representation of the field type. Combined types
+// are separated by a pipe, e.g. `integer|float`.
+func (ft FieldType) String() string {
+	var names []string
+	for _, n := range fieldTypeNames {
+		if ft.Has(n.typ) {
+			names = append(names, n.name)
+		}
+	}
+
+	if len(names) == 0 || ft>>len(fieldTypeNames) != 0 {
+		return fmt.Sprintf("FieldType(%d)", uint16(ft))
+	}
+
+	return strings.Join(names, "|")
+}
+
+// MarshalJSON implements `json.Marshaler`. It is in place to marshal the
+// FieldType to its string representation because that's what the server
+// expects.
+func (ft FieldType) MarshalJSON() ([]byte, error) {
+	return json.Marshal(ft.String())
+}
+
+// UnmarshalJSON implements `json.Unmarshaler`. It is in place to unmarshal the
+// FieldType from the string representation the server returns.
+func (ft *FieldType) UnmarshalJSON(b []byte) (err error) {
+	var s string
+	if err = json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+
+	*ft, err = fieldTypeFromString(s)
+
+	return err
+}
+
+// Table is a table of a tabular APL query result. Its values are stored by
+// column, in the order of the fields.
+type Table struct {
+	// Name of the table.
+	Name string `json:"name"`
+	// Sources are the datasets the table was created from.
+	Sources []Source `json:"sources"`
+	// Fields are the named and typed columns of the table.
+	Fields []Field `json:"fields"`
+	// Order of the rows of the table.
+	Order []Order `json:"order"`
+	// Groups are the fields the table is grouped by.
+	Groups []Group `json:"groups"`
+	// Range is the time range the table covers, if any.
+	Range *Range `json:"range"`
+	// Buckets is the time bucketing the table is created with, if any.
+	Buckets *Buckets `json:"buckets"`
+	// Columns are the values of the table. The n-th column holds the values of
+	// the n-th field.
+	Columns [][]interface{} `json:"columns"`
+}
+
+// Source is a dataset a table was created from.
+type Source struct {
+	// Name of the dataset.
+	Name string `json:"name"`
+}
+
+// Field is a named and typed column of a table.
+type Field struct {
+	// Name of the field.
+	Name string `json:"name"`
+	// Type of the field.
+	Type FieldType `json:"type"`
+	// Aggregation that produced the values of the field, if any.
+	Aggregation *Aggregation `json:"agg"`
+}
+
+// Aggregation is the aggregation that produced the values of a field.
+type Aggregation struct {
+	// Name of the aggregation function.
+	Name string `json:"name"`
+	// Fields the aggregation is performed on.
+	Fields []string `json:"fields"`
+	// Args are additional arguments to the aggregation.
+	Args []interface{} `json:"args"`
+}
+
+// Order specifies the order of a tables rows.
+type Order struct {
+	// Field the rows are ordered by.
+	Field string `json:"field"`
+	// Desc specifies if the field is ordered ascending or descending.
+	Desc bool `json:"desc"`
+}
+
+// Group is a field a table is grouped by.
+type Group struct {
+	// Name of the field.
+	Name string `json:"name"`
+}
+
+// Range is the time range a table covers.
+type Range struct {
+	// Field the range applies to.
+	Field string `json:"field"`
+	// Start of the range.
+	Start string `json:"start"`
+	// End of the range.
+	End string `json:"end"`
+}
+
+// Buckets describes the time bucketing a table is created with.
+type Buckets struct {
+	// Field the bucketing is applied on.
+	Field string `json:"field"`
+	// Size of a bucket.
+	Size interface{} `json:"size"`
+}
+
+// NumRows returns the number of rows of the table.
+func (t Table) NumRows() int {
+	if len(t.Columns) == 0 {
+		return 0
+	}
+	return len(t.Columns[0])
+}
+
+// FieldIndex returns the index of the field with the given name or -1, if the
+// table has no such field.
+func (t Table) FieldIndex(name string) int {
+	for i, f := range t.Fields {
+		if f.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Column returns the values of the field with the given name. It returns false
+// if the table has no such field.
+func (t Table) Column(name string) ([]interface{}, bool) {
+	if i := t.FieldIndex(name); i >= 0 && i < len(t.Columns) {
+		return t.Columns[i], true
+	}
+	return nil, false
+}
+
+// Row returns the values of the i-th row, in the order of the fields.
+func (t Table) Row(i int) []interface{} {
+	row := make([]interface{}, len(t.Columns))
+	for j, col := range t.Columns {
+		if i < len(col) {
+			row[j] = col[i]
+		}
+	}
+	return row
+}
+
+// RowMap returns the values of the i-th row mapped by field name.
+func (t Table) RowMap(i int) map[string]interface{} {
+	row := make(map[string]interface{}, len(t.Fields))
+	for j, f := range t.Fields {
+		if j < len(t.Columns) && i < len(t.Columns[j]) {
+			row[f.Name] = t.Columns[j][i]
+		}
+	}
+	return row
+}
+
+// ScanRow scans the i-th row into the struct pointed to by dest. Struct fields
+// are matched to table fields by the name given in their `axiom` tag or, if
+// not tagged, by their name. Values are converted into the type of the struct
+// field. Timestamps are parsed into `time.Time` values.
+func (t Table) ScanRow(i int, dest interface{}) error {
+	if i < 0 || i >= t.NumRows() {
+		return fmt.Errorf("row %d out of range [0, %d)", i, t.NumRows())
+	}
+	return scan.Into(dest, t.RowMap(i))
+}
+
+// ScanRows scans all rows into the slice pointed to by dest. The elements of
+// the slice must be structs or pointers to structs. Refer to `ScanRow()` for
+// how values are scanned.
+func (t Table) ScanRows(dest interface{}) error {
+	return scan.Slice(dest, t.NumRows(), t.RowMap)
+}
@@ -0,0 +1,173 @@
+package apl
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const actTable = `{
+	"name": "0",
+	"sources": [
+		{
+			"name": "test"
+		}
+	],
+	"fields": [
+		{
+			"name": "_time",
+			"type": "datetime"
+		},
+		{
+			"name": "host",
+			"type": "string"
+		},
+		{
+			"name": "count_",
+			"type": "integer",
+			"agg": {
+				"name": "count"
+			}
+		},
+		{
+			"name": "avg_duration",
+			"type": "integer|float",
+			"agg": {
+				"name": "avg",
+				"fields": ["duration"]
+			}
+		}
+	],
+	"order": [
+		{
+			"field": "_time",
+			"desc": true
+		}
+	],
+	"groups": [
+		{
+			"name": "host"
+		}
+	],
+	"range": {
+		"field": "_time",
+		"start": "2022-01-01T00:00:00Z",
+		"end": "2022-01-01T01:00:00Z"
+	},
+	"buckets": {
+		"field": "_time",
+		"size": 60000000000
+	},
+	"columns": [
+		["2022-01-01T00:00:00Z", "2022-01-01T00:01:00Z"],
+		["a", "b"],
+		[10, 20],
+		[1.5, null]
+	]
+}`
+
+func TestTable(t *testing.T) {
+	var table Table
+	require.NoError(t, json.Unmarshal([]byte(actTable), &table))
+
+	assert.Equal(t, "0", table.Name)
+	assert.Equal(t, []Source{{Name: "test"}}, table.Sources)
+	assert.Equal(t, TypeDatetime, table.Fields[0].Type)
+	assert.Equal(t, TypeInteger|TypeFloat, table.Fields[3].Type)
+	assert.Equal(t, &Aggregation{Name: "avg", Fields: []string{"duration"}}, table.Fields[3].Aggregation)
+	assert.Equal(t, []Group{{Name: "host"}}, table.Groups)
+
+	assert.Equal(t, 2, table.NumRows())
+	assert.Equal(t, 1, table.FieldIndex("host"))
+	assert.Equal(t, -1, table.FieldIndex("foo"))
+
+	col, ok := table.Column("host")
+	require.True(t, ok)
+	assert.Equal(t, []interface{}{"a", "b"}, col)
+
+	_, ok = table.Column("foo")
+	assert.False(t, ok)
+
+	assert.Equal(t, []interface{}{"2022-01-01T00:01:00Z", "b", float64(20), nil}, table.Row(1))
+	assert.Equal(t, map[string]interface{}{
+		"_time":        "2022-01-01T00:00:00Z",
+		"host":         "a",
+		"count_":       float64(10),
+		"avg_duration": 1.5,
+	}, table.RowMap(0))
+}
+
+func TestTable_ScanRows(t *testing.T) {
+	var table Table
+	require.NoError(t, json.Unmarshal([]byte(actTable), &table))
+
+	type row struct {
+		Time        time.Time `axiom:"_time"`
+		Host        string
+		Count       uint64   `axiom:"count_"`
+		AvgDuration *float64 `axiom:"avg_duration"`
+		Ignored     string   `axiom:"-"`
+	}
+
+	var rows []row
+	require.NoError(t, table.ScanRows(&rows))
+
+	avg := 1.5
+	assert.Equal(t, []row{
+		{
+			Time:        time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+			Host:        "a",
+			Count:       10,
+			AvgDuration: &avg,
+		},
+		{
+			Time:  time.Date(2022, 1, 1, 0, 1, 0, 0, time.UTC),
+			Host:  "b",
+			Count: 20,
+		},
+	}, rows)
+
+	var r row
+	require.NoError(t, table.ScanRow(1, &r))
+	assert.Equal(t, rows[1], r)
+
+	assert.EqualError(t, table.ScanRow(2, &r), "row 2 out of range [0, 2)")
+
+	var bad []struct {
+		Host int
+	}
+	assert.EqualError(t, table.ScanRows(&bad), `row 0: field "Host": cannot scan string value a into int`)
+}
+
+func TestFieldType_String(t *testing.T) {
+	assert.Equal(t, "string", TypeString.String())
+	assert.Equal(t, "integer|float", (TypeInteger | TypeFloat).String())
+	assert.Equal(t, "FieldType(0)", FieldType(0).String())
+	assert.Contains(t, (TypeDictionary << 1).String(), "FieldType(")
+
+	for typ := TypeUnknown; typ <= TypeDictionary; typ <<= 1 {
+		s := typ.String()
+		assert.NotEmpty(t, s)
+		assert.NotContains(t, s, "FieldType(")
+
+		parsed, err := fieldTypeFromString(s)
+		require.NoError(t, err)
+		assert.Equal(t, typ, parsed)
+	}
+}
+
+func TestFieldType_Unmarshal(t *testing.T) {
+	var act struct {
+		Type FieldType `json:"type"`
+	}
+	err := json.Unmarshal([]byte(`{ "type": "integer|float" }`), &act)
+	require.NoError(t, err)
+
+	assert.Equal(t, TypeInteger|TypeFloat, act.Type)
+
+	err = json.Unmarshal([]byte(`{ "type": "foo" }`), &act)
+	assert.EqualError(t, err, `unknown field type "foo"`)
+}
@@ -0,0 +1,496 @@
+package axiomtest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/axiomhq/axiom-go/axiom"
+)
+
+// dataset is a dataset and the events stored in it.
+type dataset struct {
+	axiom.Dataset
+
+	events     []event
+	inputBytes uint64
+}
+
+// event is an event stored in a dataset.
+type event struct {
+	time    time.Time
+	sysTime time.Time
+	rowID   string
+	// data are the fields of the event, except for its time.
+	data axiom.Event
+}
+
+// CreateDataset creates a dataset with the given name, if it doesn't exist,
+// yet.
+func (s *Server) CreateDataset(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.datasets[name]; !ok {
+		s.addDataset(axiom.DatasetCreateRequest{Name: name})
+	}
+}
+
+// AddEvents adds the given events to the dataset with the given name, as if
+// they were ingested with default options. The dataset is created, if it
+// doesn't exist, yet.
+func (s *Server) AddEvents(name string, events ...axiom.Event) *axiom.IngestStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ds, ok := s.datasets[name]
+	if !ok {
+		ds = s.addDataset(axiom.DatasetCreateRequest{Name: name})
+	}
+
+	data := make([]map[string]interface{}, len(events))
+	for i, ev := range events {
+		data[i] = make(map[string]interface{}, len(ev))
+		for k, v := range ev {
+			data[i][k] = v
+		}
+	}
+
+	return s.ingest(ds, data, 0, axiom.IngestOptions{})
+}
+
+// Events returns the events stored in the dataset with the given name, in the
+// order they have been ingested. The time of an event is set as a `time.Time`
+// on the `_time` field. It returns nil, if the dataset doesn't exist.
+func (s *Server) Events(name string) []axiom.Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ds, ok := s.datasets[name]
+	if !ok {
+		return nil
+	}
+
+	res := make([]axiom.Event, len(ds.events))
+	for i, ev := range ds.events {
+		res[i] = make(axiom.Event, len(ev.data)+1)
+		for k, v := range ev.data {
+			res[i][k] = v
+		}
+		res[i][axiom.TimestampField] = ev.time
+	}
+	return res
+}
+
+// Datasets returns the names of all datasets, sorted by name.
+func (s *Server) Datasets() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.sortedDatasets()
+}
+
+// addDataset adds a dataset with the given properties. The server must be
+// locked.
+func (s *Server) addDataset(req axiom.DatasetCreateRequest) *dataset {
+	ds := &dataset{
+		Dataset: axiom.Dataset{
+			ID:          req.Name,
+			Name:        req.Name,
+			Description: req.Description,
+			CreatedBy:   s.currentUser.ID,
+			CreatedAt:   s.now().UTC(),
+		},
+	}
+	s.datasets[req.Name] = ds
+	return ds
+}
+
+// serveDatasets serves the "/api/v1/datasets" endpoints.
+func (s *Server) serveDatasets(w http.ResponseWriter, r *http.Request, tok *token, path []string) {
+	switch {
+	case len(path) == 0 && r.Method == http.MethodGet:
+		s.mu.Lock()
+		res := make([]*axiom.Dataset, 0, len(s.datasets))
+		for _, name := range s.sortedDatasets() {
+			ds := s.datasets[name].Dataset
+			res = append(res, &ds)
+		}
+		s.mu.Unlock()
+
+		writeJSON(w, http.StatusOK, res)
+	case len(path) == 0 && r.Method == http.MethodPost:
+		var req axiom.DatasetCreateRequest
+		if !decodeJSON(w, r, &req) {
+			return
+		} else if req.Name == "" {
+			writeError(w, http.StatusBadRequest, "dataset name is required")
+			return
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if _, ok := s.datasets[req.Name]; ok {
+			writeError(w, http.StatusConflict, "dataset exists")
+			return
+		}
+		writeJSON(w, http.StatusOK, s.addDataset(req).Dataset)
+	case len(path) == 0:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	case len(path) == 1 && path[0] == "_stats" && r.Method == http.MethodGet:
+		s.mu.Lock()
+		var res axiom.DatasetStats
+		for _, name := range s.sortedDatasets() {
+			info := s.datasets[name].info()
+			res.Datasets = append(res.Datasets, info)
+			res.NumEvents += info.NumEvents
+			res.InputBytes += info.InputBytes
+		}
+		s.mu.Unlock()
+
+		writeJSON(w, http.StatusOK, res)
+	case len(path) == 1 && path[0] == "_apl":
+		writeError(w, http.StatusBadRequest, "apl queries are not supported by axiomtest")
+	case len(path) <= 2:
+		s.serveDataset(w, r, tok, path[0], path[1:])
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// serveDataset serves the endpoints of the dataset identified by the given id.
+func (s *Server) serveDataset(w http.ResponseWriter, r *http.Request, tok *token, id string, path []string) {
+	s.mu.Lock()
+	ds, ok := s.datasets[id]
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, "dataset not found")
+		return
+	}
+
+	switch {
+	case len(path) == 1 && path[0] == "ingest" && r.Method == http.MethodPost:
+		if !tok.allows(axiom.CanIngest, id) {
+			writeError(w, http.StatusForbidden, "forbidden")
+			return
+		}
+		s.serveIngest(w, r, ds)
+	case len(path) == 1 && path[0] == "query" && r.Method == http.MethodPost:
+		if !tok.allows(axiom.CanQuery, id) {
+			writeError(w, http.StatusForbidden, "forbidden")
+			return
+		}
+		s.serveQuery(w, r, ds)
+	case len(path) == 1 && path[0] == "info" && r.Method == http.MethodGet:
+		s.mu.Lock()
+		res := ds.info()
+		s.mu.Unlock()
+
+		writeJSON(w, http.StatusOK, res)
+	case len(path) > 0:
+		writeError(w, http.StatusNotFound, "not found")
+	case r.Method == http.MethodGet:
+		s.mu.Lock()
+		res := ds.Dataset
+		s.mu.Unlock()
+
+		writeJSON(w, http.StatusOK, res)
+	case r.Method == http.MethodPut:
+		var req axiom.DatasetUpdateRequest
+		if !decodeJSON(w, r, &req) {
+			return
+		}
+
+		s.mu.Lock()
+		ds.Description = req.Description
+		res := ds.Dataset
+		s.mu.Unlock()
+
+		writeJSON(w, http.StatusOK, res)
+	case r.Method == http.MethodDelete:
+		s.mu.Lock()
+		delete(s.datasets, id)
+		s.mu.Unlock()
+
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// sortedDatasets returns the names of all datasets, sorted by name. The server
+// must be locked.
+func (s *Server) sortedDatasets() []string {
+	names := make([]string, 0, len(s.datasets))
+	for name := range s.datasets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// info returns the information about the dataset. The server must be locked.
+func (ds *dataset) info() *axiom.DatasetInfo {
+	info := &axiom.DatasetInfo{
+		Name:       ds.Name,
+		NumEvents:  uint64(len(ds.events)),
+		InputBytes: ds.inputBytes,
+		CreatedBy:  ds.CreatedBy,
+		CreatedAt:  ds.CreatedAt,
+	}
+
+	fields := map[string]string{axiom.TimestampField: "datetime"}
+	for _, ev := range ds.events {
+		if info.MinTime.IsZero() || ev.time.Before(info.MinTime) {
+			info.MinTime = ev.time
+		}
+		if ev.time.After(info.MaxTime) {
+			info.MaxTime = ev.time
+		}
+		for k, v := range ev.data {
+			fields[k] = fieldType(v)
+		}
+	}
+
+	if len(ds.events) > 0 {
+		for name, typ := range fields {
+			info.Fields = append(info.Fields, axiom.Field{Name: name, Type: typ})
+		}
+		sort.Slice(info.Fields, func(i, j int) bool {
+			return info.Fields[i].Name < info.Fields[j].Name
+		})
+		info.NumFields = uint32(len(info.Fields))
+	}
+
+	return info
+}
+
+// fieldType returns the name of the type of the given field value.
+func fieldType(v interface{}) string {
+	switch v.(type) {
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64, float32, json.Number:
+		return "float"
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return "integer"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}, axiom.Event:
+		return "map"
+	}
+	return "unknown"
+}
+
+// serveIngest serves the ingest endpoint of the given dataset.
+func (s *Server) serveIngest(w http.ResponseWriter, r *http.Request, ds *dataset) {
+	var opts axiom.IngestOptions
+	q := r.URL.Query()
+	opts.TimestampField = q.Get("timestamp-field")
+	opts.TimestampFormat = q.Get("timestamp-format")
+	opts.CSVDelimiter = q.Get("csv-delimiter")
+
+	body, err := decompress(r.Body, r.Header.Get("Content-Encoding"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "read request body: "+err.Error())
+		return
+	}
+
+	events, err := decodeEvents(data, r.Header.Get("Content-Type"), opts)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	res := s.ingest(ds, events, len(data), opts)
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, res)
+}
+
+// ingest adds the given events to the dataset. The server must be locked.
+func (s *Server) ingest(ds *dataset, events []map[string]interface{}, size int, opts axiom.IngestOptions) *axiom.IngestStatus {
+	timestampField := opts.TimestampField
+	if timestampField == "" {
+		timestampField = axiom.TimestampField
+	}
+
+	now := s.now().UTC()
+	res := &axiom.IngestStatus{
+		Failures:       []*axiom.IngestFailure{},
+		ProcessedBytes: uint64(size),
+	}
+	for _, data := range events {
+		ts, err := parseTimestamp(data[timestampField], opts.TimestampFormat, now)
+		if err != nil {
+			res.Failed++
+			res.Failures = append(res.Failures, &axiom.IngestFailure{
+				Timestamp: now,
+				Error:     err.Error(),
+			})
+			continue
+		}
+		delete(data, timestampField)
+		delete(data, axiom.TimestampField)
+
+		if s.validate != nil {
+			if err = s.validate(ds.Name, axiom.Event(data)); err != nil {
+				res.Failed++
+				res.Failures = append(res.Failures, &axiom.IngestFailure{
+					Timestamp: ts,
+					Error:     err.Error(),
+				})
+				continue
+			}
+		}
+
+		ds.events = append(ds.events, event{
+			time:    ts,
+			sysTime: now,
+			rowID:   strconv.Itoa(len(ds.events)),
+			data:    data,
+		})
+		res.Ingested++
+	}
+
+	ds.inputBytes += uint64(size)
+	res.WALLength = uint32(len(ds.events))
+
+	return res
+}
+
+// parseTimestamp returns the time of an event given the value of its
+// timestamp field. If the event has no timestamp, the given current time is
+// returned.
+func parseTimestamp(v interface{}, format string, now time.Time) (time.Time, error) {
+	switch v := v.(type) {
+	case nil:
+		return now, nil
+	case time.Time:
+		return v.UTC(), nil
+	case *time.Time:
+		if v == nil {
+			return now, nil
+		}
+		return v.UTC(), nil
+	case string:
+		if format == "" {
+			format = time.RFC3339Nano
+		}
+		ts, err := time.Parse(format, v)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid timestamp %q: %w", v, err)
+		}
+		return ts.UTC(), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid timestamp of type %T", v)
+}
+
+// decompress returns a reader that decompresses the given body according to
+// the given content encoding.
+func decompress(body io.ReadCloser, encoding string) (io.ReadCloser, error) {
+	switch encoding {
+	case "", "identity":
+		return body, nil
+	case "gzip":
+		gzr, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip data: %w", err)
+		}
+		return gzr, nil
+	case "zstd":
+		zr, err := zstd.NewReader(body, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, fmt.Errorf("invalid zstd data: %w", err)
+		}
+		return zr.IOReadCloser(), nil
+	}
+	return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+}
+
+// decodeEvents decodes the events of the given data, formatted according to
+// the given content type.
+func decodeEvents(data []byte, contentType string, opts axiom.IngestOptions) ([]map[string]interface{}, error) {
+	var events []map[string]interface{}
+	switch contentType {
+	case axiom.JSON.String():
+		dec := json.NewDecoder(bytes.NewReader(data))
+		if bytes.HasPrefix(bytes.TrimSpace(data), []byte{'{'}) {
+			// A single object is accepted as well.
+			var ev map[string]interface{}
+			if err := dec.Decode(&ev); err != nil {
+				return nil, fmt.Errorf("invalid json: %w", err)
+			}
+			return []map[string]interface{}{ev}, nil
+		}
+		if err := dec.Decode(&events); err != nil {
+			return nil, fmt.Errorf("invalid json: %w", err)
+		}
+	case axiom.NDJSON.String():
+		dec := json.NewDecoder(bytes.NewReader(data))
+		for {
+			var ev map[string]interface{}
+			if err := dec.Decode(&ev); errors.Is(err, io.EOF) {
+				break
+			} else if err != nil {
+				return nil, fmt.Errorf("invalid ndjson: %w", err)
+			}
+			events = append(events, ev)
+		}
+	case axiom.CSV.String():
+		// Unlike the Axiom API, values are not converted to other types but
+		// kept as strings.
+		r := csv.NewReader(bytes.NewReader(data))
+		if opts.CSVDelimiter != "" {
+			r.Comma, _ = utf8.DecodeRuneInString(opts.CSVDelimiter)
+		}
+
+		records, err := r.ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("invalid csv: %w", err)
+		}
+		for _, record := range records[min(1, len(records)):] {
+			ev := make(map[string]interface{}, len(record))
+			for i, name := range records[0] {
+				if i < len(record) {
+					ev[name] = record[i]
+				}
+			}
+			events = append(events, ev)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported content type %q", contentType)
+	}
+
+	return events, nil
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,36 @@
+// Package axiomtest provides an in-memory fake of the Axiom API for hermetic
+// tests of code that uses the `axiom.Client`.
+//
+// A `Server` implements a subset of the Axiom API:
+//
+//   - Datasets: List, Get, Create, Update, Delete, Info, Stats and ingestion
+//     of JSON, NDJSON and CSV data, optionally gzip or zstd compressed.
+//   - Queries: The events of a dataset are filtered by time range and the
+//     `query.Filter` of a query. Aggregations are not supported.
+//   - API and personal tokens.
+//   - Users, including the authenticated user.
+//   - The version endpoint.
+//
+// Requests to any other endpoint fail with a 404 error. Requests must carry a
+// token that is known to the server. API tokens are checked for their scopes
+// and permissions.
+//
+// Create a server using `NewServer()` and a client configured to talk to it
+// using `Server.Client()`:
+//
+//	srv := axiomtest.NewServer()
+//	defer srv.Close()
+//
+//	srv.CreateDataset("logs")
+//
+//	client, err := srv.Client()
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//
+//	// Run the code under test with the client.
+//
+//	events := srv.Events("logs")
+//
+// The server is safe for concurrent use.
+package axiomtest
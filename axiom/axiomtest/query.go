@@ -0,0 +1,359 @@
+package axiomtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/axiomhq/axiom-go/axiom"
+	"github.com/axiomhq/axiom-go/axiom/query"
+)
+
+// serveQuery serves the query endpoint of the given dataset. The events of
+// the dataset are filtered by the time range and the filter of the query and
+// are returned as matches, newest first. A limit of zero returns all matching
+// events.
+func (s *Server) serveQuery(w http.ResponseWriter, r *http.Request, ds *dataset) {
+	var q query.Query
+	if !decodeJSON(w, r, &q) {
+		return
+	}
+
+	if len(q.Aggregations) > 0 || len(q.GroupBy) > 0 {
+		writeError(w, http.StatusBadRequest, "aggregations are not supported by axiomtest")
+		return
+	}
+
+	match, err := compileFilter(q.Filter)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	start := time.Now()
+
+	s.mu.Lock()
+	res := query.Result{
+		Matches: []query.Entry{},
+	}
+	for _, ev := range ds.events {
+		if (!q.StartTime.IsZero() && ev.time.Before(q.StartTime)) ||
+			(!q.EndTime.IsZero() && !ev.time.Before(q.EndTime)) {
+			continue
+		}
+		res.Status.RowsExamined++
+
+		if !match(ev) {
+			continue
+		}
+		res.Status.RowsMatched++
+
+		data := make(map[string]interface{}, len(ev.data))
+		for k, v := range ev.data {
+			data[k] = v
+		}
+		res.Matches = append(res.Matches, query.Entry{
+			Time:    ev.time,
+			SysTime: ev.sysTime,
+			RowID:   ev.rowID,
+			Data:    data,
+		})
+	}
+	s.mu.Unlock()
+
+	sort.SliceStable(res.Matches, func(i, j int) bool {
+		return res.Matches[i].Time.After(res.Matches[j].Time)
+	})
+	if q.Limit > 0 && len(res.Matches) > int(q.Limit) {
+		res.Matches = res.Matches[:q.Limit]
+	}
+
+	for _, m := range res.Matches {
+		if res.Status.MinBlockTime.IsZero() || m.Time.Before(res.Status.MinBlockTime) {
+			res.Status.MinBlockTime = m.Time
+		}
+		if m.Time.After(res.Status.MaxBlockTime) {
+			res.Status.MaxBlockTime = m.Time
+		}
+	}
+	res.Status.ElapsedTime = time.Since(start)
+
+	writeJSON(w, http.StatusOK, res)
+}
+
+// matcher reports if an event matches a filter.
+type matcher func(ev event) bool
+
+// compileFilter returns a matcher that evaluates the given filter. The zero
+// value of a filter matches all events.
+func compileFilter(f query.Filter) (matcher, error) {
+	switch f.Op {
+	case 0:
+		return func(event) bool { return true }, nil
+	case query.OpAnd, query.OpOr, query.OpNot:
+		children := make([]matcher, len(f.Children))
+		for i, child := range f.Children {
+			var err error
+			if children[i], err = compileFilter(child); err != nil {
+				return nil, err
+			}
+		}
+
+		all := func(ev event) bool {
+			for _, child := range children {
+				if !child(ev) {
+					return false
+				}
+			}
+			return true
+		}
+
+		switch f.Op {
+		case query.OpAnd:
+			return all, nil
+		case query.OpNot:
+			return func(ev event) bool { return !all(ev) }, nil
+		}
+		return func(ev event) bool {
+			for _, child := range children {
+				if child(ev) {
+					return true
+				}
+			}
+			return false
+		}, nil
+	}
+
+	if f.Field == "" {
+		return nil, fmt.Errorf("filter %s is missing a field", f.Op)
+	}
+
+	field := func(ev event) (interface{}, bool) {
+		return lookup(ev, f.Field)
+	}
+
+	switch f.Op {
+	case query.OpExists, query.OpNotExists:
+		want := f.Op == query.OpExists
+		return func(ev event) bool {
+			v, ok := field(ev)
+			return (ok && v != nil) == want
+		}, nil
+	case query.OpEqual, query.OpNotEqual:
+		want := f.Op == query.OpEqual
+		return func(ev event) bool {
+			v, _ := field(ev)
+			return equal(v, f.Value) == want
+		}, nil
+	case query.OpGreaterThan, query.OpGreaterThanEqual, query.OpLessThan, query.OpLessThanEqual:
+		return func(ev event) bool {
+			v, _ := field(ev)
+			c, ok := compare(v, f.Value)
+			if !ok {
+				return false
+			}
+			switch f.Op {
+			case query.OpGreaterThan:
+				return c > 0
+			case query.OpGreaterThanEqual:
+				return c >= 0
+			case query.OpLessThan:
+				return c < 0
+			}
+			return c <= 0
+		}, nil
+	case query.OpRegexp, query.OpNotRegexp:
+		pattern, ok := f.Value.(string)
+		if !ok {
+			return nil, fmt.Errorf("filter %s on field %q requires a string value", f.Op, f.Field)
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("filter %s on field %q: %w", f.Op, f.Field, err)
+		}
+		want := f.Op == query.OpRegexp
+		return func(ev event) bool {
+			v, _ := field(ev)
+			s, ok := v.(string)
+			return ok && re.MatchString(s) == want
+		}, nil
+	case query.OpStartsWith, query.OpNotStartsWith, query.OpEndsWith, query.OpNotEndsWith:
+		value, ok := f.Value.(string)
+		if !ok {
+			return nil, fmt.Errorf("filter %s on field %q requires a string value", f.Op, f.Field)
+		}
+
+		test, want := strings.HasPrefix, f.Op == query.OpStartsWith
+		if f.Op == query.OpEndsWith || f.Op == query.OpNotEndsWith {
+			test, want = strings.HasSuffix, f.Op == query.OpEndsWith
+		}
+		return func(ev event) bool {
+			v, _ := field(ev)
+			s, ok := v.(string)
+			if !ok {
+				return false
+			}
+			if !f.CaseSensitive {
+				return test(strings.ToLower(s), strings.ToLower(value)) == want
+			}
+			return test(s, value) == want
+		}, nil
+	case query.OpContains, query.OpNotContains:
+		want := f.Op == query.OpContains
+		return func(ev event) bool {
+			v, _ := field(ev)
+			return contains(v, f.Value, f.CaseSensitive) == want
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported filter operation %q", f.Op)
+}
+
+// lookup returns the value of the field with the given name of the event.
+// Nested fields are referenced by their path, separated by dots.
+func lookup(ev event, name string) (interface{}, bool) {
+	if name == axiom.TimestampField {
+		return ev.time, true
+	} else if v, ok := ev.data[name]; ok {
+		return v, true
+	}
+
+	var (
+		v  interface{} = map[string]interface{}(ev.data)
+		ok bool
+	)
+	for _, part := range strings.Split(name, ".") {
+		var m map[string]interface{}
+		switch obj := v.(type) {
+		case map[string]interface{}:
+			m = obj
+		case axiom.Event:
+			m = obj
+		default:
+			return nil, false
+		}
+		if v, ok = m[part]; !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// equal reports if the value of a field equals the value of a filter. Numbers
+// are compared by their value, times by the instant they represent.
+func equal(v, filterValue interface{}) bool {
+	if c, ok := compare(v, filterValue); ok {
+		return c == 0
+	}
+	return reflect.DeepEqual(v, filterValue)
+}
+
+// compare compares the value of a field to the value of a filter. Numbers,
+// strings and times can be compared. It returns false, if the values can't be
+// compared.
+func compare(v, filterValue interface{}) (int, bool) {
+	if a, ok := toFloat(v); ok {
+		if b, ok := toFloat(filterValue); ok {
+			switch {
+			case a < b:
+				return -1, true
+			case a > b:
+				return 1, true
+			}
+			return 0, true
+		}
+	}
+
+	if a, ok := v.(time.Time); ok {
+		if b, ok := toTime(filterValue); ok {
+			switch {
+			case a.Before(b):
+				return -1, true
+			case a.After(b):
+				return 1, true
+			}
+			return 0, true
+		}
+	}
+
+	if a, ok := v.(string); ok {
+		if b, ok := filterValue.(string); ok {
+			return strings.Compare(a, b), true
+		}
+	}
+
+	return 0, false
+}
+
+// contains reports if the string or array value of a field contains the value
+// of a filter.
+func contains(v, filterValue interface{}, caseSensitive bool) bool {
+	switch v := v.(type) {
+	case string:
+		s, ok := filterValue.(string)
+		if !ok {
+			return false
+		}
+		if !caseSensitive {
+			return strings.Contains(strings.ToLower(v), strings.ToLower(s))
+		}
+		return strings.Contains(v, s)
+	case []interface{}:
+		for _, elem := range v {
+			if equal(elem, filterValue) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case json.Number:
+		f, err := strconv.ParseFloat(string(v), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func toTime(v interface{}) (time.Time, bool) {
+	switch v := v.(type) {
+	case time.Time:
+		return v, true
+	case string:
+		t, err := time.Parse(time.RFC3339Nano, v)
+		return t, err == nil
+	}
+	return time.Time{}, false
+}
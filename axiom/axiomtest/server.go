@@ -0,0 +1,237 @@
+package axiomtest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/axiomhq/axiom-go/axiom"
+)
+
+// Version is the version reported by the server.
+const Version = "axiomtest"
+
+// An Option modifies the configuration of a `Server`.
+type Option func(*Server)
+
+// SetUser sets the user that is authenticated by the personal token of the
+// server. The ID of the user is generated, if not set.
+func SetUser(user axiom.AuthenticatedUser) Option {
+	return func(s *Server) {
+		if user.ID == "" {
+			user.ID = s.currentUser.ID
+		}
+		s.currentUser = user
+	}
+}
+
+// SetClock sets the function used to retrieve the current time. It is used
+// for timestamping events that have none and for creation times. Defaults to
+// `time.Now`.
+func SetClock(now func() time.Time) Option {
+	return func(s *Server) {
+		s.now = now
+	}
+}
+
+// SetEventValidator sets a function that is called for every event that is
+// ingested. If it returns an error, the event is rejected and reported as a
+// failure of the ingestion, carrying the errors message. This allows to test
+// the handling of partially failed ingestions.
+func SetEventValidator(validate func(dataset string, event axiom.Event) error) Option {
+	return func(s *Server) {
+		s.validate = validate
+	}
+}
+
+// Server is a fake Axiom API server that keeps all its state in memory.
+type Server struct {
+	// URL of the server, as accepted by `axiom.SetURL()`.
+	URL string
+
+	srv *httptest.Server
+
+	now      func() time.Time
+	validate func(dataset string, event axiom.Event) error
+
+	mu             sync.Mutex
+	currentUser    axiom.AuthenticatedUser
+	personalToken  string
+	datasets       map[string]*dataset
+	apiTokens      map[string]*token
+	personalTokens map[string]*token
+	users          map[string]*axiom.User
+}
+
+// NewServer starts and returns a new server. The caller should call `Close()`
+// when finished, to shut it down.
+func NewServer(options ...Option) *Server {
+	s := &Server{
+		now: time.Now,
+		currentUser: axiom.AuthenticatedUser{
+			ID:     uuid.NewString(),
+			Name:   "Axiom Test",
+			Emails: []string{"test@axiom.co"},
+		},
+		datasets:       make(map[string]*dataset),
+		apiTokens:      make(map[string]*token),
+		personalTokens: make(map[string]*token),
+		users:          make(map[string]*axiom.User),
+	}
+
+	for _, option := range options {
+		option(s)
+	}
+
+	// The authenticated user is a regular user of the organization which owns
+	// the personal token used by clients of the server.
+	s.users[s.currentUser.ID] = &axiom.User{
+		ID:    s.currentUser.ID,
+		Name:  s.currentUser.Name,
+		Email: firstOf(s.currentUser.Emails),
+		Role:  axiom.RoleOwner,
+	}
+	t := s.addToken(s.personalTokens, "xapt-", axiom.TokenCreateUpdateRequest{
+		Name:        Version,
+		Description: "Personal token of the axiomtest server",
+	})
+	s.personalToken = t.secret
+
+	s.srv = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	s.URL = s.srv.URL
+
+	return s
+}
+
+// Close shuts down the server and blocks until all outstanding requests have
+// completed.
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// Client returns a new client that is configured to talk to the server using
+// its personal token. The given options are applied on top.
+func (s *Server) Client(options ...axiom.Option) (*axiom.Client, error) {
+	return axiom.NewClient(append([]axiom.Option{
+		axiom.SetURL(s.URL),
+		axiom.SetAccessToken(s.PersonalToken()),
+		axiom.SetClient(s.srv.Client()),
+		axiom.SetNoEnv(),
+	}, options...)...)
+}
+
+// PersonalToken returns the personal token that authenticates the user of the
+// server.
+func (s *Server) PersonalToken() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.personalToken
+}
+
+// serveHTTP authenticates the request and dispatches it to the handler of the
+// requested resource.
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	tok, ok := s.authenticate(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "invalid token")
+		return
+	}
+
+	path := strings.Trim(r.URL.Path, "/")
+	if !strings.HasPrefix(path, "api/v1/") {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	parts := strings.Split(strings.TrimPrefix(path, "api/v1/"), "/")
+
+	// API tokens can only access ingest and query endpoints.
+	if axiom.IsAPIToken(tok.secret) && (parts[0] != "datasets" || !allowsAPIToken(parts[1:])) {
+		writeError(w, http.StatusForbidden, "forbidden")
+		return
+	}
+
+	switch parts[0] {
+	case "datasets":
+		s.serveDatasets(w, r, tok, parts[1:])
+	case "tokens":
+		s.serveTokens(w, r, parts[1:])
+	case "user":
+		s.serveCurrentUser(w, r, parts[1:])
+	case "users":
+		s.serveUsers(w, r, parts[1:])
+	case "version":
+		if len(parts) != 1 || r.Method != http.MethodGet {
+			writeError(w, http.StatusNotFound, "not found")
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"currentVersion": Version})
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// authenticate returns the token the request is authenticated with.
+func (s *Server) authenticate(r *http.Request) (*token, bool) {
+	secret := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, tokens := range []map[string]*token{s.apiTokens, s.personalTokens} {
+		for _, tok := range tokens {
+			if tok.secret == secret {
+				return tok, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// allowsAPIToken returns true if the dataset resource identified by the given
+// path can be accessed using an API token.
+func allowsAPIToken(path []string) bool {
+	switch len(path) {
+	case 1:
+		return path[0] == "_apl"
+	case 2:
+		return path[1] == "ingest" || path[1] == "query"
+	}
+	return false
+}
+
+// decodeJSON decodes the JSON request body into v. It writes an error response
+// and returns false, if the body can't be decoded.
+func decodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// writeJSON writes the JSON encoded value as response with the given status
+// code.
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an error response with the given status code and message,
+// formatted like the ones of the Axiom API.
+func writeError(w http.ResponseWriter, code int, message string) {
+	writeJSON(w, code, map[string]string{"message": message})
+}
+
+func firstOf(s []string) string {
+	if len(s) == 0 {
+		return ""
+	}
+	return s[0]
+}
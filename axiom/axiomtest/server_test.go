@@ -0,0 +1,396 @@
+package axiomtest
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/axiomhq/axiom-go/axiom"
+	"github.com/axiomhq/axiom-go/axiom/query"
+)
+
+func setup(t *testing.T, options ...Option) (*Server, *axiom.Client) {
+	t.Helper()
+
+	srv := NewServer(options...)
+	t.Cleanup(srv.Close)
+
+	client, err := srv.Client()
+	require.NoError(t, err)
+
+	return srv, client
+}
+
+func TestServer_Datasets(t *testing.T) {
+	srv, client := setup(t)
+
+	ctx := context.Background()
+
+	created, err := client.Datasets.Create(ctx, axiom.DatasetCreateRequest{
+		Name:        "test",
+		Description: "A test dataset",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "test", created.ID)
+	assert.Equal(t, "A test dataset", created.Description)
+
+	_, err = client.Datasets.Create(ctx, axiom.DatasetCreateRequest{Name: "test"})
+	assert.ErrorIs(t, err, axiom.ErrExists)
+
+	updated, err := client.Datasets.Update(ctx, "test", axiom.DatasetUpdateRequest{
+		Description: "An updated dataset",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "An updated dataset", updated.Description)
+
+	dataset, err := client.Datasets.Get(ctx, "test")
+	require.NoError(t, err)
+	assert.Equal(t, updated, dataset)
+
+	srv.CreateDataset("other")
+
+	datasets, err := client.Datasets.List(ctx)
+	require.NoError(t, err)
+	if assert.Len(t, datasets, 2) {
+		assert.Equal(t, "other", datasets[0].Name)
+		assert.Equal(t, "test", datasets[1].Name)
+	}
+
+	require.NoError(t, client.Datasets.Delete(ctx, "other"))
+	assert.Equal(t, []string{"test"}, srv.Datasets())
+
+	_, err = client.Datasets.Get(ctx, "other")
+	assert.ErrorIs(t, err, axiom.ErrNotFound)
+
+	_, err = client.Datasets.IngestEvents(ctx, "other", axiom.IngestOptions{}, axiom.Event{})
+	assert.ErrorIs(t, err, axiom.ErrNotFound)
+}
+
+func TestServer_Ingest(t *testing.T) {
+	ts := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	srv, client := setup(t, SetClock(func() time.Time { return ts }))
+	srv.CreateDataset("test")
+
+	ctx := context.Background()
+
+	res, err := client.Datasets.IngestEvents(ctx, "test", axiom.IngestOptions{},
+		axiom.Event{"_time": ts.Add(time.Second), "msg": "gzip"},
+		axiom.Event{"msg": "no time"},
+	)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, res.Ingested)
+	assert.EqualValues(t, 2, res.WALLength)
+
+	res, err = client.Datasets.IngestEvents(ctx, "test", axiom.IngestOptions{
+		Compression: axiom.Compression{Encoding: axiom.Zstd},
+	}, axiom.Event{"_time": ts.Add(2 * time.Second), "msg": "zstd", "n": 1})
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, res.Ingested)
+
+	res, err = client.Datasets.Ingest(ctx, "test", strings.NewReader("ts;msg\n01.01.2022;csv\n"), axiom.CSV, axiom.Identity, axiom.IngestOptions{
+		TimestampField:  "ts",
+		TimestampFormat: "02.01.2006",
+		CSVDelimiter:    ";",
+	})
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, res.Ingested)
+
+	res, err = client.Datasets.Ingest(ctx, "test", strings.NewReader(`[{"msg":"json"},{"_time":"invalid"}]`), axiom.JSON, axiom.Identity, axiom.IngestOptions{})
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, res.Ingested)
+	assert.EqualValues(t, 1, res.Failed)
+	if assert.Len(t, res.Failures, 1) {
+		assert.Contains(t, res.Failures[0].Error, `invalid timestamp "invalid"`)
+	}
+
+	_, err = client.Datasets.Ingest(ctx, "test", strings.NewReader(`{`), axiom.NDJSON, axiom.Identity, axiom.IngestOptions{})
+	assert.Error(t, err)
+
+	assert.Equal(t, []axiom.Event{
+		{"_time": ts.Add(time.Second), "msg": "gzip"},
+		{"_time": ts, "msg": "no time"},
+		{"_time": ts.Add(2 * time.Second), "msg": "zstd", "n": float64(1)},
+		{"_time": ts, "msg": "csv"},
+		{"_time": ts, "msg": "json"},
+	}, srv.Events("test"))
+	assert.Nil(t, srv.Events("unknown"))
+
+	info, err := client.Datasets.Info(ctx, "test")
+	require.NoError(t, err)
+	assert.EqualValues(t, 5, info.NumEvents)
+	assert.EqualValues(t, 3, info.NumFields)
+	assert.Equal(t, ts, info.MinTime)
+	assert.Equal(t, ts.Add(2*time.Second), info.MaxTime)
+
+	stats, err := client.Datasets.Stats(ctx)
+	require.NoError(t, err)
+	assert.EqualValues(t, 5, stats.NumEvents)
+}
+
+func TestServer_Ingest_Validator(t *testing.T) {
+	ts := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	srv, client := setup(t, SetEventValidator(func(dataset string, event axiom.Event) error {
+		if event["bad"] == true {
+			return errors.New("bad event")
+		}
+		return nil
+	}))
+	srv.CreateDataset("test")
+
+	events := []axiom.Event{
+		{"_time": ts, "bad": false},
+		{"_time": ts.Add(time.Second), "bad": true},
+	}
+
+	res, err := client.Datasets.IngestEvents(context.Background(), "test", axiom.IngestOptions{}, events...)
+	require.NoError(t, err)
+
+	assert.EqualValues(t, 1, res.Ingested)
+	assert.EqualValues(t, 1, res.Failed)
+	assert.Equal(t, []axiom.Event{events[1]}, res.FailedEvents(events))
+	assert.Len(t, srv.Events("test"), 1)
+}
+
+func TestServer_Query(t *testing.T) {
+	ts := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	srv, client := setup(t)
+	srv.AddEvents("test",
+		axiom.Event{"_time": ts, "level": "info", "n": 1},
+		axiom.Event{"_time": ts.Add(time.Second), "level": "error", "n": 2},
+		axiom.Event{"_time": ts.Add(2 * time.Second), "level": "INFO", "n": 3},
+		axiom.Event{"_time": ts.Add(time.Hour), "level": "info", "n": 4},
+	)
+
+	res, err := client.Datasets.Query(context.Background(), "test", query.Query{
+		StartTime: ts,
+		EndTime:   ts.Add(time.Minute),
+		Filter: query.Filter{
+			Op:    query.OpStartsWith,
+			Field: "level",
+			Value: "info",
+		},
+	}, query.Options{})
+	require.NoError(t, err)
+
+	assert.EqualValues(t, 3, res.Status.RowsExamined)
+	assert.EqualValues(t, 2, res.Status.RowsMatched)
+	if assert.Len(t, res.Matches, 2) {
+		// Newest first.
+		assert.Equal(t, ts.Add(2*time.Second), res.Matches[0].Time)
+		assert.Equal(t, map[string]interface{}{"level": "INFO", "n": float64(3)}, res.Matches[0].Data)
+		assert.Equal(t, ts, res.Matches[1].Time)
+	}
+
+	res, err = client.Datasets.Query(context.Background(), "test", query.Query{
+		StartTime: ts,
+		EndTime:   ts.Add(2 * time.Hour),
+		Limit:     1,
+	}, query.Options{})
+	require.NoError(t, err)
+	if assert.Len(t, res.Matches, 1) {
+		assert.Equal(t, ts.Add(time.Hour), res.Matches[0].Time)
+	}
+
+	_, err = client.Datasets.Query(context.Background(), "test", query.Query{
+		StartTime: ts,
+		EndTime:   ts.Add(time.Hour),
+		Aggregations: []query.Aggregation{
+			{Op: query.OpCount, Alias: "count"},
+		},
+	}, query.Options{})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "aggregations are not supported")
+	}
+}
+
+func TestCompileFilter(t *testing.T) {
+	ts := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	ev := event{
+		time: ts,
+		data: axiom.Event{
+			"msg":    "Hello World",
+			"n":      float64(42),
+			"tags":   []interface{}{"a", float64(1)},
+			"nested": map[string]interface{}{"key": "value"},
+			"null":   nil,
+		},
+	}
+
+	tests := []struct {
+		name   string
+		filter query.Filter
+		want   bool
+	}{
+		{"empty", query.Filter{}, true},
+		{"equal", query.Filter{Op: query.OpEqual, Field: "n", Value: 42}, true},
+		{"equal string", query.Filter{Op: query.OpEqual, Field: "msg", Value: "hello world"}, false},
+		{"not equal", query.Filter{Op: query.OpNotEqual, Field: "n", Value: 41}, true},
+		{"exists", query.Filter{Op: query.OpExists, Field: "nested.key"}, true},
+		{"exists null", query.Filter{Op: query.OpExists, Field: "null"}, false},
+		{"not exists", query.Filter{Op: query.OpNotExists, Field: "unknown"}, true},
+		{"greater than", query.Filter{Op: query.OpGreaterThan, Field: "n", Value: 41.5}, true},
+		{"greater than equal", query.Filter{Op: query.OpGreaterThanEqual, Field: "n", Value: 42}, true},
+		{"less than", query.Filter{Op: query.OpLessThan, Field: "n", Value: 42}, false},
+		{"less than equal string", query.Filter{Op: query.OpLessThanEqual, Field: "n", Value: "42"}, false},
+		{"time", query.Filter{Op: query.OpLessThan, Field: "_time", Value: "2022-01-01T00:00:01Z"}, true},
+		{"starts with", query.Filter{Op: query.OpStartsWith, Field: "msg", Value: "hello"}, true},
+		{"starts with case sensitive", query.Filter{Op: query.OpStartsWith, Field: "msg", Value: "hello", CaseSensitive: true}, false},
+		{"not ends with", query.Filter{Op: query.OpNotEndsWith, Field: "msg", Value: "world"}, false},
+		{"regexp", query.Filter{Op: query.OpRegexp, Field: "msg", Value: "^H.*d$"}, true},
+		{"not regexp", query.Filter{Op: query.OpNotRegexp, Field: "msg", Value: "^x"}, true},
+		{"contains", query.Filter{Op: query.OpContains, Field: "msg", Value: "O W"}, true},
+		{"contains array", query.Filter{Op: query.OpContains, Field: "tags", Value: 1}, true},
+		{"not contains", query.Filter{Op: query.OpNotContains, Field: "tags", Value: "b"}, true},
+		{"and", query.Filter{Op: query.OpAnd, Children: []query.Filter{
+			{Op: query.OpEqual, Field: "n", Value: 42},
+			{Op: query.OpEqual, Field: "nested.key", Value: "other"},
+		}}, false},
+		{"or", query.Filter{Op: query.OpOr, Children: []query.Filter{
+			{Op: query.OpEqual, Field: "n", Value: 42},
+			{Op: query.OpEqual, Field: "nested.key", Value: "other"},
+		}}, true},
+		{"not", query.Filter{Op: query.OpNot, Children: []query.Filter{
+			{Op: query.OpEqual, Field: "n", Value: 42},
+		}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match, err := compileFilter(tt.filter)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.want, match(ev))
+		})
+	}
+
+	_, err := compileFilter(query.Filter{Op: query.OpRegexp, Field: "msg", Value: "("})
+	assert.Error(t, err)
+
+	_, err = compileFilter(query.Filter{Op: query.OpStartsWith, Field: "msg", Value: 1})
+	assert.EqualError(t, err, `filter starts-with on field "msg" requires a string value`)
+
+	_, err = compileFilter(query.Filter{Op: query.OpEqual})
+	assert.EqualError(t, err, "filter == is missing a field")
+}
+
+func TestServer_Tokens(t *testing.T) {
+	srv, client := setup(t)
+	srv.CreateDataset("test")
+	srv.CreateDataset("other")
+
+	ctx := context.Background()
+
+	created, err := client.Tokens.API.Create(ctx, axiom.TokenCreateUpdateRequest{
+		Name:        "ingest",
+		Scopes:      []string{"test"},
+		Permissions: []axiom.Permission{axiom.CanIngest},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"test"}, created.Scopes)
+
+	raw, err := client.Tokens.API.View(ctx, created.ID)
+	require.NoError(t, err)
+	assert.True(t, axiom.IsAPIToken(raw.Token))
+
+	tokens, err := client.Tokens.API.List(ctx)
+	require.NoError(t, err)
+	assert.Len(t, tokens, 1)
+
+	// The API token can only ingest into the dataset it is scoped to.
+	apiClient, err := srv.Client(axiom.SetAccessToken(raw.Token))
+	require.NoError(t, err)
+
+	_, err = apiClient.Datasets.IngestEvents(ctx, "test", axiom.IngestOptions{}, axiom.Event{"a": 1})
+	assert.NoError(t, err)
+
+	_, err = apiClient.Datasets.IngestEvents(ctx, "other", axiom.IngestOptions{}, axiom.Event{"a": 1})
+	assert.ErrorIs(t, err, axiom.ErrUnauthorized)
+
+	_, err = apiClient.Datasets.Query(ctx, "test", query.Query{}, query.Options{})
+	assert.ErrorIs(t, err, axiom.ErrUnauthorized)
+
+	updated, err := client.Tokens.API.Update(ctx, created.ID, axiom.TokenCreateUpdateRequest{
+		Name:        "query",
+		Permissions: []axiom.Permission{axiom.CanQuery},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "query", updated.Name)
+
+	_, err = apiClient.Datasets.Query(ctx, "test", query.Query{}, query.Options{})
+	assert.NoError(t, err)
+
+	require.NoError(t, client.Tokens.API.Delete(ctx, created.ID))
+
+	_, err = apiClient.Datasets.Query(ctx, "test", query.Query{}, query.Options{})
+	assert.ErrorIs(t, err, axiom.ErrUnauthenticated)
+
+	personal, err := client.Tokens.Personal.List(ctx)
+	require.NoError(t, err)
+	assert.Len(t, personal, 1)
+}
+
+func TestServer_Users(t *testing.T) {
+	_, client := setup(t, SetUser(axiom.AuthenticatedUser{
+		Name:   "John Doe",
+		Emails: []string{"john@example.com"},
+	}))
+
+	ctx := context.Background()
+
+	current, err := client.Users.Current(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "John Doe", current.Name)
+	assert.NotEmpty(t, current.ID)
+	require.NoError(t, client.ValidateCredentials(ctx))
+
+	created, err := client.Users.Create(ctx, axiom.UserCreateRequest{
+		Name:  "Jane Doe",
+		Email: "jane@example.com",
+		Role:  axiom.RoleUser,
+	})
+	require.NoError(t, err)
+
+	updated, err := client.Users.Update(ctx, created.ID, axiom.UserUpdateRequest{Name: "Jane Roe"})
+	require.NoError(t, err)
+	assert.Equal(t, "Jane Roe", updated.Name)
+
+	updated, err = client.Users.UpdateRole(ctx, created.ID, axiom.RoleAdmin)
+	require.NoError(t, err)
+	assert.Equal(t, axiom.RoleAdmin, updated.Role)
+
+	users, err := client.Users.List(ctx)
+	require.NoError(t, err)
+	assert.Len(t, users, 2)
+
+	require.NoError(t, client.Users.Delete(ctx, created.ID))
+
+	_, err = client.Users.Get(ctx, created.ID)
+	assert.ErrorIs(t, err, axiom.ErrNotFound)
+}
+
+func TestServer_Unsupported(t *testing.T) {
+	srv, client := setup(t)
+
+	ctx := context.Background()
+
+	version, err := client.Version.Get(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, Version, version)
+
+	_, err = client.Monitors.List(ctx)
+	assert.ErrorIs(t, err, axiom.ErrNotFound)
+
+	client, err = srv.Client(axiom.SetAccessToken("xapt-unknown"))
+	require.NoError(t, err)
+
+	_, err = client.Version.Get(ctx)
+	assert.ErrorIs(t, err, axiom.ErrUnauthenticated)
+}
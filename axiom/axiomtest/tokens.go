@@ -0,0 +1,184 @@
+package axiomtest
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/axiomhq/axiom-go/axiom"
+)
+
+// token is an API or personal token known to the server.
+type token struct {
+	axiom.Token
+
+	secret string
+}
+
+// allows returns true if the token grants the given permission on the dataset
+// with the given name. Personal tokens grant all permissions.
+func (t *token) allows(permission axiom.Permission, dataset string) bool {
+	if !axiom.IsAPIToken(t.secret) {
+		return true
+	}
+
+	var permitted bool
+	for _, p := range t.Permissions {
+		if p == permission {
+			permitted = true
+			break
+		}
+	}
+	if !permitted {
+		return false
+	}
+
+	for _, scope := range t.Scopes {
+		if scope == "*" || scope == dataset {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateAPIToken creates an API token with the given properties and returns
+// its secret value.
+func (s *Server) CreateAPIToken(req axiom.TokenCreateUpdateRequest) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.addToken(s.apiTokens, "xaat-", req).secret
+}
+
+// addToken adds a token with the given properties and prefix of the secret
+// value to the given tokens. The server must be locked.
+func (s *Server) addToken(tokens map[string]*token, prefix string, req axiom.TokenCreateUpdateRequest) *token {
+	t := &token{
+		Token: axiom.Token{
+			ID:          uuid.NewString(),
+			Name:        req.Name,
+			Description: req.Description,
+		},
+		secret: prefix + uuid.NewString(),
+	}
+
+	// Scopes and permissions are only used by API tokens.
+	if prefix == "xaat-" {
+		t.Scopes, t.Permissions = req.Scopes, req.Permissions
+		if len(t.Scopes) == 0 {
+			t.Scopes = []string{"*"}
+		}
+	}
+
+	tokens[t.ID] = t
+
+	return t
+}
+
+// serveTokens serves the "/api/v1/tokens/{api,personal}" endpoints.
+func (s *Server) serveTokens(w http.ResponseWriter, r *http.Request, path []string) {
+	if len(path) == 0 || len(path) > 3 {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	var tokens map[string]*token
+	prefix := "xaat-"
+	switch path[0] {
+	case "api":
+		tokens = s.apiTokens
+	case "personal":
+		tokens, prefix = s.personalTokens, "xapt-"
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	switch {
+	case len(path) == 1 && r.Method == http.MethodGet:
+		s.mu.Lock()
+		res := make([]*axiom.Token, 0, len(tokens))
+		for _, t := range tokens {
+			tok := t.Token
+			res = append(res, &tok)
+		}
+		s.mu.Unlock()
+
+		writeJSON(w, http.StatusOK, res)
+	case len(path) == 1 && r.Method == http.MethodPost:
+		var req axiom.TokenCreateUpdateRequest
+		if !decodeJSON(w, r, &req) {
+			return
+		}
+
+		s.mu.Lock()
+		t := s.addToken(tokens, prefix, req)
+		res := t.Token
+		s.mu.Unlock()
+
+		writeJSON(w, http.StatusOK, res)
+	case len(path) >= 2:
+		s.serveToken(w, r, tokens, path[1], path[2:])
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// serveToken serves the endpoints of the token identified by the given id.
+func (s *Server) serveToken(w http.ResponseWriter, r *http.Request, tokens map[string]*token, id string, path []string) {
+	s.mu.Lock()
+	t, ok := tokens[id]
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, "token not found")
+		return
+	}
+
+	switch {
+	case len(path) == 1 && path[0] == "token" && r.Method == http.MethodGet:
+		s.mu.Lock()
+		res := axiom.RawToken{
+			Token:       t.secret,
+			Scopes:      t.Scopes,
+			Permissions: t.Permissions,
+		}
+		s.mu.Unlock()
+
+		writeJSON(w, http.StatusOK, res)
+	case len(path) > 0:
+		writeError(w, http.StatusNotFound, "not found")
+	case r.Method == http.MethodGet:
+		s.mu.Lock()
+		res := t.Token
+		s.mu.Unlock()
+
+		writeJSON(w, http.StatusOK, res)
+	case r.Method == http.MethodPut:
+		var req axiom.TokenCreateUpdateRequest
+		if !decodeJSON(w, r, &req) {
+			return
+		}
+
+		s.mu.Lock()
+		t.Name, t.Description = req.Name, req.Description
+		if axiom.IsAPIToken(t.secret) {
+			if len(req.Scopes) > 0 {
+				t.Scopes = req.Scopes
+			}
+			t.Permissions = req.Permissions
+		}
+		res := t.Token
+		s.mu.Unlock()
+
+		writeJSON(w, http.StatusOK, res)
+	case r.Method == http.MethodDelete:
+		s.mu.Lock()
+		delete(tokens, id)
+		s.mu.Unlock()
+
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
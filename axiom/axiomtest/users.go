@@ -0,0 +1,124 @@
+package axiomtest
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/axiomhq/axiom-go/axiom"
+)
+
+// serveCurrentUser serves the "/api/v1/user" endpoint.
+func (s *Server) serveCurrentUser(w http.ResponseWriter, r *http.Request, path []string) {
+	if len(path) != 0 {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	} else if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	s.mu.Lock()
+	res := s.currentUser
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, res)
+}
+
+// serveUsers serves the "/api/v1/users" endpoints.
+func (s *Server) serveUsers(w http.ResponseWriter, r *http.Request, path []string) {
+	switch {
+	case len(path) == 0 && r.Method == http.MethodGet:
+		s.mu.Lock()
+		res := make([]*axiom.User, 0, len(s.users))
+		for _, u := range s.users {
+			user := *u
+			res = append(res, &user)
+		}
+		s.mu.Unlock()
+
+		writeJSON(w, http.StatusOK, res)
+	case len(path) == 0 && r.Method == http.MethodPost:
+		var req axiom.UserCreateRequest
+		if !decodeJSON(w, r, &req) {
+			return
+		}
+
+		user := &axiom.User{
+			ID:    uuid.NewString(),
+			Name:  req.Name,
+			Email: req.Email,
+			Role:  req.Role,
+		}
+
+		s.mu.Lock()
+		s.users[user.ID] = user
+		res := *user
+		s.mu.Unlock()
+
+		writeJSON(w, http.StatusOK, res)
+	case len(path) == 0:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	case len(path) <= 2:
+		s.serveUser(w, r, path[0], path[1:])
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// serveUser serves the endpoints of the user identified by the given id.
+func (s *Server) serveUser(w http.ResponseWriter, r *http.Request, id string, path []string) {
+	s.mu.Lock()
+	user, ok := s.users[id]
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	switch {
+	case len(path) == 1 && path[0] == "role" && r.Method == http.MethodPut:
+		var req struct {
+			Role axiom.UserRole `json:"role"`
+		}
+		if !decodeJSON(w, r, &req) {
+			return
+		}
+
+		s.mu.Lock()
+		user.Role = req.Role
+		res := *user
+		s.mu.Unlock()
+
+		writeJSON(w, http.StatusOK, res)
+	case len(path) > 0:
+		writeError(w, http.StatusNotFound, "not found")
+	case r.Method == http.MethodGet:
+		s.mu.Lock()
+		res := *user
+		s.mu.Unlock()
+
+		writeJSON(w, http.StatusOK, res)
+	case r.Method == http.MethodPut:
+		var req axiom.UserUpdateRequest
+		if !decodeJSON(w, r, &req) {
+			return
+		}
+
+		s.mu.Lock()
+		user.Name = req.Name
+		res := *user
+		s.mu.Unlock()
+
+		writeJSON(w, http.StatusOK, res)
+	case r.Method == http.MethodDelete:
+		s.mu.Lock()
+		delete(s.users, id)
+		s.mu.Unlock()
+
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
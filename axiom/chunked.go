@@ -0,0 +1,348 @@
+package axiom
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	defaultChunkMaxBytes    = 10 << 20 // 10 MiB
+	defaultChunkMaxEvents   = 100000
+	defaultChunkConcurrency = 4
+)
+
+// chunkConfig configures the chunking of `DatasetsService.IngestChunked`.
+type chunkConfig struct {
+	maxBytes    int
+	maxEvents   int
+	concurrency int
+}
+
+// A ChunkOption modifies the chunking behaviour of
+// `DatasetsService.IngestChunked`.
+type ChunkOption func(*chunkConfig) error
+
+// SetChunkMaxBytes specifies the maximum number of uncompressed bytes sent with
+// a single request. A record that exceeds the limit on its own is sent in a
+// chunk of its own. Defaults to 10 MiB.
+func SetChunkMaxBytes(n int) ChunkOption {
+	return func(c *chunkConfig) error {
+		if n <= 0 {
+			return errors.New("maximum chunk bytes must be greater than zero")
+		}
+		c.maxBytes = n
+		return nil
+	}
+}
+
+// SetChunkMaxEvents specifies the maximum number of events sent with a single
+// request. Defaults to 100000.
+func SetChunkMaxEvents(n int) ChunkOption {
+	return func(c *chunkConfig) error {
+		if n <= 0 {
+			return errors.New("maximum chunk events must be greater than zero")
+		}
+		c.maxEvents = n
+		return nil
+	}
+}
+
+// SetChunkConcurrency specifies the maximum number of chunks that are uploaded
+// in parallel. It also bounds the amount of memory used, which is roughly the
+// concurrency times the maximum chunk bytes. Defaults to 4.
+func SetChunkConcurrency(n int) ChunkOption {
+	return func(c *chunkConfig) error {
+		if n <= 0 {
+			return errors.New("chunk concurrency must be greater than zero")
+		}
+		c.concurrency = n
+		return nil
+	}
+}
+
+// ChunkedIngestStatus is the status of a chunked ingestion. The embedded
+// `IngestStatus` is the aggregate of the statuses of all chunks: The counters
+// are summed up and the failures are concatenated in the order of the chunks.
+type ChunkedIngestStatus struct {
+	IngestStatus
+
+	// Chunks are the chunks the input was split into, in the order of the
+	// input. Chunks that haven't been uploaded because ingestion was aborted
+	// are not included.
+	Chunks []ChunkStatus
+}
+
+// ChunkStatus is the status of a single chunk of a chunked ingestion.
+type ChunkStatus struct {
+	// Offset is the byte offset of the first record of the chunk in the input.
+	Offset int64
+	// Index is the index of the first event of the chunk in the input.
+	Index int
+	// Events is the number of events in the chunk.
+	Events int
+	// Bytes is the number of uncompressed bytes of the chunk, including the
+	// header of CSV input.
+	Bytes int
+	// Status of the chunk. Nil, if the chunk failed to ingest.
+	Status *IngestStatus
+	// Err is the error the chunk failed to ingest with, if any.
+	Err error
+
+	// lines are the lines of the input the events of the chunk start on.
+	lines []int
+}
+
+// IngestChunked ingests the newline delimited JSON or CSV data read from the
+// given reader into the dataset identified by its id. Unlike `Ingest()`, the
+// data is split at record boundaries into chunks which are capped by bytes and
+// number of events (see `SetChunkMaxBytes()` and `SetChunkMaxEvents()`). Each
+// chunk is uploaded with a request of its own, with a bounded number of
+// requests in flight (see `SetChunkConcurrency()`). The header of CSV data is
+// sent with every chunk. The data must not be compressed. Chunks are
+// compressed as configured by the `Compression` of the given options or the
+// clients default, if none is set.
+//
+// If a chunk fails to ingest, no further chunks are uploaded and the error is
+// returned alongside the status of all chunks that have been uploaded. This
+// allows to tell which events made it to the server.
+func (s *DatasetsService) IngestChunked(ctx context.Context, id string, r io.Reader, typ ContentType, opts IngestOptions, options ...ChunkOption) (*ChunkedIngestStatus, error) {
+	ctx = withOperation(ctx, "Datasets.IngestChunked")
+
+	cfg := chunkConfig{
+		maxBytes:    defaultChunkMaxBytes,
+		maxEvents:   defaultChunkMaxEvents,
+		concurrency: defaultChunkConcurrency,
+	}
+	for _, option := range options {
+		if err := option(&cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	switch typ {
+	case NDJSON, CSV:
+	case JSON:
+		return nil, fmt.Errorf("content type %s can't be chunked", typ)
+	default:
+		return nil, ErrUnknownContentType
+	}
+
+	comp, err := s.compression(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		br  = bufio.NewReader(r)
+		sem = make(chan struct{}, cfg.concurrency)
+		wg  sync.WaitGroup
+
+		chunks []*ChunkStatus
+		pos    int64
+		line   int
+		header []byte
+	)
+
+	// upload uploads the given chunk in the background. It blocks while the
+	// maximum number of chunks is in flight. The chunk is dropped if ingestion
+	// has been aborted in the meantime.
+	upload := func(chunk *ChunkStatus, data []byte) {
+		sem <- struct{}{}
+		if ctx.Err() != nil {
+			<-sem
+			return
+		}
+		chunks = append(chunks, chunk)
+
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			chunk.Status, chunk.Err = s.ingestChunk(ctx, id, chunk, data, typ, comp, opts)
+			if chunk.Err != nil {
+				cancel()
+			}
+		}()
+	}
+
+	if typ == CSV {
+		var headerErr error
+		if header, headerErr = readRecord(br, nil, true); headerErr != nil && headerErr != io.EOF {
+			return nil, fmt.Errorf("read csv header: %w", headerErr)
+		}
+		pos = int64(len(header))
+		line = bytes.Count(header, []byte{'\n'})
+		header = terminateRecord(header)
+	}
+
+	var (
+		chunk   = &ChunkStatus{Offset: pos}
+		data    = append(make([]byte, 0, len(header)), header...)
+		record  []byte
+		index   int
+		readErr error
+	)
+	for ctx.Err() == nil {
+		recordPos, recordLine := pos, line+1
+		record, readErr = readRecord(br, record[:0], typ == CSV)
+		pos += int64(len(record))
+		line += bytes.Count(record, []byte{'\n'})
+		if readErr != nil && readErr != io.EOF {
+			break
+		}
+
+		if len(bytes.TrimSpace(record)) > 0 {
+			// Start a new chunk if the record doesn't fit into the current
+			// one.
+			if chunk.Events > 0 && (chunk.Events == cfg.maxEvents || len(data)+len(record)+1 > cfg.maxBytes) {
+				chunk.Bytes = len(data)
+				upload(chunk, data)
+
+				chunk = &ChunkStatus{Offset: recordPos, Index: index}
+				data = append(make([]byte, 0, len(header)+len(record)+1), header...)
+			}
+
+			if chunk.Events == 0 {
+				chunk.Offset, chunk.Index = recordPos, index
+			}
+			data = append(data, terminateRecord(record)...)
+			chunk.lines = append(chunk.lines, recordLine)
+			chunk.Events++
+			index++
+		}
+
+		if readErr == io.EOF {
+			readErr = nil
+			break
+		}
+	}
+
+	if readErr == nil && ctx.Err() == nil && chunk.Events > 0 {
+		chunk.Bytes = len(data)
+		upload(chunk, data)
+	}
+
+	wg.Wait()
+
+	res := &ChunkedIngestStatus{
+		Chunks: make([]ChunkStatus, len(chunks)),
+	}
+	var chunkErr error
+	for i, chunk := range chunks {
+		chunk.lines = nil
+		res.Chunks[i] = *chunk
+		if chunk.Status != nil {
+			res.Ingested += chunk.Status.Ingested
+			res.Failed += chunk.Status.Failed
+			res.Failures = append(res.Failures, chunk.Status.Failures...)
+			res.ProcessedBytes += chunk.Status.ProcessedBytes
+			res.BlocksCreated += chunk.Status.BlocksCreated
+			if chunk.Status.WALLength > res.WALLength {
+				res.WALLength = chunk.Status.WALLength
+			}
+			continue
+		}
+
+		// Prefer the error that caused the other chunks to be canceled.
+		if chunkErr == nil || (errors.Is(chunkErr, context.Canceled) && !errors.Is(chunk.Err, context.Canceled)) {
+			chunkErr = fmt.Errorf("chunk %d at offset %d: %w", i, chunk.Offset, chunk.Err)
+		}
+	}
+
+	switch {
+	case readErr != nil:
+		return res, fmt.Errorf("read input at offset %d: %w", pos, readErr)
+	case chunkErr != nil:
+		return res, chunkErr
+	}
+
+	// The passed context might have been canceled before all input has been
+	// read.
+	return res, ctx.Err()
+}
+
+// ingestChunk ingests the data of the given chunk. Failures are correlated
+// with the events of the input.
+func (s *DatasetsService) ingestChunk(ctx context.Context, id string, chunk *ChunkStatus, data []byte, typ ContentType, comp Compression, opts IngestOptions) (*IngestStatus, error) {
+	if len(data) < comp.MinSize {
+		comp.Encoding = Identity
+	}
+
+	r, err := comp.Encoder()(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := s.Ingest(ctx, id, r, typ, comp.Encoding, opts)
+	if err != nil || len(res.Failures) == 0 {
+		return res, err
+	}
+
+	// The data is still around, so there is no need to track it while it is
+	// sent.
+	tracker := newRecordTracker(typ, opts)
+	_, _ = tracker.Write(data)
+	tracker.flush()
+
+	n := len(tracker.records)
+	if n > len(chunk.lines) {
+		n = len(chunk.lines)
+	}
+	correlateFailures(res.Failures, n, func(i int) (time.Time, int, bool) {
+		return tracker.records[i].ts, chunk.lines[i], tracker.records[i].ok
+	})
+	for _, failure := range res.Failures {
+		if failure.Index >= 0 {
+			failure.Index += chunk.Index
+		}
+	}
+
+	return res, nil
+}
+
+// readRecord appends the next record read from the given reader to the buffer.
+// A record is a line, including its line break. A CSV record continues on the
+// next line if the line break is part of a quoted field. At the end of the
+// input, the last record is returned alongside `io.EOF`.
+func readRecord(br *bufio.Reader, buf []byte, isCSV bool) ([]byte, error) {
+	var quoted bool
+	for {
+		line, err := br.ReadSlice('\n')
+		buf = append(buf, line...)
+
+		if isCSV {
+			// Escaped quotes within a quoted field come in pairs, so they
+			// don't change the state.
+			quoted = quoted != (bytes.Count(line, []byte{'"'})%2 == 1)
+		}
+
+		switch {
+		case err == bufio.ErrBufferFull:
+			continue
+		case err != nil:
+			return buf, err
+		case !quoted:
+			return buf, nil
+		}
+	}
+}
+
+// terminateRecord makes sure the given record ends with a line break.
+func terminateRecord(record []byte) []byte {
+	if len(record) > 0 && record[len(record)-1] != '\n' {
+		return append(record, '\n')
+	}
+	return record
+}
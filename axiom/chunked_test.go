@@ -0,0 +1,259 @@
+package axiom
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// chunkRecorder is a handler that records the bodies of chunked ingestion
+// requests. It reports every record that contains "fail" as failed.
+type chunkRecorder struct {
+	t *testing.T
+
+	mu     sync.Mutex
+	bodies []string
+	// status, if set, is the HTTP status code returned for the request with
+	// the given (zero based) number.
+	status map[int]int
+}
+
+func (cr *chunkRecorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	gzr, err := gzip.NewReader(r.Body)
+	require.NoError(cr.t, err)
+
+	b, err := io.ReadAll(gzr)
+	require.NoError(cr.t, err)
+
+	cr.mu.Lock()
+	n := len(cr.bodies)
+	cr.bodies = append(cr.bodies, string(b))
+	code := cr.status[n]
+	cr.mu.Unlock()
+
+	if code != 0 {
+		w.WriteHeader(code)
+		return
+	}
+
+	var (
+		ingested, failed int
+		failures         []string
+	)
+	s := bufio.NewScanner(strings.NewReader(string(b)))
+	for s.Scan() {
+		if strings.HasPrefix(s.Text(), "name") {
+			continue // CSV header.
+		} else if strings.Contains(s.Text(), "fail") {
+			failed++
+			failures = append(failures, `{"timestamp":"2022-01-01T00:00:00Z","error":"failed"}`)
+		} else {
+			ingested++
+		}
+	}
+
+	_, _ = fmt.Fprintf(w, `{"ingested":%d,"failed":%d,"failures":[%s],"processedBytes":%d,"blocksCreated":1,"walLength":%d}`,
+		ingested, failed, strings.Join(failures, ","), len(b), n+1)
+}
+
+func (cr *chunkRecorder) sortedBodies() []string {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+
+	bodies := append([]string(nil), cr.bodies...)
+	sort.Strings(bodies)
+	return bodies
+}
+
+func TestDatasetsService_IngestChunked(t *testing.T) {
+	var input strings.Builder
+	for i := 0; i < 10; i++ {
+		fmt.Fprintf(&input, `{"i":%d}`+"\n", i)
+		if i == 4 {
+			// Blank lines are skipped.
+			input.WriteString("\n  \n")
+		}
+	}
+	// The last record lacks a line break.
+	input.WriteString(`{"i":10,"fail":true}`)
+
+	cr := &chunkRecorder{t: t}
+
+	client, teardown := setup(t, "/api/v1/datasets/test/ingest", cr.ServeHTTP)
+	defer teardown()
+
+	res, err := client.Datasets.IngestChunked(context.Background(), "test", strings.NewReader(input.String()), NDJSON, IngestOptions{},
+		SetChunkMaxEvents(4),
+		SetChunkConcurrency(2),
+	)
+	require.NoError(t, err)
+
+	assert.EqualValues(t, 10, res.Ingested)
+	assert.EqualValues(t, 1, res.Failed)
+	assert.Len(t, res.Failures, 1)
+	assert.EqualValues(t, 3, res.BlocksCreated)
+	assert.EqualValues(t, 3, res.WALLength)
+	assert.EqualValues(t, len(input.String())-len("\n  \n")+1, res.ProcessedBytes)
+
+	if assert.Len(t, res.Chunks, 3) {
+		assert.EqualValues(t, 0, res.Chunks[0].Offset)
+		assert.Equal(t, 0, res.Chunks[0].Index)
+		assert.Equal(t, 4, res.Chunks[0].Events)
+		assert.Equal(t, 4*len(`{"i":0}`+"\n"), res.Chunks[0].Bytes)
+
+		assert.EqualValues(t, strings.Index(input.String(), `{"i":4}`), res.Chunks[1].Offset)
+		assert.Equal(t, 4, res.Chunks[1].Index)
+		assert.Equal(t, 4, res.Chunks[1].Events)
+
+		assert.EqualValues(t, strings.Index(input.String(), `{"i":8}`), res.Chunks[2].Offset)
+		assert.Equal(t, 8, res.Chunks[2].Index)
+		assert.Equal(t, 3, res.Chunks[2].Events)
+		if assert.NotNil(t, res.Chunks[2].Status) {
+			assert.EqualValues(t, 1, res.Chunks[2].Status.Failed)
+		}
+
+		for _, chunk := range res.Chunks {
+			assert.NoError(t, chunk.Err)
+		}
+	}
+
+	assert.Equal(t, []string{
+		`{"i":0}` + "\n" + `{"i":1}` + "\n" + `{"i":2}` + "\n" + `{"i":3}` + "\n",
+		`{"i":4}` + "\n" + `{"i":5}` + "\n" + `{"i":6}` + "\n" + `{"i":7}` + "\n",
+		`{"i":8}` + "\n" + `{"i":9}` + "\n" + `{"i":10,"fail":true}` + "\n",
+	}, cr.sortedBodies())
+}
+
+func TestDatasetsService_IngestChunked_MaxBytes(t *testing.T) {
+	input := `{"a":"1"}
+{"a":"22"}
+{"a":"this record exceeds the limit"}
+{"a":"3"}
+`
+
+	cr := &chunkRecorder{t: t}
+
+	client, teardown := setup(t, "/api/v1/datasets/test/ingest", cr.ServeHTTP)
+	defer teardown()
+
+	res, err := client.Datasets.IngestChunked(context.Background(), "test", strings.NewReader(input), NDJSON, IngestOptions{},
+		SetChunkMaxBytes(24),
+	)
+	require.NoError(t, err)
+
+	assert.EqualValues(t, 4, res.Ingested)
+	assert.Equal(t, []string{
+		`{"a":"1"}` + "\n" + `{"a":"22"}` + "\n",
+		`{"a":"3"}` + "\n",
+		`{"a":"this record exceeds the limit"}` + "\n",
+	}, cr.sortedBodies())
+}
+
+func TestDatasetsService_IngestChunked_CSV(t *testing.T) {
+	input := `name,comment
+alice,"multi
+line, ""quoted"" comment"
+bob,plain
+carol,"another
+one"
+`
+
+	cr := &chunkRecorder{t: t}
+
+	client, teardown := setup(t, "/api/v1/datasets/test/ingest", cr.ServeHTTP)
+	defer teardown()
+
+	res, err := client.Datasets.IngestChunked(context.Background(), "test", strings.NewReader(input), CSV, IngestOptions{},
+		SetChunkMaxEvents(2),
+	)
+	require.NoError(t, err)
+
+	if assert.Len(t, res.Chunks, 2) {
+		assert.EqualValues(t, len("name,comment\n"), res.Chunks[0].Offset)
+		assert.Equal(t, 2, res.Chunks[0].Events)
+		assert.EqualValues(t, strings.Index(input, "carol"), res.Chunks[1].Offset)
+		assert.Equal(t, 2, res.Chunks[1].Index)
+		assert.Equal(t, 1, res.Chunks[1].Events)
+	}
+
+	assert.Equal(t, []string{
+		"name,comment\nalice,\"multi\nline, \"\"quoted\"\" comment\"\nbob,plain\n",
+		"name,comment\ncarol,\"another\none\"\n",
+	}, cr.sortedBodies())
+}
+
+func TestDatasetsService_IngestChunked_Error(t *testing.T) {
+	var input strings.Builder
+	for i := 0; i < 10; i++ {
+		fmt.Fprintf(&input, `{"i":%d}`+"\n", i)
+	}
+
+	cr := &chunkRecorder{
+		t:      t,
+		status: map[int]int{1: http.StatusBadRequest},
+	}
+
+	client, teardown := setup(t, "/api/v1/datasets/test/ingest", cr.ServeHTTP)
+	defer teardown()
+
+	res, err := client.Datasets.IngestChunked(context.Background(), "test", strings.NewReader(input.String()), NDJSON, IngestOptions{},
+		SetChunkMaxEvents(2),
+		SetChunkConcurrency(1),
+	)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "chunk 1 at offset 16: ")
+	}
+
+	// The first chunk made it, the second failed and no more have been
+	// uploaded.
+	require.NotNil(t, res)
+	assert.EqualValues(t, 2, res.Ingested)
+	if assert.Len(t, res.Chunks, 2) {
+		assert.NotNil(t, res.Chunks[0].Status)
+		assert.NoError(t, res.Chunks[0].Err)
+		assert.Nil(t, res.Chunks[1].Status)
+		assert.Error(t, res.Chunks[1].Err)
+	}
+	assert.Len(t, cr.sortedBodies(), 2)
+}
+
+func TestDatasetsService_IngestChunked_Invalid(t *testing.T) {
+	client, teardown := setup(t, "/api/v1/datasets/test/ingest", func(http.ResponseWriter, *http.Request) {
+		t.Error("no request expected")
+	})
+	defer teardown()
+
+	ctx := context.Background()
+
+	_, err := client.Datasets.IngestChunked(ctx, "test", strings.NewReader("[]"), JSON, IngestOptions{})
+	assert.EqualError(t, err, "content type application/json can't be chunked")
+
+	_, err = client.Datasets.IngestChunked(ctx, "test", strings.NewReader("{}"), NDJSON, IngestOptions{}, SetChunkMaxBytes(0))
+	assert.EqualError(t, err, "maximum chunk bytes must be greater than zero")
+
+	_, err = client.Datasets.IngestChunked(ctx, "test", strings.NewReader("{}"), NDJSON, IngestOptions{}, SetChunkMaxEvents(-1))
+	assert.EqualError(t, err, "maximum chunk events must be greater than zero")
+
+	_, err = client.Datasets.IngestChunked(ctx, "test", strings.NewReader("{}"), NDJSON, IngestOptions{}, SetChunkConcurrency(0))
+	assert.EqualError(t, err, "chunk concurrency must be greater than zero")
+
+	res, err := client.Datasets.IngestChunked(ctx, "test", strings.NewReader(" \n"), NDJSON, IngestOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, res.Chunks)
+
+	canceledCtx, cancel := context.WithCancel(ctx)
+	cancel()
+
+	_, err = client.Datasets.IngestChunked(canceledCtx, "test", strings.NewReader("{}"), NDJSON, IngestOptions{})
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -12,6 +12,7 @@ import (
 	"os"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -30,6 +31,10 @@ type service struct {
 // response wraps the default http.Response type. It never has an open body.
 type response struct {
 	*http.Response
+
+	// Limit is the limit that applies to the request, if reported by the
+	// server.
+	Limit Limit
 }
 
 // DefaultHTTPClient returns the default HTTP client used for making requests.
@@ -55,6 +60,14 @@ type Client struct {
 	userAgent      string
 	strictDecoding bool
 	noEnv          bool
+	retryPolicy    RetryPolicy
+	middleware     []Middleware
+
+	validateQueries   bool
+	ingestCompression Compression
+
+	limits    map[LimitType]Limit
+	limitsMtx sync.RWMutex
 
 	Dashboards    *DashboardsService
 	Datasets      *DatasetsService
@@ -99,6 +112,8 @@ func NewClient(options ...Option) (*Client, error) {
 		userAgent: "axiom-go",
 
 		httpClient: DefaultHTTPClient(),
+
+		ingestCompression: defaultCompression,
 	}
 
 	client.Dashboards = &DashboardsService{client, "/api/v1/dashboards"}
@@ -267,19 +282,70 @@ func (c *Client) newRequest(ctx context.Context, method, path string, body inter
 
 // do sends an API request and returns the API response. The response body is
 // JSON decoded or directly written to v, depending on v being an io.Writer or
-// not.
+// not. The request is retried according to the clients retry policy.
 func (c *Client) do(req *http.Request, v interface{}) (*response, error) {
-	httpResp, err := c.httpClient.Do(req)
+	policy := c.retryPolicy
+	if !policy.enabled() {
+		return c.doOnce(withAttempt(req, 1), v)
+	}
+
+	if policy.BufferBody {
+		if err := bufferBody(req); err != nil {
+			return nil, err
+		}
+	}
+
+	for attempt := 1; ; attempt++ {
+		resp, err := c.doOnce(withAttempt(req, attempt), v)
+		if !policy.shouldRetry(req, resp, err, attempt) {
+			return resp, err
+		}
+
+		if rewindErr := rewindBody(req); rewindErr == ErrNotRetryable {
+			return resp, notRetryableError{err}
+		} else if rewindErr != nil {
+			return resp, err
+		}
+
+		if sleepErr := sleep(req.Context(), policy.backoff(resp, attempt)); sleepErr != nil {
+			return resp, err
+		}
+	}
+}
+
+// doOnce sends an API request exactly once and returns the API response. The
+// response body is JSON decoded or directly written to v, depending on v being
+// an io.Writer or not.
+func (c *Client) doOnce(req *http.Request, v interface{}) (*response, error) {
+	httpResp, err := c.roundTrip(req)
 	if err != nil {
 		return nil, err
 	}
 	defer httpResp.Body.Close()
 
-	resp := &response{httpResp}
+	resp := &response{Response: httpResp}
+
+	// Record the limits reported by the server and pick the one that applies
+	// to this request.
+	limits := parseLimits(resp.Header)
+	c.updateLimits(limits)
+	limitType := limitTypeFromPath(req.URL.Path)
+	for _, limit := range limits {
+		if limit.Type == limitType {
+			resp.Limit = limit
+			break
+		}
+	}
 
 	if statusCode := resp.StatusCode; statusCode >= 400 {
 		// Handle a generic HTTP error if the response is not JSON formatted.
 		if val := resp.Header.Get("Content-Type"); !strings.HasPrefix(val, "application/json") {
+			if statusCode == http.StatusTooManyRequests {
+				return resp, LimitError{
+					Limit:   resp.Limit,
+					Message: http.StatusText(statusCode),
+				}
+			}
 			return resp, Error{
 				Status:  statusCode,
 				Message: http.StatusText(statusCode),
@@ -320,7 +386,10 @@ func (c *Client) do(req *http.Request, v interface{}) (*response, error) {
 		case http.StatusConflict:
 			return resp, fmt.Errorf("%v: %w", errResp, ErrExists)
 		case http.StatusTooManyRequests:
-			return resp, fmt.Errorf("%v: %w", errResp, ErrRateLimitExceeded)
+			return resp, LimitError{
+				Limit:   resp.Limit,
+				Message: errResp.Message,
+			}
 		}
 
 		return resp, errResp
@@ -46,6 +46,38 @@ func SetCloudConfig(accessToken, orgID string) Option {
 	}
 }
 
+// SetIngestCompression specifies the default compression used by
+// `DatasetsService.IngestEvents`, `DatasetsService.IngestStructs` and
+// `DatasetsService.IngestReader`. It can be overridden per call using the
+// `Compression` of the `IngestOptions`. Defaults to `Gzip` compression at level
+// `gzip.BestSpeed`.
+func SetIngestCompression(compression Compression) Option {
+	return func(c *Client) error {
+		normalized, err := compression.normalize()
+		if err != nil {
+			return err
+		}
+		c.ingestCompression = normalized
+		return nil
+	}
+}
+
+// SetMiddleware registers middleware that wraps the sending of requests, e.g.
+// to add tracing, custom headers or metrics. Middleware is applied in the
+// given order, with the first one being the outermost. Repeated use appends to
+// the already registered middleware. The http client of the client, which can
+// be configured using `SetClient()`, is still used to send the requests.
+func SetMiddleware(middleware ...Middleware) Option {
+	return func(c *Client) error {
+		for _, m := range middleware {
+			if m != nil {
+				c.middleware = append(c.middleware, m)
+			}
+		}
+		return nil
+	}
+}
+
 // SetNoEnv prevents the client from deriving its configuration from the
 // environment.
 func SetNoEnv() Option {
@@ -66,6 +98,28 @@ func SetOrgID(orgID string) Option {
 	}
 }
 
+// SetQueryValidation enables client-side validation of queries passed to
+// `DatasetsService.Query` using `query.Query.Validate()`. Invalid queries are
+// rejected with `query.ValidationErrors` before a request is made. Disabled by
+// default.
+func SetQueryValidation(enabled bool) Option {
+	return func(c *Client) error {
+		c.validateQueries = enabled
+		return nil
+	}
+}
+
+// SetRetryPolicy specifies the policy used to retry failed requests. Refer to
+// `RetryPolicy` for the conditions a request is retried on. Retries are
+// disabled by default. `DefaultRetryPolicy()` provides a sensible starting
+// point.
+func SetRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Client) error {
+		c.retryPolicy = policy
+		return nil
+	}
+}
+
 // SetSelfhostConfig specifies all properties needed in order to successfully
 // connect to an Axiom Selfhost deployment.
 func SetSelfhostConfig(deploymentURL, accessToken string) Option {
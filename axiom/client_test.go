@@ -2,6 +2,7 @@ package axiom
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -13,6 +14,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/klauspost/compress/zstd"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -276,6 +278,38 @@ func TestClient_Options_SetCloudConfig(t *testing.T) {
 	assert.Equal(t, orgID, client.orgID)
 }
 
+func TestClient_Options_SetIngestCompression(t *testing.T) {
+	client := newClient(t)
+
+	assert.Equal(t, Compression{Encoding: Gzip, Level: gzip.BestSpeed}, client.ingestCompression)
+
+	opt := SetIngestCompression(Compression{Encoding: Zstd, Concurrency: 1})
+
+	err := client.Options(opt)
+	assert.NoError(t, err)
+
+	assert.Equal(t, Compression{Encoding: Zstd, Level: int(zstd.SpeedDefault), Concurrency: 1}, client.ingestCompression)
+
+	err = client.Options(SetIngestCompression(Compression{Encoding: Gzip, Level: 42}))
+	assert.EqualError(t, err, "invalid gzip compression level 42")
+}
+
+func TestClient_Options_SetMiddleware(t *testing.T) {
+	client := newClient(t)
+
+	assert.Empty(t, client.middleware)
+
+	noop := func(next RoundTripFunc) RoundTripFunc { return next }
+
+	err := client.Options(SetMiddleware(noop, nil))
+	assert.NoError(t, err)
+	assert.Len(t, client.middleware, 1)
+
+	err = client.Options(SetMiddleware(noop))
+	assert.NoError(t, err)
+	assert.Len(t, client.middleware, 2)
+}
+
 func TestClient_Options_SetOrgID(t *testing.T) {
 	client := newClient(t)
 
@@ -288,6 +322,17 @@ func TestClient_Options_SetOrgID(t *testing.T) {
 	assert.Equal(t, exp, client.orgID)
 }
 
+func TestClient_Options_SetQueryValidation(t *testing.T) {
+	client := newClient(t)
+
+	opt := SetQueryValidation(true)
+
+	err := client.Options(opt)
+	assert.NoError(t, err)
+
+	assert.True(t, client.validateQueries)
+}
+
 func TestClient_Options_SetSelfhostConfig(t *testing.T) {
 	client := newClient(t)
 
@@ -555,7 +600,7 @@ func TestAPITokenPathRegex(t *testing.T) {
 // setup sets up a test HTTP server along with a client that is configured to
 // talk to that test server. Tests should pass a handler function which provides
 // the response for the API method being tested.
-func setup(t *testing.T, path string, handler http.HandlerFunc) (*Client, func()) {
+func setup(t testing.TB, path string, handler http.HandlerFunc) (*Client, func()) {
 	t.Helper()
 
 	r := http.NewServeMux()
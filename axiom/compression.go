@@ -0,0 +1,169 @@
+package axiom
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression configures how `DatasetsService.IngestEvents`,
+// `DatasetsService.IngestStructs` and `DatasetsService.IngestReader` compress
+// the data they send to the server. A default for all calls can be set on the
+// client using `SetIngestCompression()`. It can be overridden per call using
+// the `IngestOptions`. The zero value selects the clients default, which is
+// `Gzip` compression at level `gzip.BestSpeed`, unless configured otherwise.
+type Compression struct {
+	// Encoding used to compress events. Must be one of `Gzip`, `Zstd` or
+	// `Identity` to disable compression. Zero selects `Gzip`.
+	Encoding ContentEncoding
+	// Level of compression. For `Gzip`, this is a level of the
+	// `compress/gzip` package, for `Zstd` a `zstd.EncoderLevel` of the
+	// `github.com/klauspost/compress/zstd` package. Zero selects
+	// `gzip.BestSpeed` and `zstd.SpeedDefault`, respectively.
+	Level int
+	// Concurrency is the number of goroutines a `Zstd` encoder uses. Zero
+	// selects the default of the zstd package, which is `GOMAXPROCS`. Ignored
+	// for all other encodings.
+	Concurrency int
+	// MinSize is the size in bytes of the encoded events a batch must at
+	// least have to be compressed. Smaller batches are sent uncompressed as
+	// compressing them doesn't pay off. Zero compresses all batches.
+	MinSize int
+}
+
+// defaultCompression is the compression used by clients if not configured
+// otherwise.
+var defaultCompression = Compression{Encoding: Gzip, Level: gzip.BestSpeed}
+
+// normalize validates the compression and returns it with defaults applied.
+func (c Compression) normalize() (Compression, error) {
+	if c.MinSize < 0 {
+		return c, fmt.Errorf("invalid minimum compression size %d: must not be negative", c.MinSize)
+	}
+
+	if c.Encoding == 0 {
+		c.Encoding = Gzip
+	}
+
+	switch c.Encoding {
+	case Identity:
+		c.Level, c.Concurrency = 0, 0
+	case Gzip:
+		if c.Level == 0 {
+			c.Level = gzip.BestSpeed
+		} else if c.Level < gzip.HuffmanOnly || c.Level > gzip.BestCompression {
+			return c, fmt.Errorf("invalid gzip compression level %d", c.Level)
+		}
+		c.Concurrency = 0
+	case Zstd:
+		if c.Level == 0 {
+			c.Level = int(zstd.SpeedDefault)
+		} else if c.Level < int(zstd.SpeedFastest) || c.Level > int(zstd.SpeedBestCompression) {
+			return c, fmt.Errorf("invalid zstd compression level %d", c.Level)
+		}
+		if c.Concurrency < 0 {
+			return c, fmt.Errorf("invalid zstd encoder concurrency %d: must not be negative", c.Concurrency)
+		}
+	default:
+		return c, ErrUnknownContentEncoding
+	}
+
+	return c, nil
+}
+
+// Encoder returns a `ContentEncoder` that compresses the data it reads
+// according to the compression configuration. The data is returned as is for
+// `Identity` encoding. Encoders are pooled and reused. The `MinSize` is not
+// considered by the returned encoder.
+func (c Compression) Encoder() ContentEncoder {
+	return func(r io.Reader) (io.Reader, error) {
+		c, err := c.normalize()
+		if err != nil {
+			return nil, err
+		} else if c.Encoding == Identity {
+			return r, nil
+		}
+
+		pr, pw := io.Pipe()
+
+		cw := getCompressor(c, pw)
+		go func() {
+			defer putCompressor(c, cw)
+
+			_, err := io.Copy(cw, r)
+			if closeErr := cw.Close(); err == nil && closeErr != nil {
+				// If we have no error from copying but from closing, capture
+				// that one.
+				err = closeErr
+			}
+			_ = pw.CloseWithError(err)
+		}()
+
+		return pr, nil
+	}
+}
+
+// compressor is a compressing writer that can be reused by resetting it.
+type compressor interface {
+	io.WriteCloser
+	Reset(w io.Writer)
+}
+
+// compressorPoolKey identifies the pool of compressors sharing the same
+// configuration.
+type compressorPoolKey struct {
+	encoding    ContentEncoding
+	level       int
+	concurrency int
+}
+
+// compressorPools holds a pool of compressors per configuration.
+var compressorPools sync.Map // map[compressorPoolKey]*sync.Pool
+
+// getCompressor returns a pooled compressor configured by the given,
+// normalized compression that writes to the given writer. It must be returned
+// to the pool using `putCompressor()` after it has been closed.
+func getCompressor(c Compression, w io.Writer) compressor {
+	cw := compressorPool(c).Get().(compressor)
+	cw.Reset(w)
+	return cw
+}
+
+func putCompressor(c Compression, cw compressor) {
+	cw.Reset(nil)
+	compressorPool(c).Put(cw)
+}
+
+func compressorPool(c Compression) *sync.Pool {
+	key := compressorPoolKey{
+		encoding:    c.Encoding,
+		level:       c.Level,
+		concurrency: c.Concurrency,
+	}
+
+	if pool, ok := compressorPools.Load(key); ok {
+		return pool.(*sync.Pool)
+	}
+
+	pool, _ := compressorPools.LoadOrStore(key, &sync.Pool{
+		New: func() interface{} {
+			// The configuration is validated before, so creating the
+			// compressors never fails.
+			if c.Encoding == Zstd {
+				opts := []zstd.EOption{zstd.WithEncoderLevel(zstd.EncoderLevel(c.Level))}
+				if c.Concurrency > 0 {
+					opts = append(opts, zstd.WithEncoderConcurrency(c.Concurrency))
+				}
+				zw, _ := zstd.NewWriter(nil, opts...)
+				return zw
+			}
+			gzw, _ := gzip.NewWriterLevel(nil, c.Level)
+			return gzw
+		},
+	})
+
+	return pool.(*sync.Pool)
+}
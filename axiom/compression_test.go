@@ -0,0 +1,120 @@
+package axiom
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompression_normalize(t *testing.T) {
+	tests := []struct {
+		input Compression
+		want  Compression
+		err   string
+	}{
+		{
+			input: Compression{Encoding: Gzip},
+			want:  Compression{Encoding: Gzip, Level: gzip.BestSpeed},
+		},
+		{
+			input: Compression{Encoding: Gzip, Level: gzip.BestCompression, Concurrency: 4},
+			want:  Compression{Encoding: Gzip, Level: gzip.BestCompression},
+		},
+		{
+			input: Compression{Encoding: Zstd, Concurrency: 2, MinSize: 512},
+			want:  Compression{Encoding: Zstd, Level: int(zstd.SpeedDefault), Concurrency: 2, MinSize: 512},
+		},
+		{
+			input: Compression{Encoding: Identity, Level: 3},
+			want:  Compression{Encoding: Identity},
+		},
+		{
+			input: Compression{},
+			want:  Compression{Encoding: Gzip, Level: gzip.BestSpeed},
+		},
+		{
+			input: Compression{MinSize: 512},
+			want:  Compression{Encoding: Gzip, Level: gzip.BestSpeed, MinSize: 512},
+		},
+		{
+			input: Compression{Encoding: ContentEncoding(42)},
+			err:   ErrUnknownContentEncoding.Error(),
+		},
+		{
+			input: Compression{Encoding: Gzip, Level: 10},
+			err:   "invalid gzip compression level 10",
+		},
+		{
+			input: Compression{Encoding: Zstd, Level: 5},
+			err:   "invalid zstd compression level 5",
+		},
+		{
+			input: Compression{Encoding: Zstd, Concurrency: -1},
+			err:   "invalid zstd encoder concurrency -1: must not be negative",
+		},
+		{
+			input: Compression{Encoding: Gzip, MinSize: -1},
+			err:   "invalid minimum compression size -1: must not be negative",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input.Encoding.String(), func(t *testing.T) {
+			got, err := tt.input.normalize()
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCompression_Encoder(t *testing.T) {
+	exp := strings.Repeat("Some fox jumps over a fence. ", 100)
+
+	decoders := map[ContentEncoding]func(r io.Reader) (io.Reader, error){
+		Identity: func(r io.Reader) (io.Reader, error) { return r, nil },
+		Gzip:     func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+		Zstd: func(r io.Reader) (io.Reader, error) {
+			zr, err := zstd.NewReader(r)
+			return zr, err
+		},
+	}
+
+	tests := []Compression{
+		{Encoding: Identity},
+		{Encoding: Gzip},
+		{Encoding: Gzip, Level: gzip.BestCompression},
+		{Encoding: Zstd},
+		{Encoding: Zstd, Level: int(zstd.SpeedFastest), Concurrency: 1},
+	}
+	for _, tt := range tests {
+		// Run multiple times to make sure reused encoders produce valid
+		// output.
+		for i := 0; i < 3; i++ {
+			r, err := tt.Encoder()(strings.NewReader(exp))
+			require.NoError(t, err)
+
+			b, err := io.ReadAll(r)
+			require.NoError(t, err)
+
+			dr, err := decoders[tt.Encoding](bytes.NewReader(b))
+			require.NoError(t, err)
+
+			act, err := io.ReadAll(dr)
+			require.NoError(t, err)
+
+			assert.Equal(t, exp, string(act), "%+v", tt)
+		}
+	}
+
+	_, err := Compression{Encoding: Gzip, Level: 42}.Encoder()(strings.NewReader(exp))
+	assert.Error(t, err)
+}
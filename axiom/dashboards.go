@@ -122,6 +122,8 @@ type DashboardsService service
 
 // List all available dashboards.
 func (s *DashboardsService) List(ctx context.Context, opts ListOptions) ([]*Dashboard, error) {
+	ctx = withOperation(ctx, "Dashboards.List")
+
 	path, err := addOptions(s.basePath, opts)
 	if err != nil {
 		return nil, err
@@ -137,6 +139,8 @@ func (s *DashboardsService) List(ctx context.Context, opts ListOptions) ([]*Dash
 
 // Get a dashboard by id.
 func (s *DashboardsService) Get(ctx context.Context, id string) (*Dashboard, error) {
+	ctx = withOperation(ctx, "Dashboards.Get")
+
 	path := s.basePath + "/" + id
 
 	var res Dashboard
@@ -150,6 +154,8 @@ func (s *DashboardsService) Get(ctx context.Context, id string) (*Dashboard, err
 // Create a dashboard with the given properties. The ID and Version fields of
 // the request payload are ignored.
 func (s *DashboardsService) Create(ctx context.Context, req Dashboard) (*Dashboard, error) {
+	ctx = withOperation(ctx, "Dashboards.Create")
+
 	var res Dashboard
 	if err := s.client.call(ctx, http.MethodPost, s.basePath, req, &res); err != nil {
 		return nil, err
@@ -162,6 +168,8 @@ func (s *DashboardsService) Create(ctx context.Context, req Dashboard) (*Dashboa
 // When updating, the Version is mandantory and must be set to the current
 // version of the dashboard as returned by a Get() call.
 func (s *DashboardsService) Update(ctx context.Context, id string, req Dashboard) (*Dashboard, error) {
+	ctx = withOperation(ctx, "Dashboards.Update")
+
 	path := s.basePath + "/" + id
 
 	var res Dashboard
@@ -174,5 +182,7 @@ func (s *DashboardsService) Update(ctx context.Context, id string, req Dashboard
 
 // Delete the dashboard identified by the given id.
 func (s *DashboardsService) Delete(ctx context.Context, id string) error {
+	ctx = withOperation(ctx, "Dashboards.Delete")
+
 	return s.client.call(ctx, http.MethodDelete, s.basePath+"/"+id, nil, nil)
 }
@@ -5,14 +5,16 @@ import (
 	"bytes"
 	"compress/gzip"
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"reflect"
 	"time"
 	"unicode"
 
+	"github.com/klauspost/compress/zstd"
+
 	"github.com/axiomhq/axiom-go/axiom/apl"
 	"github.com/axiomhq/axiom-go/axiom/query"
 )
@@ -163,11 +165,27 @@ type IngestStatus struct {
 }
 
 // IngestFailure describes the ingestion failure of a single event.
+//
+// The server only reports the timestamp of a failed event. The client
+// correlates failures with the ingested events by matching their timestamps,
+// which requires the events to carry a timestamp that can be parsed by the
+// client (a `time.Time` or a string in RFC 3339 format or the format given by
+// `IngestOptions.TimestampFormat`). Events with the same timestamp are matched
+// in order. Data passed to `DatasetsService.Ingest` and
+// `DatasetsService.IngestReader` can only be correlated if it is newline
+// delimited JSON or CSV and not compressed.
 type IngestFailure struct {
 	// Timestamp of the event that failed to ingest.
 	Timestamp time.Time `json:"timestamp"`
 	// Error that made the event fail to ingest.
 	Error string `json:"error"`
+	// Index of the event that failed to ingest in the ingested batch or -1, if
+	// the failure couldn't be correlated with an event.
+	Index int `json:"-"`
+	// Line of the ingested data, starting at 1, the event that failed to
+	// ingest starts on or 0, if the failure couldn't be correlated with an
+	// event. For CSV data, the header is line 1.
+	Line int `json:"-"`
 }
 
 // DatasetCreateRequest is a request used to create a dataset.
@@ -234,6 +252,10 @@ type IngestOptions struct {
 	// CSVDelimiter is the delimiter that separates CSV fields. Only valid when
 	// the content to be ingested is CSV formatted.
 	CSVDelimiter string `url:"csv-delimiter,omitempty"`
+	// Compression configures how events are compressed. Only honored by
+	// IngestEvents, IngestStructs and IngestReader. Defaults to the compression
+	// configured on the client.
+	Compression Compression `url:"-"`
 }
 
 // DatasetsService handles communication with the dataset related operations of
@@ -247,6 +269,8 @@ type DatasetsService service
 // information of a specific dataset is preferred, when no aggregated
 // statistics across all datasets are needed.
 func (s *DatasetsService) Stats(ctx context.Context) (*DatasetStats, error) {
+	ctx = withOperation(ctx, "Datasets.Stats")
+
 	path := s.basePath + "/_stats"
 
 	var res *DatasetStats
@@ -259,6 +283,8 @@ func (s *DatasetsService) Stats(ctx context.Context) (*DatasetStats, error) {
 
 // List all available datasets.
 func (s *DatasetsService) List(ctx context.Context) ([]*Dataset, error) {
+	ctx = withOperation(ctx, "Datasets.List")
+
 	var res []*datasetResponse
 	if err := s.client.call(ctx, http.MethodGet, s.basePath, nil, &res); err != nil {
 		return nil, err
@@ -274,6 +300,8 @@ func (s *DatasetsService) List(ctx context.Context) ([]*Dataset, error) {
 
 // Get a dataset by id.
 func (s *DatasetsService) Get(ctx context.Context, id string) (*Dataset, error) {
+	ctx = withOperation(ctx, "Datasets.Get")
+
 	path := s.basePath + "/" + id
 
 	var res datasetResponse
@@ -286,6 +314,8 @@ func (s *DatasetsService) Get(ctx context.Context, id string) (*Dataset, error)
 
 // Create a dataset with the given properties.
 func (s *DatasetsService) Create(ctx context.Context, req DatasetCreateRequest) (*Dataset, error) {
+	ctx = withOperation(ctx, "Datasets.Create")
+
 	var res datasetResponse
 	if err := s.client.call(ctx, http.MethodPost, s.basePath, req, &res); err != nil {
 		return nil, err
@@ -296,6 +326,8 @@ func (s *DatasetsService) Create(ctx context.Context, req DatasetCreateRequest)
 
 // Update the dataset identified by the given id with the given properties.
 func (s *DatasetsService) Update(ctx context.Context, id string, req DatasetUpdateRequest) (*Dataset, error) {
+	ctx = withOperation(ctx, "Datasets.Update")
+
 	path := s.basePath + "/" + id
 
 	var res datasetResponse
@@ -309,6 +341,8 @@ func (s *DatasetsService) Update(ctx context.Context, id string, req DatasetUpda
 // Update the named field of the dataset identified by the given id with the
 // given properties.
 func (s *DatasetsService) UpdateField(ctx context.Context, dataset, field string, req FieldUpdateRequest) (*Field, error) {
+	ctx = withOperation(ctx, "Datasets.UpdateField")
+
 	path := s.basePath + "/" + dataset + "/fields/" + field
 
 	var res Field
@@ -321,11 +355,15 @@ func (s *DatasetsService) UpdateField(ctx context.Context, dataset, field string
 
 // Delete the dataset identified by the given id.
 func (s *DatasetsService) Delete(ctx context.Context, id string) error {
+	ctx = withOperation(ctx, "Datasets.Delete")
+
 	return s.client.call(ctx, http.MethodDelete, s.basePath+"/"+id, nil, nil)
 }
 
 // Info retrieves the information of the dataset identified by its id.
 func (s *DatasetsService) Info(ctx context.Context, id string) (*DatasetInfo, error) {
+	ctx = withOperation(ctx, "Datasets.Info")
+
 	path := s.basePath + "/" + id + "/info"
 
 	var res DatasetInfo
@@ -340,6 +378,8 @@ func (s *DatasetsService) Info(ctx context.Context, id string) (*DatasetInfo, er
 // given will mark the oldest timestamp an event can have. Older ones will be
 // deleted from the dataset.
 func (s *DatasetsService) Trim(ctx context.Context, id string, maxDuration time.Duration) (*TrimResult, error) {
+	ctx = withOperation(ctx, "Datasets.Trim")
+
 	req := datasetTrimRequest{
 		MaxDuration: maxDuration.String(),
 	}
@@ -357,6 +397,8 @@ func (s *DatasetsService) Trim(ctx context.Context, id string, maxDuration time.
 // History retrieves the query stored inside the query history dataset
 // identified by its id.
 func (s *DatasetsService) History(ctx context.Context, id string) (*query.History, error) {
+	ctx = withOperation(ctx, "Datasets.History")
+
 	path := s.basePath + "/_history/" + id
 
 	var res query.History
@@ -371,6 +413,8 @@ func (s *DatasetsService) History(ctx context.Context, id string) (*query.Histor
 // names (JSON object keys) can be reviewed here:
 // https://www.axiom.co/docs/usage/field-restrictions.
 func (s *DatasetsService) Ingest(ctx context.Context, id string, r io.Reader, typ ContentType, enc ContentEncoding, opts IngestOptions) (*IngestStatus, error) {
+	ctx = withOperation(ctx, "Datasets.Ingest")
+
 	path, err := addOptions(s.basePath+"/"+id+"/ingest", opts)
 	if err != nil {
 		return nil, err
@@ -388,8 +432,12 @@ func (s *DatasetsService) Ingest(ctx context.Context, id string, r io.Reader, ty
 		return nil, ErrUnknownContentType
 	}
 
+	var tracker *recordTracker
 	switch enc {
 	case Identity:
+		if tracker = newRecordTracker(typ, opts); tracker != nil {
+			tracker.track(req)
+		}
 	case Gzip, Zstd:
 		req.Header.Set("Content-Encoding", enc.String())
 	default:
@@ -401,71 +449,248 @@ func (s *DatasetsService) Ingest(ctx context.Context, id string, r io.Reader, ty
 		return nil, err
 	}
 
+	if tracker != nil {
+		tracker.correlate(res.Failures)
+	} else {
+		correlateFailures(res.Failures, 0, nil)
+	}
+
 	return &res, nil
 }
 
+// IngestReader ingests the data read from the given reader into the dataset
+// identified by its id. Unlike `Ingest()`, the content type and encoding of
+// the data are detected automatically: Gzip and zstd compressed data is
+// recognized by its magic bytes and sent as is. The content type is detected
+// using `DetectContentType()`, after decompressing the beginning of the data,
+// if necessary.
+//
+// Uncompressed data is compressed as configured by the `Compression` of the
+// given options or the clients default, if none is set. Use `Identity` as
+// encoding to send it uncompressed.
+func (s *DatasetsService) IngestReader(ctx context.Context, id string, r io.Reader, opts IngestOptions) (*IngestStatus, error) {
+	ctx = withOperation(ctx, "Datasets.IngestReader")
+
+	comp, err := s.compression(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	size := sniffSize
+	if comp.MinSize >= size {
+		size = comp.MinSize + 1
+	}
+	br := bufio.NewReaderSize(r, size)
+
+	enc, err := detectContentEncoding(br)
+	if err != nil {
+		return nil, err
+	}
+
+	var typ ContentType
+	if enc != Identity {
+		if typ, err = detectCompressedContentType(br, enc); err != nil {
+			return nil, err
+		}
+		return s.Ingest(ctx, id, br, typ, enc, opts)
+	}
+
+	// Small inputs that fit into the buffer entirely are not worth
+	// compressing.
+	if b, peekErr := br.Peek(comp.MinSize); peekErr == io.EOF && len(b) < comp.MinSize {
+		comp.Encoding = Identity
+	} else if peekErr != nil && peekErr != io.EOF {
+		return nil, peekErr
+	}
+
+	if r, typ, err = DetectContentType(br); err != nil {
+		return nil, err
+	} else if comp.Encoding == Identity {
+		return s.Ingest(ctx, id, r, typ, comp.Encoding, opts)
+	}
+
+	// Keep track of the records before they are compressed, so failures can
+	// still be correlated with them.
+	tracker := newRecordTracker(typ, opts)
+	if tracker != nil {
+		r = io.TeeReader(r, tracker)
+	}
+	if r, err = comp.Encoder()(r); err != nil {
+		return nil, err
+	}
+
+	res, err := s.Ingest(ctx, id, r, typ, comp.Encoding, opts)
+	if err == nil && tracker != nil {
+		tracker.correlate(res.Failures)
+	}
+	return res, err
+}
+
 // IngestEvents ingests events into the dataset identified by its id.
 // Restrictions for field names (JSON object keys) can be reviewed here:
 // https://www.axiom.co/docs/usage/field-restrictions.
+//
+// The events are compressed as configured by the `Compression` of the given
+// options or the clients default, if none is set.
 func (s *DatasetsService) IngestEvents(ctx context.Context, id string, opts IngestOptions, events ...Event) (*IngestStatus, error) {
-	if len(events) == 0 {
+	ctx = withOperation(ctx, "Datasets.IngestEvents")
+
+	timestampField := opts.TimestampField
+	if timestampField == "" {
+		timestampField = TimestampField
+	}
+
+	return s.ingestNDJSON(ctx, id, opts, len(events), eventsEncoder(events), func(i int) (time.Time, bool) {
+		return parseTimestamp(events[i][timestampField], opts.TimestampFormat)
+	})
+}
+
+// IngestStructs ingests the given items into the dataset identified by its id.
+// Items must be a slice or array of structs or pointers to structs which are
+// encoded as events according to their `axiom` struct tags. The encoder of a
+// struct type is built on first use and cached afterwards. Refer to
+// `StructTagName` for the supported tag options.
+//
+// The time field of the structs, if any, is encoded as the `TimestampField` of
+// the given options or as `_time`, if none is set. The events are compressed
+// like the ones passed to `IngestEvents()`.
+func (s *DatasetsService) IngestStructs(ctx context.Context, id string, opts IngestOptions, items interface{}) (*IngestStatus, error) {
+	ctx = withOperation(ctx, "Datasets.IngestStructs")
+
+	rv, enc, err := structItems(items)
+	if err != nil {
+		return nil, err
+	}
+
+	timestampField := opts.TimestampField
+	if timestampField == "" {
+		timestampField = TimestampField
+	}
+
+	return s.ingestNDJSON(ctx, id, opts, rv.Len(), func(buf []byte, i int) ([]byte, error) {
+		return enc.encode(buf, reflect.Indirect(rv.Index(i)), timestampField)
+	}, func(i int) (time.Time, bool) {
+		return enc.timestamp(reflect.Indirect(rv.Index(i)))
+	})
+}
+
+// compression returns the compression configured by the given options or the
+// clients default, if none is set.
+func (s *DatasetsService) compression(opts IngestOptions) (Compression, error) {
+	if opts.Compression == (Compression{}) {
+		return s.client.ingestCompression, nil
+	}
+	return opts.Compression.normalize()
+}
+
+// ingestNDJSON ingests n events, encoded by the given encoder, as newline
+// delimited JSON into the dataset identified by its id. Failures are
+// correlated with the events using the timestamps returned by the given
+// function.
+func (s *DatasetsService) ingestNDJSON(ctx context.Context, id string, opts IngestOptions, n int, encode eventEncoder, timestamp func(i int) (time.Time, bool)) (*IngestStatus, error) {
+	if n == 0 {
 		return &IngestStatus{}, nil
 	}
 
+	comp, err := s.compression(opts)
+	if err != nil {
+		return nil, err
+	}
+
 	path, err := addOptions(s.basePath+"/"+id+"/ingest", opts)
 	if err != nil {
 		return nil, err
 	}
 
-	pr, pw := io.Pipe()
-	go func() {
-		gzw, wErr := gzip.NewWriterLevel(pw, gzip.BestSpeed)
-		if wErr != nil {
-			_ = pw.CloseWithError(wErr)
-			return
-		}
+	bp := getBuffer()
 
-		var (
-			enc    = json.NewEncoder(gzw)
-			encErr error
-		)
-		for _, event := range events {
-			if encErr = enc.Encode(event); encErr != nil {
-				break
+	// Encode events up front until the minimum size for compression is
+	// reached. If the batch turns out to be smaller, it is sent uncompressed.
+	buf, i, err := appendNDJSON((*bp)[:0], 0, n, comp.MinSize, encode)
+	if err != nil {
+		putBuffer(bp)
+		return nil, err
+	}
+
+	var body io.Reader
+	if i == n && len(buf) < comp.MinSize {
+		comp.Encoding = Identity
+		body = bytes.NewReader(buf)
+		defer func() {
+			*bp = buf
+			putBuffer(bp)
+		}()
+	} else {
+		pr, pw := io.Pipe()
+		// Unblock the encoding goroutine in case the request is aborted
+		// before the body is read in full.
+		defer pr.Close()
+
+		go func(comp Compression) {
+			defer putBuffer(bp)
+
+			var (
+				w  io.Writer = pw
+				cw compressor
+			)
+			if comp.Encoding != Identity {
+				cw = getCompressor(comp, pw)
+				defer putCompressor(comp, cw)
+				w = cw
 			}
-		}
 
-		if closeErr := gzw.Close(); encErr == nil && closeErr != nil {
-			// If we have no error from encoding but from closing, capture that
-			// one.
-			encErr = closeErr
-		}
-		_ = pw.CloseWithError(encErr)
-	}()
+			var encErr error
+			*bp, encErr = writeNDJSON(w, buf, i, n, encode)
+			if cw != nil {
+				if closeErr := cw.Close(); encErr == nil && closeErr != nil {
+					// If we have no error from encoding but from closing,
+					// capture that one.
+					encErr = closeErr
+				}
+			}
+			_ = pw.CloseWithError(encErr)
+		}(comp)
+		body = pr
+	}
 
-	req, err := s.client.newRequest(ctx, http.MethodPost, path, pr)
+	req, err := s.client.newRequest(ctx, http.MethodPost, path, body)
 	if err != nil {
 		return nil, err
 	}
 
 	req.Header.Set("Content-Type", NDJSON.String())
-	req.Header.Set("Content-Encoding", Gzip.String())
+	if comp.Encoding != Identity {
+		req.Header.Set("Content-Encoding", comp.Encoding.String())
+	}
 
 	var res IngestStatus
 	if _, err = s.client.do(req, &res); err != nil {
 		return nil, err
 	}
 
+	correlateFailures(res.Failures, n, func(i int) (time.Time, int, bool) {
+		ts, ok := timestamp(i)
+		return ts, i + 1, ok
+	})
+
 	return &res, nil
 }
 
 // Query executes the given query on the dataset identified by its id.
 func (s *DatasetsService) Query(ctx context.Context, id string, q query.Query, opts query.Options) (*query.Result, error) {
+	ctx = withOperation(ctx, "Datasets.Query")
+
 	if opts.SaveKind == query.APL {
 		return nil, fmt.Errorf("invalid query kind %q: must be %q or %q",
 			opts.SaveKind, query.Analytics, query.Stream)
 	}
 
+	if s.client.validateQueries {
+		if err := q.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
 	path, err := addOptions(s.basePath+"/"+id+"/query", opts)
 	if err != nil {
 		return nil, err
@@ -491,6 +716,8 @@ func (s *DatasetsService) Query(ctx context.Context, id string, q query.Query, o
 // APLQuery executes the given query specified using the Axiom Processing
 // Language (APL).
 func (s *DatasetsService) APLQuery(ctx context.Context, raw string, opts apl.Options) (*apl.Result, error) {
+	ctx = withOperation(ctx, "Datasets.APLQuery")
+
 	path, err := addOptions(s.basePath+"/_apl", opts)
 	if err != nil {
 		return nil, err
@@ -519,7 +746,7 @@ func (s *DatasetsService) APLQuery(ctx context.Context, raw string, opts apl.Opt
 
 // DetectContentType detects the content type of an io.Reader's data. The
 // returned io.Reader must be used instead of the passed one. Compressed content
-// is not detected.
+// is not detected, use `DetectContentEncoding()` for that.
 func DetectContentType(r io.Reader) (io.Reader, ContentType, error) {
 	var (
 		br  = bufio.NewReader(r)
@@ -552,14 +779,82 @@ func DetectContentType(r io.Reader) (io.Reader, ContentType, error) {
 		break
 	}
 
-	// Create a new reader and prepend what we have already consumed in order to
-	// figure out the content type.
-	buf, err := br.Peek(br.Buffered())
+	// The buffered reader still holds what has been consumed in order to
+	// figure out the content type. Don't wrap it in another reader because
+	// `bufio.NewReader()` returns the passed reader, if it already is a
+	// sufficiently large `bufio.Reader`.
+	return br, typ, nil
+}
+
+// DetectContentEncoding detects the content encoding of an io.Reader's data by
+// looking for the magic bytes of gzip and zstd compressed data. Data that is
+// not compressed is reported as `Identity`. The returned io.Reader must be used
+// instead of the passed one.
+func DetectContentEncoding(r io.Reader) (io.Reader, ContentEncoding, error) {
+	br := bufio.NewReader(r)
+	enc, err := detectContentEncoding(br)
 	if err != nil {
 		return nil, 0, err
 	}
-	alreadyRead := bytes.NewReader(buf)
-	r = io.MultiReader(alreadyRead, r)
+	return br, enc, nil
+}
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// sniffSize is the amount of compressed data that is decompressed to detect
+// the content type of compressed data.
+const sniffSize = 64 << 10 // 64 KiB
+
+func detectContentEncoding(br *bufio.Reader) (ContentEncoding, error) {
+	b, err := br.Peek(len(zstdMagic))
+	if err != nil && err != io.EOF {
+		return 0, err
+	}
+
+	switch {
+	case bytes.HasPrefix(b, gzipMagic):
+		return Gzip, nil
+	case bytes.HasPrefix(b, zstdMagic):
+		return Zstd, nil
+	}
+	return Identity, nil
+}
+
+// detectCompressedContentType detects the content type of the compressed data
+// buffered by the given reader without consuming it.
+func detectCompressedContentType(br *bufio.Reader, enc ContentEncoding) (ContentType, error) {
+	b, err := br.Peek(br.Size())
+	if err != nil && err != io.EOF {
+		return 0, err
+	}
+
+	var dr io.Reader
+	switch enc {
+	case Gzip:
+		gzr, err := gzip.NewReader(bytes.NewReader(b))
+		if err != nil {
+			return 0, fmt.Errorf("read gzip header: %w", err)
+		}
+		defer gzr.Close()
+		dr = gzr
+	case Zstd:
+		zr, err := zstd.NewReader(bytes.NewReader(b), zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return 0, err
+		}
+		defer zr.Close()
+		dr = zr
+	default:
+		return 0, ErrUnknownContentEncoding
+	}
+
+	_, typ, err := DetectContentType(dr)
+	if err != nil {
+		return 0, fmt.Errorf("detect content type of %s compressed data: %w", enc, err)
+	}
 
-	return r, typ, nil
+	return typ, nil
 }
@@ -1,9 +1,11 @@
 package axiom
 
 import (
+	"bytes"
 	"compress/gzip"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -11,6 +13,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/klauspost/compress/zstd"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -865,6 +868,128 @@ func TestDatasetsService_Ingest(t *testing.T) {
 	assert.Equal(t, exp, res)
 }
 
+func TestDatasetsService_IngestReader(t *testing.T) {
+	const (
+		ndjson = `{"foo":"bar"}
+{"foo":"baz"}
+`
+		csv = `foo,number
+bar,42
+`
+	)
+
+	tests := []struct {
+		name         string
+		input        []byte
+		compression  Compression
+		wantType     string
+		wantEncoding string
+		wantBody     string
+	}{
+		{
+			name:         "uncompressed ndjson is compressed",
+			input:        []byte(ndjson),
+			wantType:     "application/x-ndjson",
+			wantEncoding: "gzip",
+			wantBody:     ndjson,
+		},
+		{
+			name:         "uncompressed csv is compressed as configured",
+			input:        []byte(csv),
+			compression:  Compression{Encoding: Zstd, Concurrency: 1},
+			wantType:     "text/csv",
+			wantEncoding: "zstd",
+			wantBody:     csv,
+		},
+		{
+			name:        "uncompressed json is sent as is",
+			input:       []byte(`  [{"foo":"bar"}]`),
+			compression: Compression{Encoding: Identity},
+			wantType:    "application/json",
+			// Leading whitespace is skipped by the content type detection.
+			wantBody: `[{"foo":"bar"}]`,
+		},
+		{
+			name:        "small input is not compressed",
+			input:       []byte(ndjson),
+			compression: Compression{Encoding: Gzip, MinSize: 1024},
+			wantType:    "application/x-ndjson",
+			wantBody:    ndjson,
+		},
+		{
+			name:         "gzip compressed ndjson",
+			input:        compress(t, Gzip, ndjson),
+			compression:  Compression{Encoding: Zstd},
+			wantType:     "application/x-ndjson",
+			wantEncoding: "gzip",
+			wantBody:     ndjson,
+		},
+		{
+			name:         "zstd compressed csv",
+			input:        compress(t, Zstd, "\n"+csv),
+			wantType:     "text/csv",
+			wantEncoding: "zstd",
+			wantBody:     "\n" + csv,
+		},
+		{
+			name:         "large gzip compressed ndjson",
+			input:        compress(t, Gzip, strings.Repeat(ndjson, 1<<14)),
+			wantType:     "application/x-ndjson",
+			wantEncoding: "gzip",
+			wantBody:     strings.Repeat(ndjson, 1<<14),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hf := func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, tt.wantType, r.Header.Get("Content-Type"))
+				assert.Equal(t, tt.wantEncoding, r.Header.Get("Content-Encoding"))
+
+				var body io.Reader = r.Body
+				switch tt.wantEncoding {
+				case "gzip":
+					gzr, err := gzip.NewReader(r.Body)
+					require.NoError(t, err)
+					defer gzr.Close()
+					body = gzr
+				case "zstd":
+					zr, err := zstd.NewReader(r.Body)
+					require.NoError(t, err)
+					defer zr.Close()
+					body = zr
+				}
+
+				b, err := io.ReadAll(body)
+				require.NoError(t, err)
+				assert.Equal(t, tt.wantBody, string(b))
+
+				_, _ = fmt.Fprint(w, `{"ingested":2,"failed":0,"failures":[],"processedBytes":0,"blocksCreated":0,"walLength":2}`)
+			}
+
+			client, teardown := setup(t, "/api/v1/datasets/test/ingest", hf)
+			defer teardown()
+
+			res, err := client.Datasets.IngestReader(context.Background(), "test", bytes.NewReader(tt.input), IngestOptions{
+				Compression: tt.compression,
+			})
+			require.NoError(t, err)
+
+			assert.EqualValues(t, 2, res.Ingested)
+		})
+	}
+
+	client, teardown := setup(t, "/api/v1/datasets/test/ingest", func(http.ResponseWriter, *http.Request) {
+		t.Error("no request expected")
+	})
+	defer teardown()
+
+	_, err := client.Datasets.IngestReader(context.Background(), "test", strings.NewReader("123"), IngestOptions{})
+	assert.EqualError(t, err, "cannot determine content type")
+
+	_, err = client.Datasets.IngestReader(context.Background(), "test", bytes.NewReader(compress(t, Gzip, "123")), IngestOptions{})
+	assert.EqualError(t, err, "detect content type of gzip compressed data: cannot determine content type")
+}
+
 func TestDatasetsService_IngestEvents(t *testing.T) {
 	exp := &IngestStatus{
 		Ingested:       2,
@@ -929,6 +1054,203 @@ func TestDatasetsService_IngestEvents(t *testing.T) {
 	assert.Equal(t, exp, res)
 }
 
+func TestDatasetsService_IngestEvents_Compression(t *testing.T) {
+	events := []Event{
+		{"foo": "bar", "number": 42},
+		{"foo": "baz", "number": 43},
+	}
+
+	tests := []struct {
+		name          string
+		clientOptions []Option
+		compression   Compression
+		wantEncoding  string
+	}{
+		{
+			name:         "client default",
+			wantEncoding: "gzip",
+		},
+		{
+			name:          "client zstd",
+			clientOptions: []Option{SetIngestCompression(Compression{Encoding: Zstd, Concurrency: 1})},
+			wantEncoding:  "zstd",
+		},
+		{
+			name:          "per call overrides client",
+			clientOptions: []Option{SetIngestCompression(Compression{Encoding: Zstd})},
+			compression:   Compression{Encoding: Gzip, Level: gzip.BestCompression},
+			wantEncoding:  "gzip",
+		},
+		{
+			name:         "per call zstd",
+			compression:  Compression{Encoding: Zstd, Level: int(zstd.SpeedFastest)},
+			wantEncoding: "zstd",
+		},
+		{
+			name:        "identity",
+			compression: Compression{Encoding: Identity},
+		},
+		{
+			name:        "batch below minimum size",
+			compression: Compression{Encoding: Zstd, MinSize: 1024},
+		},
+		{
+			name:         "batch above minimum size",
+			compression:  Compression{Encoding: Zstd, MinSize: 32},
+			wantEncoding: "zstd",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hf := func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "application/x-ndjson", r.Header.Get("Content-Type"))
+				assert.Equal(t, tt.wantEncoding, r.Header.Get("Content-Encoding"))
+
+				var body io.Reader = r.Body
+				switch tt.wantEncoding {
+				case "gzip":
+					gzr, err := gzip.NewReader(r.Body)
+					require.NoError(t, err)
+					defer gzr.Close()
+					body = gzr
+				case "zstd":
+					zr, err := zstd.NewReader(r.Body)
+					require.NoError(t, err)
+					defer zr.Close()
+					body = zr
+				}
+
+				b, err := io.ReadAll(body)
+				require.NoError(t, err)
+
+				lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+				if assert.Len(t, lines, 2) {
+					assert.JSONEq(t, `{"foo":"bar","number":42}`, lines[0])
+					assert.JSONEq(t, `{"foo":"baz","number":43}`, lines[1])
+				}
+
+				_, _ = fmt.Fprint(w, `{"ingested":2,"failed":0,"failures":[],"processedBytes":0,"blocksCreated":0,"walLength":2}`)
+			}
+
+			client, teardown := setup(t, "/api/v1/datasets/test/ingest", hf)
+			defer teardown()
+
+			require.NoError(t, client.Options(tt.clientOptions...))
+
+			res, err := client.Datasets.IngestEvents(context.Background(), "test", IngestOptions{
+				Compression: tt.compression,
+			}, events...)
+			require.NoError(t, err)
+
+			assert.EqualValues(t, 2, res.Ingested)
+		})
+	}
+
+	client, teardown := setup(t, "/api/v1/datasets/test/ingest", func(http.ResponseWriter, *http.Request) {
+		t.Error("no request expected")
+	})
+	defer teardown()
+
+	_, err := client.Datasets.IngestEvents(context.Background(), "test", IngestOptions{
+		Compression: Compression{Encoding: Zstd, Level: 42},
+	}, events...)
+	assert.EqualError(t, err, "invalid zstd compression level 42")
+}
+
+func TestDatasetsService_IngestStructs(t *testing.T) {
+	type request struct {
+		Path   string `axiom:"path"`
+		Status int    `axiom:"status"`
+	}
+	type event struct {
+		Time     time.Time `axiom:"ts,time"`
+		Remote   string    `axiom:"remote_ip"`
+		User     string    `axiom:"remote_user,omitempty"`
+		Request  request   `axiom:"request,flatten"`
+		Referrer *request  `axiom:"referrer,omitempty"`
+	}
+
+	exp := &IngestStatus{
+		Ingested:       2,
+		Failed:         0,
+		Failures:       []*IngestFailure{},
+		ProcessedBytes: 630,
+		BlocksCreated:  0,
+		WALLength:      2,
+	}
+
+	hf := func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/x-ndjson", r.Header.Get("Content-Type"))
+		assert.Equal(t, "gzip", r.Header.Get("Content-Encoding"))
+		assert.Equal(t, "timestamp", r.URL.Query().Get("timestamp-field"))
+
+		gzr, err := gzip.NewReader(r.Body)
+		require.NoError(t, err)
+
+		b, err := io.ReadAll(gzr)
+		require.NoError(t, err)
+		assert.NoError(t, gzr.Close())
+
+		lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+		if assert.Len(t, lines, 2) {
+			assert.JSONEq(t, `{
+				"timestamp": "2015-05-17T08:05:32Z",
+				"remote_ip": "93.180.71.3",
+				"request.path": "/downloads/product_1",
+				"request.status": 304
+			}`, lines[0])
+			assert.JSONEq(t, `{
+				"remote_ip": "93.180.71.4",
+				"remote_user": "admin",
+				"request.path": "/downloads/product_2",
+				"request.status": 200,
+				"referrer": {
+					"path": "/",
+					"status": 0
+				}
+			}`, lines[1])
+		}
+
+		_, err = fmt.Fprint(w, `{
+			"ingested": 2,
+			"failed": 0,
+			"failures": [],
+			"processedBytes": 630,
+			"blocksCreated": 0,
+			"walLength": 2
+		}`)
+		assert.NoError(t, err)
+	}
+
+	client, teardown := setup(t, "/api/v1/datasets/test/ingest", hf)
+	defer teardown()
+
+	events := []*event{
+		{
+			Time:    time.Date(2015, time.May, 17, 8, 5, 32, 0, time.UTC),
+			Remote:  "93.180.71.3",
+			Request: request{Path: "/downloads/product_1", Status: 304},
+		},
+		{
+			Remote:   "93.180.71.4",
+			User:     "admin",
+			Request:  request{Path: "/downloads/product_2", Status: 200},
+			Referrer: &request{Path: "/"},
+		},
+	}
+
+	res, err := client.Datasets.IngestStructs(context.Background(), "test", IngestOptions{
+		TimestampField: "timestamp",
+	}, events)
+	require.NoError(t, err)
+
+	assert.Equal(t, exp, res)
+
+	_, err = client.Datasets.IngestStructs(context.Background(), "test", IngestOptions{}, "foo")
+	assert.Error(t, err)
+}
+
 // TODO(lukasmalkmus): Write an ingest test that contains some failures in the
 // server response.
 
@@ -963,6 +1285,26 @@ func TestDatasetsService_Query(t *testing.T) {
 	assert.Equal(t, expQueryRes, res)
 }
 
+func TestDatasetsService_Query_Validation(t *testing.T) {
+	hf := func(w http.ResponseWriter, r *http.Request) {
+		t.Error("no request should be made for an invalid query")
+	}
+
+	client, teardown := setup(t, "/api/v1/datasets/test/query", hf)
+	defer teardown()
+
+	require.NoError(t, client.Options(SetQueryValidation(true)))
+
+	_, err := client.Datasets.Query(context.Background(), "test", query.Query{
+		StartTime: mustTimeParse(t, time.RFC3339Nano, "2020-11-26T11:18:00Z"),
+		EndTime:   mustTimeParse(t, time.RFC3339Nano, "2020-11-17T11:18:00Z"),
+	}, query.Options{})
+
+	var validationErrs query.ValidationErrors
+	require.True(t, errors.As(err, &validationErrs))
+	assert.EqualError(t, err, "invalid query: endTime: must not be before startTime")
+}
+
 func TestDatasetsService_Query_InvalidSaveKind(t *testing.T) {
 	client, teardown := setup(t, "/api/v1/datasets/test/query", nil)
 	defer teardown()
@@ -1008,6 +1350,107 @@ func TestDatasetsService_APLQuery(t *testing.T) {
 	assert.Equal(t, expAPLQueryRes, res)
 }
 
+func TestDatasetsService_APLQuery_Tabular(t *testing.T) {
+	hf := func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "tabular", r.URL.Query().Get("format"))
+
+		w.Header().Set("Content-Type", "application/json")
+		_, err := fmt.Fprint(w, `{
+			"format": "tabular",
+			"status": {
+				"elapsedTime": 1000,
+				"rowsExamined": 2,
+				"rowsMatched": 2
+			},
+			"tables": [
+				{
+					"name": "0",
+					"sources": [{ "name": "test" }],
+					"fields": [
+						{ "name": "host", "type": "string" },
+						{ "name": "count_", "type": "integer", "agg": { "name": "count" } }
+					],
+					"columns": [
+						["a", "b"],
+						[1, 2]
+					]
+				}
+			],
+			"datasetNames": ["test"]
+		}`)
+		assert.NoError(t, err)
+	}
+
+	client, teardown := setup(t, "/api/v1/datasets/_apl", hf)
+	defer teardown()
+
+	res, err := client.Datasets.APLQuery(context.Background(),
+		"['test'] | summarize count() by host", apl.Options{
+			Format: apl.Tabular,
+		})
+	require.NoError(t, err)
+
+	assert.Equal(t, apl.Tabular, res.Format)
+	assert.Equal(t, []string{"test"}, res.Datasets)
+	require.Len(t, res.Tables, 1)
+
+	var rows []struct {
+		Host  string
+		Count int `axiom:"count_"`
+	}
+	require.NoError(t, res.Tables[0].ScanRows(&rows))
+
+	if assert.Len(t, rows, 2) {
+		assert.Equal(t, "b", rows[1].Host)
+		assert.Equal(t, 2, rows[1].Count)
+	}
+}
+
+func TestDetectContentEncoding(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []byte
+		want  ContentEncoding
+	}{
+		{
+			name:  "gzip",
+			input: compress(t, Gzip, `{"a":"b"}`),
+			want:  Gzip,
+		},
+		{
+			name:  "zstd",
+			input: compress(t, Zstd, `{"a":"b"}`),
+			want:  Zstd,
+		},
+		{
+			name:  "identity",
+			input: []byte(`{"a":"b"}`),
+			want:  Identity,
+		},
+		{
+			name:  "short",
+			input: []byte("{"),
+			want:  Identity,
+		},
+		{
+			name: "empty",
+			want: Identity,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, got, err := DetectContentEncoding(bytes.NewReader(tt.input))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+
+			// The returned reader must yield the complete input.
+			b, err := io.ReadAll(r)
+			require.NoError(t, err)
+			assert.Equal(t, string(tt.input), string(b))
+		})
+	}
+}
+
 func TestDetectContentType(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -1062,6 +1505,128 @@ func TestDetectContentType(t *testing.T) {
 	}
 }
 
+func BenchmarkDatasetsService_IngestEvents(b *testing.B) {
+	hf := func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		_, _ = fmt.Fprint(w, `{"ingested":0,"failed":0,"failures":[],"processedBytes":0,"blocksCreated":0,"walLength":0}`)
+	}
+
+	client, teardown := setup(b, "/api/v1/datasets/test/ingest", hf)
+	defer teardown()
+
+	events := benchmarkEvents(1000)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := client.Datasets.IngestEvents(context.Background(), "test", IngestOptions{}, events...); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkEncodeEvents compares the event encoding path of IngestEvents with
+// the `encoding/json` based one it replaced.
+func BenchmarkEncodeEvents(b *testing.B) {
+	events := benchmarkEvents(1000)
+
+	b.Run("encoding/json", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			gzw, err := gzip.NewWriterLevel(io.Discard, gzip.BestSpeed)
+			if err != nil {
+				b.Fatal(err)
+			}
+			enc := json.NewEncoder(gzw)
+			for _, event := range events {
+				if err = enc.Encode(event); err != nil {
+					b.Fatal(err)
+				}
+			}
+			if err = gzw.Close(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	for _, comp := range []Compression{
+		{Encoding: Gzip, Level: gzip.BestSpeed},
+		{Encoding: Zstd, Level: int(zstd.SpeedFastest), Concurrency: 1},
+		{Encoding: Zstd, Level: int(zstd.SpeedDefault)},
+	} {
+		comp := comp
+		b.Run(fmt.Sprintf("writeEvents/%s/%d", comp.Encoding, comp.Level), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				cw := getCompressor(comp, io.Discard)
+				if err := writeEvents(cw, events); err != nil {
+					b.Fatal(err)
+				}
+				if err := cw.Close(); err != nil {
+					b.Fatal(err)
+				}
+				putCompressor(comp, cw)
+			}
+		})
+	}
+
+	b.Run("encoding/json/uncompressed", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			enc := json.NewEncoder(io.Discard)
+			for _, event := range events {
+				if err := enc.Encode(event); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+
+	b.Run("writeEvents/uncompressed", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if err := writeEvents(io.Discard, events); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func benchmarkEvents(n int) []Event {
+	events := make([]Event, n)
+	for i := range events {
+		events[i] = Event{
+			"_time":       time.Unix(1652344000, int64(i)).UTC(),
+			"remote_ip":   "93.180.71.3",
+			"remote_user": "-",
+			"request":     "GET /downloads/product_1 HTTP/1.1",
+			"response":    304,
+			"bytes":       int64(i),
+			"duration":    0.123 * float64(i),
+			"cached":      i%2 == 0,
+			"agent":       "Debian APT-HTTP/1.3 (0.8.16~exp12ubuntu10.21)",
+			"tags":        []string{"downloads", "apt"},
+			"geo": map[string]interface{}{
+				"country": "DE",
+				"city":    "Berlin",
+			},
+		}
+	}
+	return events
+}
+
+func compress(t *testing.T, enc ContentEncoding, s string) []byte {
+	t.Helper()
+
+	r, err := Compression{Encoding: enc}.Encoder()(strings.NewReader(s))
+	require.NoError(t, err)
+
+	b, err := io.ReadAll(r)
+	require.NoError(t, err)
+
+	return b
+}
+
 func assertValidJSON(t *testing.T, r io.Reader) bool {
 	dec := json.NewDecoder(r)
 	for dec.More() {
@@ -3,21 +3,20 @@ package axiom
 import (
 	"compress/gzip"
 	"io"
-
-	"github.com/klauspost/compress/zstd"
 )
 
 // ContentEncoder is a function that wraps a given `io.Reader` with encoding
 // functionality and returns that enhanced reader. The content type of the
 // encoded content must obviously be accepted by the server.
 //
-// See `GzipEncoder` and `ZstdEncoder` for implementation reference.
+// See `GzipEncoder` and `ZstdEncoder` for implementation reference and
+// `Compression.Encoder()` for a configurable one.
 type ContentEncoder func(io.Reader) (io.Reader, error)
 
 // GzipEncoder is a `ContentEncoder` that gzip compresses the data it reads
 // from the provided reader. The compression level defaults to `gzip.BestSpeed`.
 func GzipEncoder(r io.Reader) (io.Reader, error) {
-	return GzipEncoderWithLevel(gzip.BestSpeed)(r)
+	return Compression{Encoding: Gzip}.Encoder()(r)
 }
 
 // GzipEncoderWithLevel returns a `ContentEncoder` that gzip compresses data
@@ -48,22 +47,5 @@ func GzipEncoderWithLevel(level int) ContentEncoder {
 // ZstdEncoder is a `ContentEncoder` that zstd compresses the data it reads
 // from the provided reader.
 func ZstdEncoder(r io.Reader) (io.Reader, error) {
-	pr, pw := io.Pipe()
-
-	zw, err := zstd.NewWriter(pw)
-	if err != nil {
-		return nil, err
-	}
-
-	go func() {
-		_, err := io.Copy(zw, r)
-		if closeErr := zw.Close(); err == nil {
-			// If we have no error from copying but from closing, capture that
-			// one.
-			err = closeErr
-		}
-		_ = pw.CloseWithError(err)
-	}()
-
-	return pr, nil
+	return Compression{Encoding: Zstd}.Encoder()(r)
 }
@@ -55,3 +55,27 @@ type Error struct {
 func (e Error) Error() string {
 	return fmt.Sprintf("API error %d: %s", e.Status, e.Message)
 }
+
+// IsTemporary returns true if the given ingestion error is likely to go away
+// when retrying the ingestion at a later point in time, e.g. a network error, an
+// exceeded rate limit or a server error. Data that failed to ingest because of
+// a temporary error is worth keeping, e.g. in a Spool, while data that was
+// rejected permanently (e.g. because of invalid credentials or a bad request)
+// is not.
+func IsTemporary(err error) bool {
+	if errors.Is(err, ErrUnauthenticated) || errors.Is(err, ErrUnauthorized) ||
+		errors.Is(err, ErrNotFound) || errors.Is(err, ErrUnprivilegedToken) {
+		return false
+	} else if errors.Is(err, ErrRateLimitExceeded) {
+		// A LimitError also matches an Error with a 429 status code, which
+		// would otherwise be considered permanent.
+		return true
+	}
+
+	var apiErr Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Status >= 500
+	}
+
+	return true
+}
@@ -0,0 +1,28 @@
+package axiom
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsTemporary(t *testing.T) {
+	tests := []struct {
+		err error
+		exp bool
+	}{
+		{ErrUnauthenticated, false},
+		{ErrNotFound, false},
+		{Error{Status: http.StatusBadRequest}, false},
+		{Error{Status: http.StatusServiceUnavailable}, true},
+		{LimitError{}, true},
+		{context.DeadlineExceeded, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			assert.Equal(t, tt.exp, IsTemporary(tt.err))
+		})
+	}
+}
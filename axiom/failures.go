@@ -0,0 +1,436 @@
+package axiom
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// FailedEvents returns the events of the given batch that failed to ingest, in
+// the order they are reported by the server. The batch must be the one that
+// was passed to `DatasetsService.IngestEvents()` and resulted in the status.
+// Failures that couldn't be correlated with an event are skipped. Use this to
+// route rejected events to a dead-letter dataset.
+func (s *IngestStatus) FailedEvents(events []Event) []Event {
+	var failed []Event
+	for _, failure := range s.Failures {
+		if failure.Index >= 0 && failure.Index < len(events) {
+			failed = append(failed, events[failure.Index])
+		}
+	}
+	return failed
+}
+
+// correlateFailures sets the index and line of the given failures by matching
+// their timestamps against the ones of the n events of the batch they have
+// been reported for. Events with the same timestamp are matched in order.
+// Failures that can't be matched have an index of -1 and a line of 0.
+func correlateFailures(failures []*IngestFailure, n int, event func(i int) (ts time.Time, line int, ok bool)) {
+	for _, failure := range failures {
+		failure.Index, failure.Line = -1, 0
+	}
+	if len(failures) == 0 || n == 0 {
+		return
+	}
+
+	// Index the events by their timestamp. Only do so for timestamps that
+	// actually failed to keep memory usage low.
+	candidates := make(map[int64][]int, len(failures))
+	for _, failure := range failures {
+		candidates[failure.Timestamp.UnixNano()] = nil
+	}
+	for i := 0; i < n; i++ {
+		ts, _, ok := event(i)
+		if !ok {
+			continue
+		}
+		key := ts.UnixNano()
+		if indices, ok := candidates[key]; ok {
+			candidates[key] = append(indices, i)
+		}
+	}
+
+	for _, failure := range failures {
+		key := failure.Timestamp.UnixNano()
+		if indices := candidates[key]; len(indices) > 0 {
+			failure.Index = indices[0]
+			_, failure.Line, _ = event(indices[0])
+			candidates[key] = indices[1:]
+		}
+	}
+}
+
+// parseTimestamp returns the time represented by the given value of a
+// timestamp field. Strings are parsed using the given layout or RFC 3339, if
+// none is given.
+func parseTimestamp(v interface{}, layout string) (time.Time, bool) {
+	switch v := v.(type) {
+	case time.Time:
+		return v, true
+	case *time.Time:
+		if v != nil {
+			return *v, true
+		}
+	case string:
+		if layout == "" {
+			layout = time.RFC3339Nano
+		}
+		if ts, err := time.Parse(layout, v); err == nil {
+			return ts, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// trackedRecord is a record seen by a recordTracker.
+type trackedRecord struct {
+	ts   time.Time
+	line int
+	ok   bool
+}
+
+// recordTracker keeps track of the timestamps and line numbers of the records
+// of newline delimited JSON or CSV data written to it. It allows to correlate
+// ingestion failures with the records of data that is sent as is.
+type recordTracker struct {
+	typ            ContentType
+	timestampField string
+	timestampFmt   string
+	csvComma       rune
+
+	mu       sync.Mutex
+	records  []trackedRecord
+	partial  []byte
+	quoted   bool
+	lines    int
+	start    int
+	header   bool
+	tsColumn int
+}
+
+// newRecordTracker returns a tracker for data of the given content type
+// ingested with the given options. It returns nil, if records of the content
+// type can't be tracked.
+func newRecordTracker(typ ContentType, opts IngestOptions) *recordTracker {
+	if typ != NDJSON && typ != CSV {
+		return nil
+	}
+
+	t := &recordTracker{
+		typ:            typ,
+		timestampField: opts.TimestampField,
+		timestampFmt:   opts.TimestampFormat,
+		csvComma:       ',',
+		tsColumn:       -1,
+	}
+	if t.timestampField == "" {
+		t.timestampField = TimestampField
+	}
+	if r, _ := utf8.DecodeRuneInString(opts.CSVDelimiter); r != utf8.RuneError {
+		t.csvComma = r
+	}
+
+	return t
+}
+
+// Write implements `io.Writer`. It never fails.
+func (t *recordTracker) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	n := len(p)
+	for len(p) > 0 {
+		if len(t.partial) == 0 {
+			t.start = t.lines + 1
+		}
+
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			t.updateQuoted(p)
+			t.partial = append(t.partial, p...)
+			break
+		}
+		t.lines++
+
+		line := p[:i+1]
+		p = p[i+1:]
+
+		// Only copy the line if the record is continued on the next line or
+		// started on a previous one.
+		if t.updateQuoted(line); t.quoted {
+			t.partial = append(t.partial, line...)
+			continue
+		} else if len(t.partial) > 0 {
+			t.partial = append(t.partial, line...)
+			line = t.partial
+		}
+
+		t.record(line)
+		t.partial = t.partial[:0]
+	}
+
+	return n, nil
+}
+
+// updateQuoted updates the state of quoted CSV fields with the given data of
+// the current record. Escaped quotes within a quoted field come in pairs, so
+// they don't change the state.
+func (t *recordTracker) updateQuoted(p []byte) {
+	if t.typ == CSV {
+		t.quoted = t.quoted != (bytes.Count(p, []byte{'"'})%2 == 1)
+	}
+}
+
+// flush records a final record that lacks a line break.
+func (t *recordTracker) flush() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.partial) > 0 {
+		t.record(t.partial)
+		t.partial = t.partial[:0]
+		t.quoted = false
+	}
+}
+
+// reset clears all records so the data can be written to the tracker again.
+func (t *recordTracker) reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.records = t.records[:0]
+	t.partial = t.partial[:0]
+	t.quoted = false
+	t.lines, t.start = 0, 0
+	t.header, t.tsColumn = false, -1
+}
+
+// record records the given record that starts on the current start line.
+// Blank records are ignored, just like the server does.
+func (t *recordTracker) record(record []byte) {
+	if len(bytes.TrimSpace(record)) == 0 {
+		return
+	}
+
+	rec := trackedRecord{line: t.start}
+	switch t.typ {
+	case NDJSON:
+		if v, ok := jsonField(record, t.timestampField); ok {
+			rec.ts, rec.ok = parseJSONTimestamp(v, t.timestampFmt)
+		}
+	case CSV:
+		fields := t.csvFields(record)
+		if !t.header {
+			t.header = true
+			for i, name := range fields {
+				if name == t.timestampField {
+					t.tsColumn = i
+					break
+				}
+			}
+			return
+		}
+		if t.tsColumn >= 0 && t.tsColumn < len(fields) {
+			rec.ts, rec.ok = parseTimestamp(fields[t.tsColumn], t.timestampFmt)
+		}
+	}
+
+	t.records = append(t.records, rec)
+}
+
+// csvFields returns the fields of the given CSV record. Records are only
+// parsed as long as a timestamp column is known.
+func (t *recordTracker) csvFields(record []byte) []string {
+	if t.header && t.tsColumn < 0 {
+		return nil
+	}
+
+	r := csv.NewReader(bytes.NewReader(record))
+	r.Comma = t.csvComma
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+
+	fields, _ := r.Read()
+	return fields
+}
+
+// correlate correlates the given failures with the records seen by the
+// tracker.
+func (t *recordTracker) correlate(failures []*IngestFailure) {
+	t.flush()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	correlateFailures(failures, len(t.records), func(i int) (time.Time, int, bool) {
+		return t.records[i].ts, t.records[i].line, t.records[i].ok
+	})
+}
+
+// track makes the tracker see the body of the given request as it is sent.
+// If the body is replayed, e.g. for a retry, the tracker starts over.
+func (t *recordTracker) track(req *http.Request) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return
+	}
+
+	req.Body = trackedBody{Reader: io.TeeReader(req.Body, t), Closer: req.Body}
+	if getBody := req.GetBody; getBody != nil {
+		req.GetBody = func() (io.ReadCloser, error) {
+			body, err := getBody()
+			if err != nil {
+				return nil, err
+			}
+			t.reset()
+			return trackedBody{Reader: io.TeeReader(body, t), Closer: body}, nil
+		}
+	}
+}
+
+type trackedBody struct {
+	io.Reader
+	io.Closer
+}
+
+// jsonField returns the raw value of the top-level field with the given name
+// of the given JSON object. Only as much of the object is scanned as necessary
+// to find the field and no validation beyond that is done.
+func jsonField(data []byte, name string) ([]byte, bool) {
+	i := skipJSONSpace(data, 0)
+	if i >= len(data) || data[i] != '{' {
+		return nil, false
+	}
+	i++
+
+	for {
+		i = skipJSONSpace(data, i)
+		if i >= len(data) || data[i] != '"' {
+			return nil, false
+		}
+		keyEnd, ok := skipJSONString(data, i)
+		if !ok {
+			return nil, false
+		}
+		key := data[i:keyEnd]
+
+		i = skipJSONSpace(data, keyEnd)
+		if i >= len(data) || data[i] != ':' {
+			return nil, false
+		}
+		i = skipJSONSpace(data, i+1)
+
+		valueEnd, ok := skipJSONValue(data, i)
+		if !ok {
+			return nil, false
+		}
+		if s, ok := unquoteJSONString(key); ok && s == name {
+			return data[i:valueEnd], true
+		}
+
+		i = skipJSONSpace(data, valueEnd)
+		if i >= len(data) || data[i] != ',' {
+			return nil, false
+		}
+		i++
+	}
+}
+
+// parseJSONTimestamp returns the time represented by the given raw JSON value.
+// Only strings are considered.
+func parseJSONTimestamp(value []byte, layout string) (time.Time, bool) {
+	if len(value) == 0 || value[0] != '"' {
+		return time.Time{}, false
+	}
+	s, ok := unquoteJSONString(value)
+	if !ok {
+		return time.Time{}, false
+	}
+	return parseTimestamp(s, layout)
+}
+
+// unquoteJSONString returns the value of the given quoted JSON string.
+func unquoteJSONString(quoted []byte) (string, bool) {
+	if bytes.IndexByte(quoted, '\\') < 0 {
+		return string(quoted[1 : len(quoted)-1]), true
+	}
+	var s string
+	if err := json.Unmarshal(quoted, &s); err != nil {
+		return "", false
+	}
+	return s, true
+}
+
+func skipJSONSpace(data []byte, i int) int {
+	for i < len(data) {
+		switch data[i] {
+		case ' ', '\t', '\r', '\n':
+			i++
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// skipJSONString returns the position after the JSON string that starts at
+// the given position.
+func skipJSONString(data []byte, i int) (int, bool) {
+	for i++; i < len(data); i++ {
+		switch data[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1, true
+		}
+	}
+	return 0, false
+}
+
+// skipJSONValue returns the position after the JSON value that starts at the
+// given position.
+func skipJSONValue(data []byte, i int) (int, bool) {
+	if i >= len(data) {
+		return 0, false
+	}
+
+	switch data[i] {
+	case '"':
+		return skipJSONString(data, i)
+	case '{', '[':
+		var depth int
+		for i < len(data) {
+			switch data[i] {
+			case '"':
+				var ok bool
+				if i, ok = skipJSONString(data, i); !ok {
+					return 0, false
+				}
+				continue
+			case '{', '[':
+				depth++
+			case '}', ']':
+				if depth--; depth == 0 {
+					return i + 1, true
+				}
+			}
+			i++
+		}
+		return 0, false
+	}
+
+	// Numbers and literals.
+	start := i
+	for i < len(data) {
+		switch data[i] {
+		case ',', '}', ']', ' ', '\t', '\r', '\n':
+			return i, i > start
+		}
+		i++
+	}
+	return i, i > start
+}
@@ -0,0 +1,332 @@
+package axiom
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingHandler returns a handler that reports the events with the given
+// timestamps as failed.
+func failingHandler(t *testing.T, timestamps ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, err := io.Copy(io.Discard, r.Body)
+		require.NoError(t, err)
+
+		failures := make([]string, len(timestamps))
+		for i, ts := range timestamps {
+			failures[i] = fmt.Sprintf(`{"timestamp":%q,"error":"failed"}`, ts)
+		}
+
+		_, _ = fmt.Fprintf(w, `{"ingested":0,"failed":%d,"failures":[%s],"processedBytes":0,"blocksCreated":0,"walLength":0}`,
+			len(failures), strings.Join(failures, ","))
+	}
+}
+
+func failureIndices(failures []*IngestFailure) (indices, lines []int) {
+	for _, failure := range failures {
+		indices = append(indices, failure.Index)
+		lines = append(lines, failure.Line)
+	}
+	return indices, lines
+}
+
+func TestDatasetsService_IngestEvents_Failures(t *testing.T) {
+	ts := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	events := []Event{
+		{"_time": ts, "n": 0},
+		{"_time": ts.Add(time.Second).Format(time.RFC3339), "n": 1},
+		{"_time": ts, "n": 2},
+		{"n": 3},
+		{"_time": ts.Add(2 * time.Second), "n": 4},
+	}
+
+	client, teardown := setup(t, "/api/v1/datasets/test/ingest", failingHandler(t,
+		"2022-01-01T00:00:01Z",
+		"2022-01-01T00:00:00Z",
+		"2022-01-01T00:00:00Z",
+		"2022-01-01T00:00:00Z",
+		"2022-01-01T00:10:00Z",
+	))
+	defer teardown()
+
+	res, err := client.Datasets.IngestEvents(context.Background(), "test", IngestOptions{}, events...)
+	require.NoError(t, err)
+
+	indices, lines := failureIndices(res.Failures)
+	assert.Equal(t, []int{1, 0, 2, -1, -1}, indices)
+	assert.Equal(t, []int{2, 1, 3, 0, 0}, lines)
+
+	assert.Equal(t, []Event{events[1], events[0], events[2]}, res.FailedEvents(events))
+}
+
+func TestDatasetsService_IngestStructs_Failures(t *testing.T) {
+	type event struct {
+		Time *time.Time `axiom:"ts,time"`
+		N    int        `axiom:"n"`
+	}
+
+	ts := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	client, teardown := setup(t, "/api/v1/datasets/test/ingest", failingHandler(t, "2022-01-01T00:00:00Z"))
+	defer teardown()
+
+	res, err := client.Datasets.IngestStructs(context.Background(), "test", IngestOptions{}, []event{
+		{N: 0},
+		{Time: &ts, N: 1},
+	})
+	require.NoError(t, err)
+
+	indices, lines := failureIndices(res.Failures)
+	assert.Equal(t, []int{1}, indices)
+	assert.Equal(t, []int{2}, lines)
+}
+
+func TestDatasetsService_Ingest_Failures(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		typ         ContentType
+		enc         ContentEncoding
+		opts        IngestOptions
+		wantIndices []int
+		wantLines   []int
+	}{
+		{
+			name: "ndjson",
+			input: `{"_time":"2022-01-01T00:00:00Z"}
+
+{"nested":{"_time":"2022-01-01T00:00:02Z"},"_time":"2022-01-01T00:00:01Z"}
+{"_time":"2022-01-01T00:00:02Z"}`,
+			typ:         NDJSON,
+			enc:         Identity,
+			wantIndices: []int{2, 1},
+			wantLines:   []int{4, 3},
+		},
+		{
+			name:  "ndjson custom timestamp",
+			input: `{"ts":"01.01.2022 00:00:02"}` + "\n" + `{"ts":"01.01.2022 00:00:01"}` + "\n",
+			typ:   NDJSON,
+			enc:   Identity,
+			opts: IngestOptions{
+				TimestampField:  "ts",
+				TimestampFormat: "02.01.2006 15:04:05",
+			},
+			wantIndices: []int{0, 1},
+			wantLines:   []int{1, 2},
+		},
+		{
+			name: "csv",
+			input: `msg;_time
+"multi
+line";2022-01-01T00:00:02Z
+plain;2022-01-01T00:00:01Z
+`,
+			typ:         CSV,
+			enc:         Identity,
+			opts:        IngestOptions{CSVDelimiter: ";"},
+			wantIndices: []int{0, 1},
+			wantLines:   []int{2, 4},
+		},
+		{
+			name:        "json",
+			input:       `[{"_time":"2022-01-01T00:00:01Z"}]`,
+			typ:         JSON,
+			enc:         Identity,
+			wantIndices: []int{-1, -1},
+			wantLines:   []int{0, 0},
+		},
+		{
+			name:        "compressed",
+			input:       string(compress(t, Gzip, `{"_time":"2022-01-01T00:00:02Z"}`)),
+			typ:         NDJSON,
+			enc:         Gzip,
+			wantIndices: []int{-1, -1},
+			wantLines:   []int{0, 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, teardown := setup(t, "/api/v1/datasets/test/ingest", failingHandler(t,
+				"2022-01-01T00:00:02Z",
+				"2022-01-01T00:00:01Z",
+			))
+			defer teardown()
+
+			res, err := client.Datasets.Ingest(context.Background(), "test", strings.NewReader(tt.input), tt.typ, tt.enc, tt.opts)
+			require.NoError(t, err)
+
+			indices, lines := failureIndices(res.Failures)
+			assert.Equal(t, tt.wantIndices, indices)
+			assert.Equal(t, tt.wantLines, lines)
+		})
+	}
+}
+
+func TestDatasetsService_IngestReader_Failures(t *testing.T) {
+	client, teardown := setup(t, "/api/v1/datasets/test/ingest", failingHandler(t, "2022-01-01T00:00:01Z"))
+	defer teardown()
+
+	input := `{"_time":"2022-01-01T00:00:00Z"}` + "\n" + `{"_time":"2022-01-01T00:00:01Z"}` + "\n"
+
+	res, err := client.Datasets.IngestReader(context.Background(), "test", strings.NewReader(input), IngestOptions{})
+	require.NoError(t, err)
+
+	indices, lines := failureIndices(res.Failures)
+	assert.Equal(t, []int{1}, indices)
+	assert.Equal(t, []int{2}, lines)
+}
+
+func TestDatasetsService_IngestChunked_Failures(t *testing.T) {
+	client, teardown := setup(t, "/api/v1/datasets/test/ingest", func(w http.ResponseWriter, r *http.Request) {
+		cr := &chunkRecorder{t: t}
+		cr.ServeHTTP(w, r)
+	})
+	defer teardown()
+
+	// The chunk recorder reports failures with a fixed timestamp.
+	input := `{"_time":"2022-01-01T00:00:01Z"}
+
+{"_time":"2022-01-01T00:00:00Z","fail":true}
+{"_time":"2022-01-01T00:00:02Z"}
+{"_time":"2022-01-01T00:00:00Z","fail":true}
+`
+
+	res, err := client.Datasets.IngestChunked(context.Background(), "test", strings.NewReader(input), NDJSON, IngestOptions{},
+		SetChunkMaxEvents(2),
+		SetChunkConcurrency(1),
+	)
+	require.NoError(t, err)
+
+	indices, lines := failureIndices(res.Failures)
+	assert.Equal(t, []int{1, 3}, indices)
+	assert.Equal(t, []int{3, 5}, lines)
+}
+
+func TestCorrelateFailures(t *testing.T) {
+	ts := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	failures := []*IngestFailure{
+		{Timestamp: ts},
+		{Timestamp: ts.Add(time.Minute), Index: 5, Line: 5},
+		{Timestamp: ts},
+		{Timestamp: ts},
+	}
+
+	timestamps := []time.Time{ts.Add(time.Second), ts, {}, ts.In(time.FixedZone("CET", 3600))}
+	correlateFailures(failures, len(timestamps), func(i int) (time.Time, int, bool) {
+		return timestamps[i], 10 + i, !timestamps[i].IsZero()
+	})
+
+	indices, lines := failureIndices(failures)
+	assert.Equal(t, []int{1, -1, 3, -1}, indices)
+	assert.Equal(t, []int{11, 0, 13, 0}, lines)
+}
+
+func TestRecordTracker(t *testing.T) {
+	tracker := newRecordTracker(CSV, IngestOptions{TimestampField: "ts"})
+	require.NotNil(t, tracker)
+
+	input := "ts,msg\n2022-01-01T00:00:00Z,\"a \"\"quoted\"\"\nmulti line\"\n\n  \ninvalid,b\n2022-01-01T00:00:01Z,c"
+
+	// Write in tiny pieces to make sure records spanning writes are handled.
+	for i := 0; i < len(input); i += 3 {
+		end := i + 3
+		if end > len(input) {
+			end = len(input)
+		}
+		_, err := tracker.Write([]byte(input[i:end]))
+		require.NoError(t, err)
+	}
+	tracker.flush()
+
+	assert.Equal(t, []trackedRecord{
+		{ts: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC), line: 2, ok: true},
+		{line: 6},
+		{ts: time.Date(2022, 1, 1, 0, 0, 1, 0, time.UTC), line: 7, ok: true},
+	}, tracker.records)
+
+	tracker.reset()
+	assert.Empty(t, tracker.records)
+
+	assert.Nil(t, newRecordTracker(JSON, IngestOptions{}))
+}
+
+func TestJSONField(t *testing.T) {
+	tests := []struct {
+		input  string
+		name   string
+		want   string
+		wantOK bool
+	}{
+		{`{"a":1,"b":"x"}`, "b", `"x"`, true},
+		{` { "a" : [1,{"b":"]"}] , "b" : true } `, "b", `true`, true},
+		{`{"a":{"b":1},"b":{"c":"}"}}`, "b", `{"c":"}"}`, true},
+		{`{"a\"b":"x","ab":"y"}`, "ab", `"y"`, true},
+		{`{"a":"x\"y","b":null}`, "b", `null`, true},
+		{`{"a":1}`, "b", "", false},
+		{`{}`, "a", "", false},
+		{`[{"a":1}]`, "a", "", false},
+		{`{"a":"unterminated}`, "a", "", false},
+		{`{"a":}`, "a", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			v, ok := jsonField([]byte(tt.input), tt.name)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, string(v))
+		})
+	}
+}
+
+func TestIngestStatus_FailedEvents(t *testing.T) {
+	events := []Event{{"n": 0}, {"n": 1}}
+
+	status := &IngestStatus{
+		Failures: []*IngestFailure{
+			{Index: 1},
+			{Index: -1},
+			{Index: 2},
+		},
+	}
+
+	assert.Equal(t, []Event{events[1]}, status.FailedEvents(events))
+	assert.Empty(t, (&IngestStatus{}).FailedEvents(events))
+}
+
+func TestDatasetsService_Ingest_FailuresRetried(t *testing.T) {
+	var attempts int
+	hf := failingHandler(t, "2022-01-01T00:00:01Z")
+
+	client, teardown := setup(t, "/api/v1/datasets/test/ingest", func(w http.ResponseWriter, r *http.Request) {
+		if attempts++; attempts == 1 {
+			// Only consume part of the body on the first attempt.
+			_, _ = io.ReadFull(r.Body, make([]byte, 10))
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		hf(w, r)
+	})
+	defer teardown()
+
+	require.NoError(t, client.Options(SetRetryPolicy(testRetryPolicy)))
+
+	input := `{"_time":"2022-01-01T00:00:00Z"}` + "\n" + `{"_time":"2022-01-01T00:00:01Z"}` + "\n"
+
+	res, err := client.Datasets.Ingest(context.Background(), "test", strings.NewReader(input), NDJSON, Identity, IngestOptions{})
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, attempts)
+
+	indices, lines := failureIndices(res.Failures)
+	assert.Equal(t, []int{1}, indices)
+	assert.Equal(t, []int{2}, lines)
+}
@@ -0,0 +1,483 @@
+package axiom
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+//go:generate go run -mod=mod golang.org/x/tools/cmd/stringer -type=OverflowPolicy -linecomment -output=ingester_string.go
+
+const (
+	defaultBatchSize     = 1024
+	defaultFlushInterval = time.Second
+	defaultQueueBatches  = 4
+)
+
+// ErrIngesterClosed is returned when events are passed to an Ingester that
+// has been closed.
+var ErrIngesterClosed = errors.New("ingester closed")
+
+// OverflowPolicy describes how an Ingester behaves when its queue is full.
+type OverflowPolicy uint8
+
+// All available overflow policies.
+const (
+	// OverflowBlock blocks the caller until there is room in the queue.
+	OverflowBlock OverflowPolicy = iota // block
+	// OverflowDropOldest drops the oldest queued event to make room for the
+	// new one.
+	OverflowDropOldest // drop-oldest
+	// OverflowDropNewest drops the new event.
+	OverflowDropNewest // drop-newest
+)
+
+// IngesterErrorHandler is called by an Ingester when a batch of events failed
+// to ingest, either completely (err is not nil and status is nil) or partially
+// (status carries the failures). It is passed the affected batch which is nil
+// for failures of batches replayed from a spool. Spooled batches rejected with a
+// permanent error are reported by a QuarantineError.
+type IngesterErrorHandler func(err error, status *IngestStatus, events []Event)
+
+// An IngesterOption modifies the behaviour of an Ingester.
+type IngesterOption func(*Ingester) error
+
+// SetBatchSize specifies the maximum number of events sent with a single
+// request. Defaults to 1024.
+func SetBatchSize(n int) IngesterOption {
+	return func(i *Ingester) error {
+		if n <= 0 {
+			return errors.New("batch size must be greater than zero")
+		}
+		i.batchSize = n
+		return nil
+	}
+}
+
+// SetMaxBatchBytes specifies the maximum number of uncompressed bytes sent with
+// a single request. A batch is flushed as soon as it reaches that size. This
+// requires every event to be JSON encoded an additional time in order to
+// determine its size. Disabled by default.
+func SetMaxBatchBytes(n int) IngesterOption {
+	return func(i *Ingester) error {
+		i.maxBatchBytes = n
+		return nil
+	}
+}
+
+// SetFlushInterval specifies the interval at which queued events are sent,
+// even if they don't form a full batch. Defaults to one second.
+func SetFlushInterval(d time.Duration) IngesterOption {
+	return func(i *Ingester) error {
+		if d <= 0 {
+			return errors.New("flush interval must be greater than zero")
+		}
+		i.flushInterval = d
+		return nil
+	}
+}
+
+// SetQueueSize specifies the maximum number of events held in memory while
+// waiting to be sent. It is raised to the batch size, if lower. Defaults to
+// four times the batch size.
+func SetQueueSize(n int) IngesterOption {
+	return func(i *Ingester) error {
+		i.queueSize = n
+		return nil
+	}
+}
+
+// SetOverflowPolicy specifies how the Ingester behaves when its queue is full.
+// Defaults to `OverflowBlock`.
+func SetOverflowPolicy(policy OverflowPolicy) IngesterOption {
+	return func(i *Ingester) error {
+		i.overflowPolicy = policy
+		return nil
+	}
+}
+
+// SetDropHandler specifies a function that is called with every event dropped
+// because the queue was full, e.g. to write it to a fallback destination. It is
+// called by the caller of `Ingest()` and must not block.
+func SetDropHandler(handler func(event Event)) IngesterOption {
+	return func(i *Ingester) error {
+		i.dropHandler = handler
+		return nil
+	}
+}
+
+// SetErrorHandler specifies the function that is called when a batch of events
+// failed to ingest.
+func SetErrorHandler(handler IngesterErrorHandler) IngesterOption {
+	return func(i *Ingester) error {
+		i.errorHandler = handler
+		return nil
+	}
+}
+
+// SetIngestOptions specifies the ingestion options to use for ingesting the
+// events.
+func SetIngestOptions(opts IngestOptions) IngesterOption {
+	return func(i *Ingester) error {
+		i.ingestOptions = opts
+		return nil
+	}
+}
+
+// SetSpool specifies a spool that batches which failed to ingest because of a
+// temporary error (e.g. a network error or an unavailable server) are written
+// to. Spooled batches are replayed in the order they were written at the flush
+// interval once all queued events have been sent successfully. Batches that
+// were spooled successfully are not reported to the error handler.
+func SetSpool(spool *Spool) IngesterOption {
+	return func(i *Ingester) error {
+		i.spool = spool
+		return nil
+	}
+}
+
+type queuedEvent struct {
+	event Event
+	size  int
+}
+
+// Ingester ingests events into a dataset in the background. It batches events
+// passed to it by concurrent callers and sends them when a batch is full or at
+// a regular interval, whatever comes first.
+//
+// An Ingester needs to be closed properly to make sure all events are sent by
+// calling `Close()`.
+type Ingester struct {
+	client    *Client
+	datasetID string
+
+	ingestOptions  IngestOptions
+	batchSize      int
+	maxBatchBytes  int
+	flushInterval  time.Duration
+	queueSize      int
+	overflowPolicy OverflowPolicy
+	dropHandler    func(event Event)
+	errorHandler   IngesterErrorHandler
+	spool          *Spool
+
+	queue      []queuedEvent
+	queueBytes int
+	spaceCh    chan struct{}
+	closed     bool
+	mtx        sync.Mutex
+
+	dropped uint64
+
+	wakeCh    chan struct{}
+	flushCh   chan chan struct{}
+	closeCh   chan struct{}
+	doneCh    chan struct{}
+	closeOnce sync.Once
+	ctx       context.Context
+	cancel    context.CancelFunc
+}
+
+// NewIngester creates a new Ingester which ingests events into the dataset
+// identified by its id using the given client. Options can be supplied to
+// configure the Ingester.
+func NewIngester(client *Client, datasetID string, options ...IngesterOption) (*Ingester, error) {
+	if client == nil {
+		return nil, errors.New("client must not be nil")
+	}
+
+	i := &Ingester{
+		client:    client,
+		datasetID: datasetID,
+
+		batchSize:     defaultBatchSize,
+		flushInterval: defaultFlushInterval,
+
+		spaceCh: make(chan struct{}),
+		wakeCh:  make(chan struct{}, 1),
+		flushCh: make(chan chan struct{}),
+		closeCh: make(chan struct{}),
+		doneCh:  make(chan struct{}),
+	}
+
+	// Apply supplied options.
+	for _, option := range options {
+		if err := option(i); err != nil {
+			return nil, err
+		}
+	}
+
+	if i.queueSize <= 0 {
+		i.queueSize = defaultQueueBatches * i.batchSize
+	} else if i.queueSize < i.batchSize {
+		i.queueSize = i.batchSize
+	}
+
+	// Run background scheduler.
+	i.ctx, i.cancel = context.WithCancel(context.Background())
+	go i.run()
+
+	return i, nil
+}
+
+// Ingest queues the given events for ingestion. Depending on the overflow
+// policy, it blocks until there is room in the queue or the context is done or
+// drops events when the queue is full.
+func (i *Ingester) Ingest(ctx context.Context, events ...Event) error {
+	for _, event := range events {
+		if err := i.enqueue(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Dropped returns the number of events dropped because the queue was full.
+func (i *Ingester) Dropped() uint64 {
+	return atomic.LoadUint64(&i.dropped)
+}
+
+// Flush sends all queued events and waits until they have been sent or the
+// context is done. Failures are reported to the error handler.
+func (i *Ingester) Flush(ctx context.Context) error {
+	done := make(chan struct{})
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-i.doneCh:
+		return ErrIngesterClosed
+	case i.flushCh <- done:
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-done:
+		return nil
+	}
+}
+
+// Close the Ingester and make sure all queued events are sent. If the context
+// is done before all events have been sent, in-flight requests are canceled
+// and remaining events are discarded. Closing the Ingester renders it unusable
+// for further use.
+func (i *Ingester) Close(ctx context.Context) error {
+	i.closeOnce.Do(func() {
+		i.mtx.Lock()
+		i.closed = true
+		i.mtx.Unlock()
+
+		close(i.closeCh)
+	})
+
+	select {
+	case <-i.doneCh:
+		return nil
+	case <-ctx.Done():
+		i.cancel()
+		<-i.doneCh
+		return ctx.Err()
+	}
+}
+
+func (i *Ingester) enqueue(ctx context.Context, event Event) error {
+	var size int
+	if i.maxBatchBytes > 0 {
+		b, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		size = len(b) + 1 // Account for the newline.
+	}
+
+	var dropped []Event
+	defer func() {
+		if i.dropHandler != nil {
+			for _, event := range dropped {
+				i.dropHandler(event)
+			}
+		}
+	}()
+
+	i.mtx.Lock()
+	for !i.closed && len(i.queue) >= i.queueSize {
+		switch i.overflowPolicy {
+		case OverflowDropOldest:
+			dropped = append(dropped, i.queue[0].event)
+			i.queueBytes -= i.queue[0].size
+			i.queue[0] = queuedEvent{}
+			i.queue = i.queue[1:]
+			atomic.AddUint64(&i.dropped, 1)
+		case OverflowDropNewest:
+			i.mtx.Unlock()
+			dropped = append(dropped, event)
+			atomic.AddUint64(&i.dropped, 1)
+			return nil
+		default:
+			spaceCh := i.spaceCh
+			i.mtx.Unlock()
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-i.closeCh:
+				return ErrIngesterClosed
+			case <-spaceCh:
+			}
+
+			i.mtx.Lock()
+		}
+	}
+
+	if i.closed {
+		i.mtx.Unlock()
+		return ErrIngesterClosed
+	}
+
+	i.queue = append(i.queue, queuedEvent{event: event, size: size})
+	i.queueBytes += size
+	full := i.batchReady()
+	i.mtx.Unlock()
+
+	if full {
+		select {
+		case i.wakeCh <- struct{}{}:
+		default:
+		}
+	}
+
+	return nil
+}
+
+// batchReady returns true if the queued events form at least one full batch.
+// The lock must be held by the caller.
+func (i *Ingester) batchReady() bool {
+	return len(i.queue) >= i.batchSize ||
+		(i.maxBatchBytes > 0 && i.queueBytes >= i.maxBatchBytes)
+}
+
+func (i *Ingester) run() {
+	defer close(i.doneCh)
+
+	t := time.NewTicker(i.flushInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-i.closeCh:
+			if i.flush(true) {
+				i.replay()
+			}
+			return
+		case done := <-i.flushCh:
+			i.flush(true)
+			close(done)
+		case <-t.C:
+			if i.flush(true) {
+				i.replay()
+			}
+		case <-i.wakeCh:
+			i.flush(false)
+		}
+	}
+}
+
+// flush sends all queued events or only full batches, if all is false. It
+// returns false if any batch failed to ingest.
+func (i *Ingester) flush(all bool) bool {
+	ok := true
+	for {
+		batch := i.nextBatch(all)
+		if len(batch) == 0 {
+			return ok
+		}
+		ok = i.ingest(batch) && ok
+	}
+}
+
+// nextBatch dequeues the next batch of events. If all is false, only a full
+// batch is dequeued.
+func (i *Ingester) nextBatch(all bool) []Event {
+	i.mtx.Lock()
+	defer i.mtx.Unlock()
+
+	if len(i.queue) == 0 || (!all && !i.batchReady()) {
+		return nil
+	}
+
+	var (
+		n     int
+		bytes int
+	)
+	for n < len(i.queue) && n < i.batchSize {
+		size := i.queue[n].size
+		if i.maxBatchBytes > 0 && n > 0 && bytes+size > i.maxBatchBytes {
+			break
+		}
+		bytes += size
+		n++
+	}
+
+	batch := make([]Event, n)
+	for j := range batch {
+		batch[j] = i.queue[j].event
+		i.queue[j] = queuedEvent{}
+	}
+	i.queue = i.queue[n:]
+	i.queueBytes -= bytes
+
+	// Wake up callers waiting for room in the queue.
+	close(i.spaceCh)
+	i.spaceCh = make(chan struct{})
+
+	return batch
+}
+
+// ingest sends the given batch of events. It returns false if the batch failed
+// to ingest.
+func (i *Ingester) ingest(events []Event) bool {
+	res, err := i.client.Datasets.IngestEvents(i.ctx, i.datasetID, i.ingestOptions, events...)
+	if err != nil && i.spool != nil && IsTemporary(err) {
+		spoolErr := i.spool.AppendEvents(events...)
+		if spoolErr == nil {
+			return false
+		}
+		err = fmt.Errorf("%w (failed to spool batch: %s)", err, spoolErr)
+	}
+
+	if i.errorHandler == nil {
+		return err == nil
+	} else if err != nil {
+		i.errorHandler(err, nil, events)
+	} else if res.Failed > 0 {
+		i.errorHandler(nil, res, events)
+	}
+
+	return err == nil
+}
+
+// replay replays the spooled batches, if any. Partial failures and segments
+// quarantined because of a permanent error are reported to the error handler.
+func (i *Ingester) replay() {
+	if i.spool == nil || !i.spool.Pending() {
+		return
+	}
+
+	res, err := i.spool.Replay(i.ctx, i.client, i.datasetID, i.ingestOptions)
+	if i.errorHandler == nil {
+		return
+	}
+
+	// Other errors are not reported as the spooled batches remain in place and
+	// are replayed again at the next flush interval.
+	if errors.As(err, &QuarantineError{}) {
+		i.errorHandler(err, nil, nil)
+	}
+	if res != nil && res.Failed > 0 {
+		i.errorHandler(nil, res, nil)
+	}
+}
@@ -0,0 +1,340 @@
+package axiom
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewIngester(t *testing.T) {
+	client := newClient(t)
+
+	ingester, err := NewIngester(client, "test",
+		SetBatchSize(10),
+		SetQueueSize(5),
+	)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, ingester.Close(context.Background()))
+	}()
+
+	assert.Equal(t, 10, ingester.batchSize)
+	assert.Equal(t, 10, ingester.queueSize, "queue size should be raised to batch size")
+	assert.Equal(t, defaultFlushInterval, ingester.flushInterval)
+	assert.Equal(t, OverflowBlock, ingester.overflowPolicy)
+
+	_, err = NewIngester(nil, "test")
+	assert.Error(t, err)
+
+	_, err = NewIngester(client, "test", SetBatchSize(0))
+	assert.Error(t, err)
+}
+
+func TestIngester_FlushFullBatch(t *testing.T) {
+	var (
+		lines    uint64
+		requests uint64
+	)
+	hf := func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddUint64(&requests, 1)
+		atomic.AddUint64(&lines, countLines(t, r))
+
+		_, _ = fmt.Fprint(w, "{}")
+	}
+
+	client, teardown := setup(t, "/api/v1/datasets/test/ingest", hf)
+	defer teardown()
+
+	ingester, err := NewIngester(client, "test",
+		SetBatchSize(10),
+		SetFlushInterval(time.Hour),
+	)
+	require.NoError(t, err)
+
+	for i := 0; i < 25; i++ {
+		require.NoError(t, ingester.Ingest(context.Background(), Event{"i": i}))
+	}
+
+	// Let the server process.
+	time.Sleep(100 * time.Millisecond)
+
+	// Should have two full batches right away.
+	assert.EqualValues(t, 20, atomic.LoadUint64(&lines))
+	assert.EqualValues(t, 2, atomic.LoadUint64(&requests))
+
+	// Closing sends the remainder.
+	require.NoError(t, ingester.Close(context.Background()))
+
+	assert.EqualValues(t, 25, atomic.LoadUint64(&lines))
+	assert.EqualValues(t, 3, atomic.LoadUint64(&requests))
+
+	err = ingester.Ingest(context.Background(), Event{})
+	assert.ErrorIs(t, err, ErrIngesterClosed)
+}
+
+func TestIngester_FlushInterval(t *testing.T) {
+	var lines uint64
+	hf := func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddUint64(&lines, countLines(t, r))
+
+		_, _ = fmt.Fprint(w, "{}")
+	}
+
+	client, teardown := setup(t, "/api/v1/datasets/test/ingest", hf)
+	defer teardown()
+
+	ingester, err := NewIngester(client, "test", SetFlushInterval(50*time.Millisecond))
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, ingester.Close(context.Background()))
+	}()
+
+	require.NoError(t, ingester.Ingest(context.Background(), Event{"a": "b"}, Event{"c": "d"}))
+
+	// Wait for timer based flush.
+	time.Sleep(150 * time.Millisecond)
+
+	assert.EqualValues(t, 2, atomic.LoadUint64(&lines))
+}
+
+func TestIngester_Flush(t *testing.T) {
+	var lines uint64
+	hf := func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddUint64(&lines, countLines(t, r))
+
+		_, _ = fmt.Fprint(w, "{}")
+	}
+
+	client, teardown := setup(t, "/api/v1/datasets/test/ingest", hf)
+	defer teardown()
+
+	ingester, err := NewIngester(client, "test", SetFlushInterval(time.Hour))
+	require.NoError(t, err)
+
+	require.NoError(t, ingester.Ingest(context.Background(), Event{"a": "b"}))
+	require.NoError(t, ingester.Flush(context.Background()))
+
+	assert.EqualValues(t, 1, atomic.LoadUint64(&lines))
+
+	require.NoError(t, ingester.Close(context.Background()))
+
+	assert.ErrorIs(t, ingester.Flush(context.Background()), ErrIngesterClosed)
+}
+
+func TestIngester_MaxBatchBytes(t *testing.T) {
+	var requests uint64
+	hf := func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddUint64(&requests, 1)
+		assert.EqualValues(t, 2, countLines(t, r))
+
+		_, _ = fmt.Fprint(w, "{}")
+	}
+
+	client, teardown := setup(t, "/api/v1/datasets/test/ingest", hf)
+	defer teardown()
+
+	// Every event is 10 bytes, including the newline.
+	ingester, err := NewIngester(client, "test",
+		SetMaxBatchBytes(25),
+		SetFlushInterval(time.Hour),
+	)
+	require.NoError(t, err)
+
+	for i := 0; i < 6; i++ {
+		require.NoError(t, ingester.Ingest(context.Background(), Event{"a": "bcd"}))
+	}
+	require.NoError(t, ingester.Close(context.Background()))
+
+	assert.EqualValues(t, 3, atomic.LoadUint64(&requests))
+}
+
+func TestIngester_OverflowPolicy(t *testing.T) {
+	tests := []struct {
+		policy  OverflowPolicy
+		exp     []interface{}
+		dropped []interface{}
+	}{
+		{OverflowDropOldest, []interface{}{float64(0), float64(3), float64(4)}, []interface{}{1, 2}},
+		{OverflowDropNewest, []interface{}{float64(0), float64(1), float64(2)}, []interface{}{3, 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy.String(), func(t *testing.T) {
+			var (
+				received []interface{}
+				dropped  []interface{}
+				mtx      sync.Mutex
+				blockCh  = make(chan struct{})
+			)
+			hf := func(w http.ResponseWriter, r *http.Request) {
+				<-blockCh
+
+				gzr, err := gzip.NewReader(r.Body)
+				require.NoError(t, err)
+
+				events := decodeEvents(t, gzr)
+				mtx.Lock()
+				for _, event := range events {
+					received = append(received, event["i"])
+				}
+				mtx.Unlock()
+
+				_, _ = fmt.Fprint(w, "{}")
+			}
+
+			client, teardown := setup(t, "/api/v1/datasets/test/ingest", hf)
+			defer teardown()
+
+			ingester, err := NewIngester(client, "test",
+				SetBatchSize(1),
+				SetQueueSize(2),
+				SetOverflowPolicy(tt.policy),
+				SetFlushInterval(time.Hour),
+				SetDropHandler(func(event Event) {
+					dropped = append(dropped, event["i"])
+				}),
+			)
+			require.NoError(t, err)
+
+			// The first event is picked up and blocks in the server handler.
+			require.NoError(t, ingester.Ingest(context.Background(), Event{"i": 0}))
+			time.Sleep(50 * time.Millisecond)
+
+			for i := 1; i < 5; i++ {
+				require.NoError(t, ingester.Ingest(context.Background(), Event{"i": i}))
+			}
+
+			close(blockCh)
+			require.NoError(t, ingester.Close(context.Background()))
+
+			assert.EqualValues(t, 2, ingester.Dropped())
+			assert.Equal(t, tt.exp, received)
+			assert.Equal(t, tt.dropped, dropped)
+		})
+	}
+}
+
+func TestIngester_OverflowBlock(t *testing.T) {
+	blockCh := make(chan struct{})
+	hf := func(w http.ResponseWriter, r *http.Request) {
+		<-blockCh
+		_, _ = fmt.Fprint(w, "{}")
+	}
+
+	client, teardown := setup(t, "/api/v1/datasets/test/ingest", hf)
+	defer teardown()
+
+	ingester, err := NewIngester(client, "test",
+		SetBatchSize(1),
+		SetQueueSize(1),
+		SetFlushInterval(time.Hour),
+	)
+	require.NoError(t, err)
+
+	require.NoError(t, ingester.Ingest(context.Background(), Event{"i": 0}))
+	time.Sleep(50 * time.Millisecond)
+	require.NoError(t, ingester.Ingest(context.Background(), Event{"i": 1}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err = ingester.Ingest(ctx, Event{"i": 2})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	close(blockCh)
+	require.NoError(t, ingester.Close(context.Background()))
+
+	assert.Zero(t, ingester.Dropped())
+}
+
+func TestIngester_ErrorHandler(t *testing.T) {
+	hf := func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{
+			"ingested": 1,
+			"failed": 1,
+			"failures": [
+				{
+					"timestamp": "2022-01-01T00:00:00Z",
+					"error": "invalid event"
+				}
+			]
+		}`)
+	}
+
+	client, teardown := setup(t, "/api/v1/datasets/test/ingest", hf)
+	defer teardown()
+
+	var (
+		called uint64
+		events = []Event{{"a": "b"}, {"c": "d"}}
+	)
+	ingester, err := NewIngester(client, "test",
+		SetErrorHandler(func(err error, status *IngestStatus, batch []Event) {
+			atomic.AddUint64(&called, 1)
+			assert.NoError(t, err)
+			if assert.NotNil(t, status) {
+				assert.EqualValues(t, 1, status.Failed)
+			}
+			assert.Equal(t, events, batch)
+		}),
+	)
+	require.NoError(t, err)
+
+	require.NoError(t, ingester.Ingest(context.Background(), events...))
+	require.NoError(t, ingester.Close(context.Background()))
+
+	assert.EqualValues(t, 1, atomic.LoadUint64(&called))
+}
+
+func TestOverflowPolicy_String(t *testing.T) {
+	// Check outer bounds.
+	assert.Contains(t, (OverflowDropNewest + 1).String(), "OverflowPolicy(")
+
+	for p := OverflowBlock; p <= OverflowDropNewest; p++ {
+		s := p.String()
+		assert.NotEmpty(t, s)
+		assert.NotContains(t, s, "OverflowPolicy(")
+	}
+}
+
+func countLines(t *testing.T, r *http.Request) uint64 {
+	t.Helper()
+
+	gzr, err := gzip.NewReader(r.Body)
+	require.NoError(t, err)
+
+	var lines uint64
+	s := bufio.NewScanner(gzr)
+	for s.Scan() {
+		lines++
+	}
+	assert.NoError(t, s.Err())
+
+	return lines
+}
+
+func decodeEvents(t *testing.T, r io.Reader) []Event {
+	t.Helper()
+
+	var (
+		events []Event
+		dec    = json.NewDecoder(r)
+	)
+	for dec.More() {
+		var event Event
+		require.NoError(t, dec.Decode(&event))
+		events = append(events, event)
+	}
+
+	return events
+}
@@ -0,0 +1,365 @@
+// Package scan implements decoding of loosely typed query result values into Go
+// structs. Struct fields are matched by the name given in their `axiom` tag or,
+// if not tagged, by their name. Values are coerced into the type of the
+// struct field where possible.
+package scan
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TagName is the name of the struct tag that holds the name a struct field is
+// matched by.
+const TagName = "axiom"
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+// field is an exported struct field that values are scanned into.
+type field struct {
+	name  string
+	index []int
+}
+
+// fieldCache caches the fields of struct types.
+var fieldCache sync.Map // map[reflect.Type][]field
+
+// Into scans the values of the given map into the struct pointed to by dest.
+// Values are looked up by the name of the struct field. If the name is not a
+// key of the map, it is treated as a dotted path into nested maps. If that
+// fails as well, the lookup falls back to a case-insensitive match.
+func Into(dest interface{}, values map[string]interface{}) error {
+	rv := reflect.ValueOf(dest)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("destination must be a non-nil pointer to a struct, got %T", dest)
+	}
+	return scanStruct(rv.Elem(), values)
+}
+
+// Slice scans n value maps into the slice pointed to by dest. The elements of
+// the slice must be structs or pointers to structs. The i-th map is returned by
+// the given function.
+func Slice(dest interface{}, n int, values func(i int) map[string]interface{}) error {
+	rv := reflect.ValueOf(dest)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("destination must be a non-nil pointer to a slice, got %T", dest)
+	}
+
+	slice := rv.Elem()
+	elemType := slice.Type().Elem()
+	isPtr := elemType.Kind() == reflect.Ptr
+	if isPtr {
+		elemType = elemType.Elem()
+	}
+	if elemType.Kind() != reflect.Struct {
+		return fmt.Errorf("destination slice elements must be structs or pointers to structs, got %s", slice.Type().Elem())
+	}
+
+	res := reflect.MakeSlice(slice.Type(), n, n)
+	for i := 0; i < n; i++ {
+		elem := reflect.New(elemType)
+		if err := scanStruct(elem.Elem(), values(i)); err != nil {
+			return fmt.Errorf("row %d: %w", i, err)
+		}
+		if isPtr {
+			res.Index(i).Set(elem)
+		} else {
+			res.Index(i).Set(elem.Elem())
+		}
+	}
+	slice.Set(res)
+
+	return nil
+}
+
+// Lookup returns the value for the given name. If the name is not a key of the
+// map, it is treated as a dotted path into nested maps. If that fails as well,
+// the lookup falls back to a case-insensitive match of the keys.
+func Lookup(values map[string]interface{}, name string) (interface{}, bool) {
+	if v, ok := values[name]; ok {
+		return v, true
+	}
+
+	// Walk nested maps, preferring the longest matching key.
+	for i := strings.LastIndexByte(name, '.'); i > 0; i = strings.LastIndexByte(name[:i], '.') {
+		if nested, ok := values[name[:i]].(map[string]interface{}); ok {
+			if v, ok := Lookup(nested, name[i+1:]); ok {
+				return v, true
+			}
+		}
+	}
+
+	for k, v := range values {
+		if strings.EqualFold(k, name) {
+			return v, true
+		}
+	}
+
+	return nil, false
+}
+
+func scanStruct(dest reflect.Value, values map[string]interface{}) error {
+	for _, f := range fieldsOf(dest.Type()) {
+		v, ok := Lookup(values, f.name)
+		if !ok {
+			continue
+		}
+		if err := Value(fieldByIndex(dest, f.index), v); err != nil {
+			return fmt.Errorf("field %q: %w", f.name, err)
+		}
+	}
+	return nil
+}
+
+// fieldByIndex returns the nested field of the given struct, allocating
+// embedded struct pointers on the way.
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// fieldsOf returns the fields of the given struct type. Fields of embedded
+// structs without a tag are promoted.
+func fieldsOf(t reflect.Type) []field {
+	if cached, ok := fieldCache.Load(t); ok {
+		return cached.([]field)
+	}
+
+	var fields []field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+
+		tag, hasTag := sf.Tag.Lookup(TagName)
+		name := strings.Split(tag, ",")[0]
+		if name == "-" {
+			continue
+		}
+
+		ft := sf.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if sf.Anonymous && !hasTag && ft.Kind() == reflect.Struct {
+			for _, ef := range fieldsOf(ft) {
+				fields = append(fields, field{
+					name:  ef.name,
+					index: append([]int{i}, ef.index...),
+				})
+			}
+			continue
+		} else if sf.PkgPath != "" {
+			// Unexported.
+			continue
+		}
+
+		if name == "" {
+			name = sf.Name
+		}
+		fields = append(fields, field{name: name, index: []int{i}})
+	}
+
+	fieldCache.Store(t, fields)
+
+	return fields
+}
+
+// Value assigns the given value to dest, coercing it into the type of dest.
+func Value(dest reflect.Value, v interface{}) error {
+	if v == nil {
+		dest.Set(reflect.Zero(dest.Type()))
+		return nil
+	}
+
+	switch dest.Kind() {
+	case reflect.Ptr:
+		elem := reflect.New(dest.Type().Elem())
+		if err := Value(elem.Elem(), v); err != nil {
+			return err
+		}
+		dest.Set(elem)
+		return nil
+	case reflect.Interface:
+		rv := reflect.ValueOf(v)
+		if !rv.Type().AssignableTo(dest.Type()) {
+			return typeError(v, dest.Type())
+		}
+		dest.Set(rv)
+		return nil
+	}
+
+	switch dest.Type() {
+	case timeType:
+		t, err := toTime(v)
+		if err != nil {
+			return err
+		}
+		dest.Set(reflect.ValueOf(t))
+		return nil
+	case durationType:
+		d, err := toDuration(v)
+		if err != nil {
+			return err
+		}
+		dest.SetInt(int64(d))
+		return nil
+	}
+
+	switch dest.Kind() {
+	case reflect.String:
+		s, ok := v.(string)
+		if !ok {
+			return typeError(v, dest.Type())
+		}
+		dest.SetString(s)
+	case reflect.Bool:
+		switch b := v.(type) {
+		case bool:
+			dest.SetBool(b)
+		case string:
+			parsed, err := strconv.ParseBool(b)
+			if err != nil {
+				return typeError(v, dest.Type())
+			}
+			dest.SetBool(parsed)
+		default:
+			return typeError(v, dest.Type())
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		f, err := toFloat(v)
+		if err != nil || f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 || dest.OverflowInt(int64(f)) {
+			return typeError(v, dest.Type())
+		}
+		dest.SetInt(int64(f))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		f, err := toFloat(v)
+		if err != nil || f < 0 || f != math.Trunc(f) || f >= math.MaxUint64 || dest.OverflowUint(uint64(f)) {
+			return typeError(v, dest.Type())
+		}
+		dest.SetUint(uint64(f))
+	case reflect.Float32, reflect.Float64:
+		f, err := toFloat(v)
+		if err != nil || dest.OverflowFloat(f) {
+			return typeError(v, dest.Type())
+		}
+		dest.SetFloat(f)
+	case reflect.Slice:
+		arr, ok := v.([]interface{})
+		if !ok {
+			return assignDirect(dest, v)
+		}
+		s := reflect.MakeSlice(dest.Type(), len(arr), len(arr))
+		for i, elem := range arr {
+			if err := Value(s.Index(i), elem); err != nil {
+				return fmt.Errorf("index %d: %w", i, err)
+			}
+		}
+		dest.Set(s)
+	case reflect.Map:
+		m, ok := v.(map[string]interface{})
+		if !ok || dest.Type().Key().Kind() != reflect.String {
+			return assignDirect(dest, v)
+		}
+		res := reflect.MakeMapWithSize(dest.Type(), len(m))
+		for k, elem := range m {
+			ev := reflect.New(dest.Type().Elem()).Elem()
+			if err := Value(ev, elem); err != nil {
+				return fmt.Errorf("key %q: %w", k, err)
+			}
+			res.SetMapIndex(reflect.ValueOf(k).Convert(dest.Type().Key()), ev)
+		}
+		dest.Set(res)
+	case reflect.Struct:
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return assignDirect(dest, v)
+		}
+		return scanStruct(dest, m)
+	default:
+		return assignDirect(dest, v)
+	}
+
+	return nil
+}
+
+func assignDirect(dest reflect.Value, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if !rv.Type().AssignableTo(dest.Type()) {
+		return typeError(v, dest.Type())
+	}
+	dest.Set(rv)
+	return nil
+}
+
+func toFloat(v interface{}) (float64, error) {
+	switch n := v.(type) {
+	case json.Number:
+		return n.Float64()
+	case string:
+		return strconv.ParseFloat(n, 64)
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), nil
+	}
+
+	return 0, errors.New("not a number")
+}
+
+// toTime converts the given value into a time. Strings are parsed as RFC 3339
+// timestamps, numbers are interpreted as nanoseconds since the Unix epoch.
+func toTime(v interface{}) (time.Time, error) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, nil
+	case string:
+		return time.Parse(time.RFC3339Nano, t)
+	}
+
+	f, err := toFloat(v)
+	if err != nil {
+		return time.Time{}, typeError(v, timeType)
+	}
+	return time.Unix(0, int64(f)).UTC(), nil
+}
+
+// toDuration converts the given value into a duration. Strings are parsed as Go
+// durations, numbers are interpreted as nanoseconds.
+func toDuration(v interface{}) (time.Duration, error) {
+	if s, ok := v.(string); ok {
+		return time.ParseDuration(s)
+	}
+
+	f, err := toFloat(v)
+	if err != nil {
+		return 0, typeError(v, durationType)
+	}
+	return time.Duration(f), nil
+}
+
+func typeError(v interface{}, t reflect.Type) error {
+	return fmt.Errorf("cannot scan %T value %v into %s", v, v, t)
+}
@@ -0,0 +1,152 @@
+package scan
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type embedded struct {
+	Region string `axiom:"region"`
+}
+
+type target struct {
+	embedded
+
+	Time     time.Time     `axiom:"_time"`
+	Duration time.Duration `axiom:"duration"`
+	Status   int           `axiom:"status"`
+	Bytes    *uint32       `axiom:"bytes"`
+	Ratio    float32       `axiom:"ratio"`
+	OK       bool          `axiom:"ok"`
+	Host     string
+	Agent    string            `axiom:"request.user_agent"`
+	Tags     []string          `axiom:"tags"`
+	Labels   map[string]int    `axiom:"labels"`
+	Raw      interface{}       `axiom:"raw"`
+	Nested   struct{ A int }   `axiom:"nested"`
+	Ignored  string            `axiom:"-"`
+	Missing  string            `axiom:"missing"`
+	Extra    map[string]string `axiom:"extra"`
+
+	unexported string //nolint:structcheck,unused // Must be ignored.
+}
+
+func TestInto(t *testing.T) {
+	values := map[string]interface{}{
+		"_time":    "2022-01-01T00:00:00Z",
+		"duration": "1.5s",
+		"status":   float64(200),
+		"bytes":    json.Number("1024"),
+		"ratio":    "0.5",
+		"ok":       true,
+		"host":     "a",
+		"request": map[string]interface{}{
+			"user_agent": "curl",
+		},
+		"tags":    []interface{}{"x", "y"},
+		"labels":  map[string]interface{}{"a": float64(1)},
+		"raw":     []interface{}{float64(1)},
+		"nested":  map[string]interface{}{"a": float64(2)},
+		"Ignored": "foo",
+		"region":  "eu",
+		"extra":   nil,
+	}
+
+	var act target
+	require.NoError(t, Into(&act, values))
+
+	bytes := uint32(1024)
+	exp := target{
+		embedded: embedded{Region: "eu"},
+		Time:     time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+		Duration: 1500 * time.Millisecond,
+		Status:   200,
+		Bytes:    &bytes,
+		Ratio:    0.5,
+		OK:       true,
+		Host:     "a",
+		Agent:    "curl",
+		Tags:     []string{"x", "y"},
+		Labels:   map[string]int{"a": 1},
+		Raw:      []interface{}{float64(1)},
+		Nested:   struct{ A int }{A: 2},
+	}
+	assert.Equal(t, exp, act)
+}
+
+func TestInto_Error(t *testing.T) {
+	tests := []struct {
+		name   string
+		values map[string]interface{}
+		err    string
+	}{
+		{"fraction into int", map[string]interface{}{"status": 1.5}, `field "status": cannot scan float64 value 1.5 into int`},
+		{"negative into uint", map[string]interface{}{"bytes": float64(-1)}, `field "bytes": cannot scan float64 value -1 into uint32`},
+		{"overflow", map[string]interface{}{"bytes": float64(1 << 40)}, `field "bytes": cannot scan float64 value 1.099511627776e+12 into uint32`},
+		{"number into string", map[string]interface{}{"host": float64(1)}, `field "Host": cannot scan float64 value 1 into string`},
+		{"invalid time", map[string]interface{}{"_time": "yesterday"}, `field "_time": parsing time "yesterday" as "2006-01-02T15:04:05.999999999Z07:00": cannot parse "yesterday" as "2006"`},
+		{"invalid element", map[string]interface{}{"tags": []interface{}{"x", true}}, `field "tags": index 1: cannot scan bool value true into string`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var act target
+			assert.EqualError(t, Into(&act, tt.values), tt.err)
+		})
+	}
+
+	assert.Error(t, Into(target{}, nil))
+}
+
+func TestSlice(t *testing.T) {
+	rows := []map[string]interface{}{
+		{"host": "a"},
+		{"host": "b"},
+	}
+	values := func(i int) map[string]interface{} { return rows[i] }
+
+	var act []*target
+	require.NoError(t, Slice(&act, len(rows), values))
+
+	if assert.Len(t, act, 2) {
+		assert.Equal(t, "a", act[0].Host)
+		assert.Equal(t, "b", act[1].Host)
+	}
+
+	var invalid []string
+	assert.Error(t, Slice(&invalid, len(rows), values))
+}
+
+func TestLookup(t *testing.T) {
+	values := map[string]interface{}{
+		"a.b": 1,
+		"c": map[string]interface{}{
+			"d": map[string]interface{}{
+				"e": 2,
+			},
+		},
+		"Foo": 3,
+	}
+
+	tests := []struct {
+		name string
+		exp  interface{}
+		ok   bool
+	}{
+		{"a.b", 1, true},
+		{"c.d.e", 2, true},
+		{"c.d.f", nil, false},
+		{"foo", 3, true},
+		{"bar", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, ok := Lookup(values, tt.name)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.exp, v)
+		})
+	}
+}
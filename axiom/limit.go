@@ -0,0 +1,219 @@
+package axiom
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:generate go run -mod=mod golang.org/x/tools/cmd/stringer -type=LimitScope,LimitType -linecomment -output=limit_string.go
+
+const (
+	headerRateScope = "X-RateLimit-Scope"
+
+	headerAPILimit     = "X-RateLimit-Limit"
+	headerAPIRemaining = "X-RateLimit-Remaining"
+	headerAPIReset     = "X-RateLimit-Reset"
+
+	headerQueryLimit     = "X-QueryLimit-Limit"
+	headerQueryRemaining = "X-QueryLimit-Remaining"
+	headerQueryReset     = "X-QueryLimit-Reset"
+
+	headerIngestLimit     = "X-IngestLimit-Limit"
+	headerIngestRemaining = "X-IngestLimit-Remaining"
+	headerIngestReset     = "X-IngestLimit-Reset"
+)
+
+// LimitScope is the scope of a Limit.
+type LimitScope uint8
+
+// All available limit scopes.
+const (
+	LimitScopeUnknown      LimitScope = iota // unknown
+	LimitScopeUser                           // user
+	LimitScopeOrganization                   // organization
+	LimitScopeAnonymous                      // anonymous
+)
+
+func limitScopeFromString(s string) (ls LimitScope) {
+	switch strings.ToLower(s) {
+	case LimitScopeUser.String():
+		ls = LimitScopeUser
+	case LimitScopeOrganization.String():
+		ls = LimitScopeOrganization
+	case LimitScopeAnonymous.String():
+		ls = LimitScopeAnonymous
+	default:
+		ls = LimitScopeUnknown
+	}
+
+	return ls
+}
+
+// LimitType is the type of a Limit. It describes the kind of operations the
+// limit applies to.
+type LimitType uint8
+
+// All available limit types.
+const (
+	emptyLimitType LimitType = iota //
+
+	APILimit    // api
+	QueryLimit  // query
+	IngestLimit // ingest
+)
+
+// limitTypeFromPath returns the type of limit that applies to requests to the
+// given API path.
+func limitTypeFromPath(path string) LimitType {
+	switch {
+	case strings.HasSuffix(path, "/ingest"):
+		return IngestLimit
+	case strings.HasSuffix(path, "/query"), strings.HasSuffix(path, "/_apl"):
+		return QueryLimit
+	}
+	return APILimit
+}
+
+// Limit represents a limit for the current client, as reported by the server.
+type Limit struct {
+	// Type of the limit.
+	Type LimitType
+	// Scope a limit is enforced for. Only present on API limits.
+	Scope LimitScope
+	// Limit is the maximum number of operations (API and query limits) or
+	// bytes (ingest limit) allowed in the current window.
+	Limit uint64
+	// Remaining is the number of operations or bytes remaining in the current
+	// window.
+	Remaining uint64
+	// Reset is the time at which the current window resets.
+	Reset time.Time
+}
+
+// String returns a string representation of the limit.
+func (l Limit) String() string {
+	return fmt.Sprintf("%d/%d %s remaining until %s", l.Remaining, l.Limit, l.Type, l.Reset)
+}
+
+// IsZero returns true if the limit was not reported by the server.
+func (l Limit) IsZero() bool {
+	return l.Type == emptyLimitType
+}
+
+// parseLimits parses all limits present in the given response headers.
+func parseLimits(h http.Header) []Limit {
+	var limits []Limit
+	for _, typ := range []LimitType{APILimit, QueryLimit, IngestLimit} {
+		if limit, ok := parseLimit(h, typ); ok {
+			limits = append(limits, limit)
+		}
+	}
+	return limits
+}
+
+// parseLimit parses the limit of the given type from the given response
+// headers. It returns false if the headers don't carry such a limit.
+func parseLimit(h http.Header, typ LimitType) (Limit, bool) {
+	var limitHeader, remainingHeader, resetHeader string
+	switch typ {
+	case APILimit:
+		limitHeader, remainingHeader, resetHeader = headerAPILimit, headerAPIRemaining, headerAPIReset
+	case QueryLimit:
+		limitHeader, remainingHeader, resetHeader = headerQueryLimit, headerQueryRemaining, headerQueryReset
+	case IngestLimit:
+		limitHeader, remainingHeader, resetHeader = headerIngestLimit, headerIngestRemaining, headerIngestReset
+	default:
+		return Limit{}, false
+	}
+
+	limitStr := h.Get(limitHeader)
+	if limitStr == "" {
+		return Limit{}, false
+	}
+
+	limit := Limit{Type: typ}
+	limit.Limit, _ = strconv.ParseUint(limitStr, 10, 64)
+	limit.Remaining, _ = strconv.ParseUint(h.Get(remainingHeader), 10, 64)
+	if reset, err := strconv.ParseInt(h.Get(resetHeader), 10, 64); err == nil {
+		limit.Reset = time.Unix(reset, 0)
+	}
+	if typ == APILimit {
+		limit.Scope = limitScopeFromString(h.Get(headerRateScope))
+	}
+
+	return limit, true
+}
+
+// Limit returns the most recent limit of the given type reported by the
+// server. It is updated with every response that carries limit information,
+// successful or not, and can be used to slow down proactively before the limit
+// is exceeded. The returned limit is zero, if the server hasn't reported it,
+// yet.
+func (c *Client) Limit(typ LimitType) Limit {
+	c.limitsMtx.RLock()
+	defer c.limitsMtx.RUnlock()
+
+	return c.limits[typ]
+}
+
+// updateLimits records the given limits on the client.
+func (c *Client) updateLimits(limits []Limit) {
+	if len(limits) == 0 {
+		return
+	}
+
+	c.limitsMtx.Lock()
+	defer c.limitsMtx.Unlock()
+
+	if c.limits == nil {
+		c.limits = make(map[LimitType]Limit, len(limits))
+	}
+	for _, limit := range limits {
+		c.limits[limit.Type] = limit
+	}
+}
+
+var _ error = (*LimitError)(nil)
+
+// LimitError is returned when the server responds with a 429 status code
+// because a limit was exceeded. It matches `ErrRateLimitExceeded` when used
+// with `errors.Is()`. For compatibility with callers that inspect the status
+// code of API errors, it can also be retrieved as an Error with a Status of 429
+// using `errors.As()`.
+type LimitError struct {
+	// Limit that was exceeded. It is zero, if the server didn't report the
+	// limit.
+	Limit Limit
+	// Message returned by the server.
+	Message string
+}
+
+// Error implements the error interface.
+func (e LimitError) Error() string {
+	if e.Limit.IsZero() {
+		return fmt.Sprintf("API error %d: %s", http.StatusTooManyRequests, e.Message)
+	}
+	return fmt.Sprintf("API error %d: %s: %s limit exceeded, try again after %s",
+		http.StatusTooManyRequests, e.Message, e.Limit.Type, e.Limit.Reset)
+}
+
+// Is reports whether target is `ErrRateLimitExceeded`.
+func (e LimitError) Is(target error) bool {
+	return target == ErrRateLimitExceeded
+}
+
+// As sets target to the equivalent Error, if target is a pointer to an Error.
+func (e LimitError) As(target interface{}) bool {
+	apiErr, ok := target.(*Error)
+	if !ok {
+		return false
+	}
+	*apiErr = Error{
+		Status:  http.StatusTooManyRequests,
+		Message: e.Message,
+	}
+	return true
+}
@@ -0,0 +1,168 @@
+package axiom
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_Limit(t *testing.T) {
+	reset := time.Now().Add(time.Minute).Truncate(time.Second)
+
+	hf := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Scope", "organization")
+		w.Header().Set("X-RateLimit-Limit", "1000")
+		w.Header().Set("X-RateLimit-Remaining", "999")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+		w.Header().Set("X-QueryLimit-Limit", "100")
+		w.Header().Set("X-QueryLimit-Remaining", "42")
+		w.Header().Set("X-QueryLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+		w.WriteHeader(http.StatusOK)
+	}
+
+	client, teardown := setup(t, "/api/v1/datasets/test/query", hf)
+	defer teardown()
+
+	assert.True(t, client.Limit(QueryLimit).IsZero())
+
+	req, err := client.newRequest(context.Background(), http.MethodPost, "/api/v1/datasets/test/query", nil)
+	require.NoError(t, err)
+
+	resp, err := client.do(req, nil)
+	require.NoError(t, err)
+
+	expQueryLimit := Limit{
+		Type:      QueryLimit,
+		Limit:     100,
+		Remaining: 42,
+		Reset:     reset,
+	}
+	assert.Equal(t, expQueryLimit, resp.Limit)
+	assert.Equal(t, expQueryLimit, client.Limit(QueryLimit))
+
+	assert.Equal(t, Limit{
+		Type:      APILimit,
+		Scope:     LimitScopeOrganization,
+		Limit:     1000,
+		Remaining: 999,
+		Reset:     reset,
+	}, client.Limit(APILimit))
+
+	assert.True(t, client.Limit(IngestLimit).IsZero())
+}
+
+func TestClient_do_LimitError(t *testing.T) {
+	reset := time.Now().Add(time.Minute).Truncate(time.Second)
+
+	hf := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-IngestLimit-Limit", "1024")
+		w.Header().Set("X-IngestLimit-Remaining", "0")
+		w.Header().Set("X-IngestLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusTooManyRequests)
+
+		err := json.NewEncoder(w).Encode(Error{
+			Message: "ingest limit exceeded",
+		})
+		assert.NoError(t, err)
+	}
+
+	client, teardown := setup(t, "/api/v1/datasets/test/ingest", hf)
+	defer teardown()
+
+	req, err := client.newRequest(context.Background(), http.MethodPost, "/api/v1/datasets/test/ingest", nil)
+	require.NoError(t, err)
+
+	_, err = client.do(req, nil)
+	require.ErrorIs(t, err, ErrRateLimitExceeded)
+
+	var limitErr LimitError
+	if assert.True(t, errors.As(err, &limitErr)) {
+		assert.Equal(t, "ingest limit exceeded", limitErr.Message)
+		assert.Equal(t, Limit{
+			Type:      IngestLimit,
+			Limit:     1024,
+			Remaining: 0,
+			Reset:     reset,
+		}, limitErr.Limit)
+	}
+	assert.Contains(t, err.Error(), "ingest limit exceeded, try again after")
+}
+
+func TestClient_do_LimitErrorNoJSON(t *testing.T) {
+	hf := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}
+
+	client, teardown := setup(t, "/", hf)
+	defer teardown()
+
+	req, err := client.newRequest(context.Background(), http.MethodGet, "/", nil)
+	require.NoError(t, err)
+
+	_, err = client.do(req, nil)
+	require.ErrorIs(t, err, ErrRateLimitExceeded)
+	assert.EqualError(t, err, "API error 429: Too Many Requests")
+
+	var limitErr LimitError
+	if assert.True(t, errors.As(err, &limitErr)) {
+		assert.True(t, limitErr.Limit.IsZero())
+	}
+
+	// A 429 used to be returned as an Error and can still be handled as such.
+	var apiErr Error
+	if assert.True(t, errors.As(err, &apiErr)) {
+		assert.Equal(t, http.StatusTooManyRequests, apiErr.Status)
+		assert.Equal(t, "Too Many Requests", apiErr.Message)
+	}
+}
+
+func TestLimitTypeFromPath(t *testing.T) {
+	tests := []struct {
+		input string
+		exp   LimitType
+	}{
+		{"/api/v1/datasets/test/ingest", IngestLimit},
+		{"/api/v1/datasets/test/query", QueryLimit},
+		{"/api/v1/datasets/_apl", QueryLimit},
+		{"/api/v1/datasets", APILimit},
+		{"/api/v1/users/current", APILimit},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			assert.Equal(t, tt.exp, limitTypeFromPath(tt.input))
+		})
+	}
+}
+
+func TestLimitScope_String(t *testing.T) {
+	// Check outer bounds.
+	assert.Contains(t, (LimitScopeAnonymous + 1).String(), "LimitScope(")
+
+	for s := LimitScopeUnknown; s <= LimitScopeAnonymous; s++ {
+		str := s.String()
+		assert.NotEmpty(t, str)
+		assert.NotContains(t, str, "LimitScope(")
+		assert.Equal(t, s, limitScopeFromString(str))
+	}
+}
+
+func TestLimitType_String(t *testing.T) {
+	// Check outer bounds.
+	assert.Empty(t, LimitType(0).String())
+	assert.Empty(t, emptyLimitType.String())
+	assert.Contains(t, (IngestLimit + 1).String(), "LimitType(")
+
+	for typ := APILimit; typ <= IngestLimit; typ++ {
+		s := typ.String()
+		assert.NotEmpty(t, s)
+		assert.NotContains(t, s, "LimitType(")
+	}
+}
@@ -0,0 +1,91 @@
+package axiom
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// RoundTripFunc sends a single HTTP request and returns its response. It
+// follows the semantics of `http.RoundTripper`.
+type RoundTripFunc func(req *http.Request) (*http.Response, error)
+
+// RoundTrip implements `http.RoundTripper`.
+func (f RoundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// A Middleware wraps the sending of requests made by the client. It can
+// observe or modify a request before passing it on to the next `RoundTripFunc`
+// and observe or modify the response before returning it. A middleware must
+// not retain the request or response beyond the call. The logical operation a
+// request belongs to (e.g. "Datasets.Ingest") can be retrieved from the
+// requests context using `OperationFromContext()`.
+//
+// Middleware is invoked for every attempt to send a request, including
+// retries. The number of the attempt can be retrieved using
+// `AttemptFromContext()`. Use `SetMiddleware()` to register a middleware with
+// the client.
+type Middleware func(next RoundTripFunc) RoundTripFunc
+
+type (
+	operationContextKey struct{}
+	attemptContextKey   struct{}
+)
+
+// OperationFromContext returns the name of the logical operation the request
+// using the given context belongs to. The name is composed of the service and
+// method name of the client, e.g. "Datasets.Ingest" or "Tokens.API.Create".
+// If an operation is composed of other operations (e.g.
+// "Datasets.IngestReader" which uses "Datasets.Ingest"), the name of the
+// outermost one is returned. It returns an empty string, if the context isn't
+// associated with an operation.
+func OperationFromContext(ctx context.Context) string {
+	op, _ := ctx.Value(operationContextKey{}).(string)
+	return op
+}
+
+// withOperation returns a copy of the given context that is associated with
+// the operation of the given name, unless it already is associated with an
+// operation.
+func withOperation(ctx context.Context, op string) context.Context {
+	if OperationFromContext(ctx) != "" {
+		return ctx
+	}
+	return context.WithValue(ctx, operationContextKey{}, op)
+}
+
+// AttemptFromContext returns the number of the attempt to send the request
+// using the given context, starting at one for the initial attempt. Retries of
+// a request, as configured by `SetRetryPolicy()`, increment it. It returns
+// zero, if the context isn't associated with a request sent by the client.
+func AttemptFromContext(ctx context.Context) int {
+	attempt, _ := ctx.Value(attemptContextKey{}).(int)
+	return attempt
+}
+
+// withAttempt returns a shallow copy of the given request whose context is
+// associated with the given attempt.
+func withAttempt(req *http.Request, attempt int) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), attemptContextKey{}, attempt))
+}
+
+// operation returns the name of the operation of the given method of the
+// token service, depending on the kind of tokens it manages.
+func (s *tokensService) operation(method string) string {
+	kind := "API"
+	if strings.HasSuffix(s.basePath, "/"+personalTokenStr) {
+		kind = "Personal"
+	}
+	return "Tokens." + kind + "." + method
+}
+
+// roundTrip sends the given request using the http client of the client,
+// wrapped by the registered middleware.
+func (c *Client) roundTrip(req *http.Request) (*http.Response, error) {
+	rt := RoundTripFunc(c.httpClient.Do)
+	for i := len(c.middleware) - 1; i >= 0; i-- {
+		rt = c.middleware[i](rt)
+	}
+	return rt(req)
+}
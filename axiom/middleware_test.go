@@ -0,0 +1,127 @@
+package axiom
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_Middleware(t *testing.T) {
+	var calls []string
+	record := func(name string) Middleware {
+		return func(next RoundTripFunc) RoundTripFunc {
+			return func(req *http.Request) (*http.Response, error) {
+				calls = append(calls, name+":"+OperationFromContext(req.Context()))
+				assert.Equal(t, 1, AttemptFromContext(req.Context()))
+				req.Header.Add("X-Middleware", name)
+				resp, err := next(req)
+				calls = append(calls, name+":done")
+				return resp, err
+			}
+		}
+	}
+
+	hf := func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, []string{"first", "second"}, r.Header.Values("X-Middleware"))
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, "[]")
+	}
+
+	client, teardown := setup(t, "/api/v1/tokens/api", hf)
+	defer teardown()
+
+	err := client.Options(SetMiddleware(record("first"), record("second")))
+	require.NoError(t, err)
+
+	_, err = client.Tokens.API.List(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"first:Tokens.API.List",
+		"second:Tokens.API.List",
+		"second:done",
+		"first:done",
+	}, calls)
+}
+
+func TestClient_Middleware_Operation(t *testing.T) {
+	var op string
+	hf := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, "{}")
+	}
+
+	client, teardown := setup(t, "/api/v1/datasets/test/ingest", hf)
+	defer teardown()
+
+	err := client.Options(SetMiddleware(func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			op = OperationFromContext(req.Context())
+			return next(req)
+		}
+	}))
+	require.NoError(t, err)
+
+	r := strings.NewReader(`{"foo":"bar"}`)
+	_, err = client.Datasets.Ingest(context.Background(), "test", r, JSON, Identity, IngestOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "Datasets.Ingest", op)
+
+	// The outermost operation is reported.
+	r = strings.NewReader(`{"foo":"bar"}`)
+	_, err = client.Datasets.IngestReader(context.Background(), "test", r, IngestOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "Datasets.IngestReader", op)
+}
+
+func TestClient_Middleware_Retry(t *testing.T) {
+	var hits uint64
+	hf := func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddUint64(&hits, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, "[]")
+	}
+
+	client, teardown := setup(t, "/api/v1/tokens/personal", hf)
+	defer teardown()
+
+	var attempts int
+	err := client.Options(
+		SetRetryPolicy(testRetryPolicy),
+		SetMiddleware(func(next RoundTripFunc) RoundTripFunc {
+			return func(req *http.Request) (*http.Response, error) {
+				attempts++
+				assert.Equal(t, "Tokens.Personal.List", OperationFromContext(req.Context()))
+				assert.Equal(t, attempts, AttemptFromContext(req.Context()))
+				return next(req)
+			}
+		}),
+	)
+	require.NoError(t, err)
+
+	_, err = client.Tokens.Personal.List(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, attempts)
+}
+
+func TestOperationFromContext(t *testing.T) {
+	ctx := context.Background()
+	assert.Empty(t, OperationFromContext(ctx))
+
+	ctx = withOperation(ctx, "Datasets.IngestReader")
+	assert.Equal(t, "Datasets.IngestReader", OperationFromContext(ctx))
+
+	ctx = withOperation(ctx, "Datasets.Ingest")
+	assert.Equal(t, "Datasets.IngestReader", OperationFromContext(ctx))
+}
@@ -151,6 +151,8 @@ type MonitorsService service
 
 // List all available monitors.
 func (s *MonitorsService) List(ctx context.Context) ([]*Monitor, error) {
+	ctx = withOperation(ctx, "Monitors.List")
+
 	var res []*Monitor
 	if err := s.client.call(ctx, http.MethodGet, s.basePath, nil, &res); err != nil {
 		return nil, err
@@ -161,6 +163,8 @@ func (s *MonitorsService) List(ctx context.Context) ([]*Monitor, error) {
 
 // Get a monitor by id.
 func (s *MonitorsService) Get(ctx context.Context, id string) (*Monitor, error) {
+	ctx = withOperation(ctx, "Monitors.Get")
+
 	path := s.basePath + "/" + id
 
 	var res Monitor
@@ -173,6 +177,8 @@ func (s *MonitorsService) Get(ctx context.Context, id string) (*Monitor, error)
 
 // Create a monitor with the given properties.
 func (s *MonitorsService) Create(ctx context.Context, req Monitor) (*Monitor, error) {
+	ctx = withOperation(ctx, "Monitors.Create")
+
 	var res Monitor
 	if err := s.client.call(ctx, http.MethodPost, s.basePath, req, &res); err != nil {
 		return nil, err
@@ -183,6 +189,8 @@ func (s *MonitorsService) Create(ctx context.Context, req Monitor) (*Monitor, er
 
 // Update the monitor identified by the given id with the given properties.
 func (s *MonitorsService) Update(ctx context.Context, id string, req Monitor) (*Monitor, error) {
+	ctx = withOperation(ctx, "Monitors.Update")
+
 	path := s.basePath + "/" + id
 
 	var res Monitor
@@ -195,5 +203,7 @@ func (s *MonitorsService) Update(ctx context.Context, id string, req Monitor) (*
 
 // Delete the monitor identified by the given id.
 func (s *MonitorsService) Delete(ctx context.Context, id string) error {
+	ctx = withOperation(ctx, "Monitors.Delete")
+
 	return s.client.call(ctx, http.MethodDelete, s.basePath+"/"+id, nil, nil)
 }
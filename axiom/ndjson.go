@@ -0,0 +1,302 @@
+package axiom
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// flushThreshold is the size of encoded events that is buffered before it is
+// written to the underlying writer.
+const flushThreshold = 32 << 10 // 32 KiB
+
+var ndjsonBufferPool = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, 0, 2*flushThreshold)
+		return &b
+	},
+}
+
+func getBuffer() *[]byte {
+	return ndjsonBufferPool.Get().(*[]byte)
+}
+
+func putBuffer(bp *[]byte) {
+	// Don't keep exceptionally large buffers around.
+	if cap(*bp) <= 4*flushThreshold {
+		*bp = (*bp)[:0]
+		ndjsonBufferPool.Put(bp)
+	}
+}
+
+// eventEncoder appends the JSON representation of the i-th event of a batch
+// to the buffer.
+type eventEncoder func(buf []byte, i int) ([]byte, error)
+
+// eventsEncoder returns an eventEncoder for the given events.
+func eventsEncoder(events []Event) eventEncoder {
+	return func(buf []byte, i int) ([]byte, error) {
+		return appendEvent(buf, events[i])
+	}
+}
+
+// appendNDJSON appends the events [i, n) of a batch as newline delimited JSON
+// to the buffer until it holds at least limit bytes. It returns the extended
+// buffer and the index of the next event to encode.
+func appendNDJSON(buf []byte, i, n, limit int, encode eventEncoder) ([]byte, int, error) {
+	var err error
+	for ; i < n && len(buf) < limit; i++ {
+		if buf, err = encode(buf, i); err != nil {
+			return buf, i, fmt.Errorf("event %d: %w", i, err)
+		}
+		buf = append(buf, '\n')
+	}
+	return buf, i, nil
+}
+
+// writeNDJSON writes the given buffer followed by the events [i, n) of a batch
+// as newline delimited JSON to the given writer. Encoded events are collected
+// in the buffer and written in chunks of roughly `flushThreshold` bytes. The
+// buffer is returned for reuse.
+func writeNDJSON(w io.Writer, buf []byte, i, n int, encode eventEncoder) ([]byte, error) {
+	for {
+		var err error
+		if buf, i, err = appendNDJSON(buf, i, n, flushThreshold, encode); err != nil {
+			return buf, err
+		} else if len(buf) == 0 {
+			return buf, nil
+		}
+
+		if _, err = w.Write(buf); err != nil {
+			return buf, err
+		}
+		buf = buf[:0]
+	}
+}
+
+// writeEvents writes the given events as newline delimited JSON to the given
+// writer.
+func writeEvents(w io.Writer, events []Event) error {
+	bp := getBuffer()
+	defer putBuffer(bp)
+
+	var err error
+	*bp, err = writeNDJSON(w, (*bp)[:0], 0, len(events), eventsEncoder(events))
+
+	return err
+}
+
+// appendEvent appends the JSON representation of the given event to the
+// buffer. Common value types are encoded without allocating, all others are
+// encoded using `json.Marshal()`. Unlike `encoding/json`, keys are not sorted.
+func appendEvent(buf []byte, event Event) ([]byte, error) {
+	return appendObject(buf, event)
+}
+
+func appendObject(buf []byte, m map[string]interface{}) ([]byte, error) {
+	if m == nil {
+		return append(buf, "null"...), nil
+	}
+
+	buf = append(buf, '{')
+	first := true
+	for k, v := range m {
+		if !first {
+			buf = append(buf, ',')
+		}
+		first = false
+
+		buf = appendString(buf, k)
+		buf = append(buf, ':')
+
+		var err error
+		if buf, err = appendValue(buf, v); err != nil {
+			return buf, err
+		}
+	}
+	return append(buf, '}'), nil
+}
+
+// appendValue appends the JSON representation of the given value to the
+// buffer.
+func appendValue(buf []byte, v interface{}) ([]byte, error) {
+	switch v := v.(type) {
+	case nil:
+		return append(buf, "null"...), nil
+	case string:
+		return appendString(buf, v), nil
+	case bool:
+		return strconv.AppendBool(buf, v), nil
+	case int:
+		return strconv.AppendInt(buf, int64(v), 10), nil
+	case int8:
+		return strconv.AppendInt(buf, int64(v), 10), nil
+	case int16:
+		return strconv.AppendInt(buf, int64(v), 10), nil
+	case int32:
+		return strconv.AppendInt(buf, int64(v), 10), nil
+	case int64:
+		return strconv.AppendInt(buf, v, 10), nil
+	case uint:
+		return strconv.AppendUint(buf, uint64(v), 10), nil
+	case uint8:
+		return strconv.AppendUint(buf, uint64(v), 10), nil
+	case uint16:
+		return strconv.AppendUint(buf, uint64(v), 10), nil
+	case uint32:
+		return strconv.AppendUint(buf, uint64(v), 10), nil
+	case uint64:
+		return strconv.AppendUint(buf, v, 10), nil
+	case float32:
+		return appendFloat(buf, float64(v), 32)
+	case float64:
+		return appendFloat(buf, v, 64)
+	case json.Number:
+		if v == "" {
+			return append(buf, '0'), nil
+		}
+		return append(buf, v...), nil
+	case time.Time:
+		if y := v.Year(); y < 0 || y >= 10000 {
+			return buf, fmt.Errorf("time %s: year outside of range [0,9999]", v)
+		}
+		buf = append(buf, '"')
+		buf = v.AppendFormat(buf, time.RFC3339Nano)
+		return append(buf, '"'), nil
+	case time.Duration:
+		return strconv.AppendInt(buf, int64(v), 10), nil
+	case []byte:
+		if v == nil {
+			return append(buf, "null"...), nil
+		}
+		buf = append(buf, '"')
+		n := len(buf)
+		buf = append(buf, make([]byte, base64.StdEncoding.EncodedLen(len(v)))...)
+		base64.StdEncoding.Encode(buf[n:], v)
+		return append(buf, '"'), nil
+	case Event:
+		return appendObject(buf, v)
+	case map[string]interface{}:
+		return appendObject(buf, v)
+	case []interface{}:
+		if v == nil {
+			return append(buf, "null"...), nil
+		}
+		buf = append(buf, '[')
+		for i, elem := range v {
+			if i > 0 {
+				buf = append(buf, ',')
+			}
+			var err error
+			if buf, err = appendValue(buf, elem); err != nil {
+				return buf, err
+			}
+		}
+		return append(buf, ']'), nil
+	case []string:
+		if v == nil {
+			return append(buf, "null"...), nil
+		}
+		buf = append(buf, '[')
+		for i, elem := range v {
+			if i > 0 {
+				buf = append(buf, ',')
+			}
+			buf = appendString(buf, elem)
+		}
+		return append(buf, ']'), nil
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return buf, err
+	}
+	return append(buf, b...), nil
+}
+
+// appendFloat appends the given float the same way `encoding/json` does.
+func appendFloat(buf []byte, f float64, bits int) ([]byte, error) {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return buf, fmt.Errorf("unsupported float value %s", strconv.FormatFloat(f, 'g', -1, bits))
+	}
+
+	// Use exponent notation for very large and very small numbers, just like
+	// ES6 and `encoding/json`.
+	format := byte('f')
+	if abs := math.Abs(f); abs != 0 {
+		if bits == 64 && (abs < 1e-6 || abs >= 1e21) || bits == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) {
+			format = 'e'
+		}
+	}
+
+	buf = strconv.AppendFloat(buf, f, format, -1, bits)
+	if format == 'e' {
+		// Clean up e-09 to e-9.
+		if n := len(buf); n >= 4 && buf[n-4] == 'e' && buf[n-3] == '-' && buf[n-2] == '0' {
+			buf[n-2] = buf[n-1]
+			buf = buf[:n-1]
+		}
+	}
+
+	return buf, nil
+}
+
+const hex = "0123456789abcdef"
+
+// appendString appends the given string as a quoted JSON string, escaping it
+// the same way `encoding/json` does.
+func appendString(buf []byte, s string) []byte {
+	buf = append(buf, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		if b := s[i]; b < utf8.RuneSelf {
+			if b >= 0x20 && b != '"' && b != '\\' && b != '<' && b != '>' && b != '&' {
+				i++
+				continue
+			}
+			buf = append(buf, s[start:i]...)
+			switch b {
+			case '\\', '"':
+				buf = append(buf, '\\', b)
+			case '\n':
+				buf = append(buf, '\\', 'n')
+			case '\r':
+				buf = append(buf, '\\', 'r')
+			case '\t':
+				buf = append(buf, '\\', 't')
+			default:
+				// Control characters and HTML special characters.
+				buf = append(buf, '\\', 'u', '0', '0', hex[b>>4], hex[b&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+
+		c, size := utf8.DecodeRuneInString(s[i:])
+		if c == utf8.RuneError && size == 1 {
+			buf = append(buf, s[start:i]...)
+			buf = append(buf, `\ufffd`...)
+			i += size
+			start = i
+			continue
+		}
+		// U+2028 and U+2029 are valid JSON but break JSONP.
+		if c == '\u2028' || c == '\u2029' {
+			buf = append(buf, s[start:i]...)
+			buf = append(buf, '\\', 'u', '2', '0', '2', hex[c&0xF])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	buf = append(buf, s[start:]...)
+	return append(buf, '"')
+}
@@ -0,0 +1,120 @@
+package axiom
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppendValue(t *testing.T) {
+	type custom struct {
+		A string `json:"a"`
+	}
+
+	now := time.Now()
+
+	tests := []interface{}{
+		nil,
+		"foo",
+		"quote \" backslash \\ newline \n tab \t cr \r",
+		"html <script>&</script>",
+		"control \x00\x01\x1f",
+		"unicode äöü 世界 \u2028\u2029",
+		"invalid \xff utf8",
+		true,
+		false,
+		int(-1), int8(-8), int16(-16), int32(-32), int64(math.MinInt64),
+		uint(1), uint8(8), uint16(16), uint32(32), uint64(math.MaxUint64),
+		float32(1.5), float32(1e-7), float32(3.4e38),
+		float64(0), float64(-0.1), float64(1e21), float64(1e-7), float64(123456789.123),
+		json.Number("42.5"),
+		now,
+		time.Second,
+		[]byte("bytes"),
+		[]byte(nil),
+		[]string{"a", "b"},
+		[]interface{}{"a", 1, true, nil, map[string]interface{}{"b": 2.5}},
+		map[string]interface{}{"nested": Event{"deep": "value"}},
+		Event{"a": 1},
+		custom{A: "custom"},
+		[]int{1, 2, 3},
+	}
+	for _, tt := range tests {
+		exp, err := json.Marshal(tt)
+		require.NoError(t, err)
+
+		act, err := appendValue(nil, tt)
+		require.NoError(t, err)
+
+		assert.JSONEq(t, string(exp), string(act), "%#v", tt)
+		if _, isMap := tt.(map[string]interface{}); !isMap {
+			// Only maps with multiple keys can differ in key order.
+			assert.Equal(t, string(exp), string(act), "%#v", tt)
+		}
+	}
+}
+
+func TestAppendValue_Invalid(t *testing.T) {
+	tests := []interface{}{
+		math.NaN(),
+		math.Inf(1),
+		float32(math.Inf(-1)),
+		time.Date(10000, 1, 1, 0, 0, 0, 0, time.UTC),
+		make(chan int),
+	}
+	for _, tt := range tests {
+		_, err := appendValue(nil, tt)
+		assert.Error(t, err, "%#v", tt)
+	}
+}
+
+func TestAppendEvent_Allocations(t *testing.T) {
+	event := Event{
+		"time":     time.Now(),
+		"message":  "some message <with> special & characters",
+		"status":   200,
+		"duration": 1.5,
+		"success":  true,
+		"tags":     []string{"a", "b"},
+		"nested":   map[string]interface{}{"key": "value", "count": int64(1)},
+	}
+
+	buf := make([]byte, 0, 4096)
+	allocs := testing.AllocsPerRun(100, func() {
+		var err error
+		if buf, err = appendEvent(buf[:0], event); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	assert.Zero(t, allocs)
+}
+
+func TestWriteEvents(t *testing.T) {
+	events := make([]Event, 2000)
+	for i := range events {
+		events[i] = Event{
+			"index":   i,
+			"message": strings.Repeat("x", 32),
+		}
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, writeEvents(&buf, events))
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	require.Len(t, lines, len(events))
+	for i, line := range lines {
+		var event Event
+		require.NoError(t, json.Unmarshal([]byte(line), &event))
+		assert.EqualValues(t, i, event["index"])
+	}
+
+	assert.Error(t, writeEvents(&buf, []Event{{"invalid": math.NaN()}}))
+}
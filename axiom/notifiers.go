@@ -90,6 +90,8 @@ type NotifiersService service
 
 // List all available notifiers.
 func (s *NotifiersService) List(ctx context.Context) ([]*Notifier, error) {
+	ctx = withOperation(ctx, "Notifiers.List")
+
 	var res []*Notifier
 	if err := s.client.call(ctx, http.MethodGet, s.basePath, nil, &res); err != nil {
 		return nil, err
@@ -100,6 +102,8 @@ func (s *NotifiersService) List(ctx context.Context) ([]*Notifier, error) {
 
 // Get a notifier by id.
 func (s *NotifiersService) Get(ctx context.Context, id string) (*Notifier, error) {
+	ctx = withOperation(ctx, "Notifiers.Get")
+
 	path := s.basePath + "/" + id
 
 	var res Notifier
@@ -112,6 +116,8 @@ func (s *NotifiersService) Get(ctx context.Context, id string) (*Notifier, error
 
 // Create a notifier with the given properties.
 func (s *NotifiersService) Create(ctx context.Context, req Notifier) (*Notifier, error) {
+	ctx = withOperation(ctx, "Notifiers.Create")
+
 	var res Notifier
 	if err := s.client.call(ctx, http.MethodPost, s.basePath, req, &res); err != nil {
 		return nil, err
@@ -122,6 +128,8 @@ func (s *NotifiersService) Create(ctx context.Context, req Notifier) (*Notifier,
 
 // Update the notifier identified by the given id with the given properties.
 func (s *NotifiersService) Update(ctx context.Context, id string, req Notifier) (*Notifier, error) {
+	ctx = withOperation(ctx, "Notifiers.Update")
+
 	path := s.basePath + "/" + id
 
 	var res Notifier
@@ -134,5 +142,7 @@ func (s *NotifiersService) Update(ctx context.Context, id string, req Notifier)
 
 // Delete the notifier identified by the given id.
 func (s *NotifiersService) Delete(ctx context.Context, id string) error {
+	ctx = withOperation(ctx, "Notifiers.Delete")
+
 	return s.client.call(ctx, http.MethodDelete, s.basePath+"/"+id, nil, nil)
 }
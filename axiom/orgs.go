@@ -219,6 +219,8 @@ type OrganizationsService service
 
 // List all available organizations.
 func (s *OrganizationsService) List(ctx context.Context) ([]*Organization, error) {
+	ctx = withOperation(ctx, "Organizations.List")
+
 	var res []*Organization
 	if err := s.client.call(ctx, http.MethodGet, s.basePath, nil, &res); err != nil {
 		return nil, err
@@ -229,6 +231,8 @@ func (s *OrganizationsService) List(ctx context.Context) ([]*Organization, error
 
 // Get an organization by id.
 func (s *OrganizationsService) Get(ctx context.Context, id string) (*Organization, error) {
+	ctx = withOperation(ctx, "Organizations.Get")
+
 	path := s.basePath + "/" + id
 
 	var res Organization
@@ -241,6 +245,8 @@ func (s *OrganizationsService) Get(ctx context.Context, id string) (*Organizatio
 
 // Update the organization identified by the given id with the given properties.
 func (s *OrganizationsService) Update(ctx context.Context, id string, req OrganizationCreateUpdateRequest) (*Organization, error) {
+	ctx = withOperation(ctx, "Organizations.Update")
+
 	path := s.basePath + "/" + id
 
 	var res Organization
@@ -253,6 +259,8 @@ func (s *OrganizationsService) Update(ctx context.Context, id string, req Organi
 
 // License gets an organizations license.
 func (s *OrganizationsService) License(ctx context.Context, id string) (*License, error) {
+	ctx = withOperation(ctx, "Organizations.License")
+
 	path := s.basePath + "/" + id + "/license"
 
 	var res License
@@ -265,6 +273,8 @@ func (s *OrganizationsService) License(ctx context.Context, id string) (*License
 
 // Status gets an organizations status.
 func (s *OrganizationsService) Status(ctx context.Context, id string) (*Status, error) {
+	ctx = withOperation(ctx, "Organizations.Status")
+
 	path := s.basePath + "/" + id + "/status"
 
 	var res Status
@@ -288,6 +298,8 @@ type CloudOrganizationsService struct {
 // ViewSharedAccessKeys rotates the shared access signing keys for the
 // organization identified by the given id.
 func (s *CloudOrganizationsService) ViewSharedAccessKeys(ctx context.Context, id string) (*SharedAccessKeys, error) {
+	ctx = withOperation(ctx, "Organizations.ViewSharedAccessKeys")
+
 	path := s.basePath + "/" + id + "/keys"
 
 	var res SharedAccessKeys
@@ -301,6 +313,8 @@ func (s *CloudOrganizationsService) ViewSharedAccessKeys(ctx context.Context, id
 // RotateSharedAccessKeys rotates the shared access signing keys for the
 // organization identified by the given id.
 func (s *CloudOrganizationsService) RotateSharedAccessKeys(ctx context.Context, id string) (*SharedAccessKeys, error) {
+	ctx = withOperation(ctx, "Organizations.RotateSharedAccessKeys")
+
 	path := s.basePath + "/" + id + "/rotate-keys"
 
 	var res SharedAccessKeys
@@ -313,6 +327,8 @@ func (s *CloudOrganizationsService) RotateSharedAccessKeys(ctx context.Context,
 
 // Create an organization with the given properties.
 func (s *CloudOrganizationsService) Create(ctx context.Context, req OrganizationCreateUpdateRequest) (*Organization, error) {
+	ctx = withOperation(ctx, "Organizations.Create")
+
 	var res Organization
 	if err := s.client.call(ctx, http.MethodPost, s.basePath, req, &res); err != nil {
 		return nil, err
@@ -323,5 +339,7 @@ func (s *CloudOrganizationsService) Create(ctx context.Context, req Organization
 
 // Delete the organization identified by the given id.
 func (s *CloudOrganizationsService) Delete(ctx context.Context, id string) error {
+	ctx = withOperation(ctx, "Organizations.Delete")
+
 	return s.client.call(ctx, http.MethodDelete, s.basePath+"/"+id, nil, nil)
 }
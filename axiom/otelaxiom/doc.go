@@ -0,0 +1,33 @@
+// Package otelaxiom provides OpenTelemetry instrumentation for the
+// `axiom.Client`.
+//
+// The instrumentation is implemented as an `axiom.Middleware` and is
+// registered with a client using the `Instrument()` option:
+//
+//	client, err := axiom.NewClient(
+//		otelaxiom.Instrument(),
+//	)
+//
+// Every attempt to send a request creates a span named after the logical
+// operation it belongs to, e.g. "Datasets.Ingest" or "Datasets.Query". Spans
+// carry the HTTP method, URL and status code, the dataset a request targets,
+// the attempt number and, if applicable, the amount of rows examined and
+// matched by a query or the amount of events ingested and failed to ingest.
+// A request that is retried thus results in one span per attempt. These spans
+// are siblings sharing the parent span of the context the operation was
+// called with and can be told apart by their `axiom.attempt` attribute.
+// The trace context is propagated to the server using the configured
+// propagator.
+//
+// The following metrics are recorded, all of them carrying the operation name
+// and, if applicable, the dataset as attributes:
+//
+//   - axiom.client.duration: Duration of a request attempt in milliseconds.
+//   - axiom.client.request.size: Size of a request payload in bytes.
+//   - axiom.client.retries: Amount of retried request attempts.
+//   - axiom.client.events.ingested: Amount of events ingested.
+//   - axiom.client.events.failed: Amount of events that failed to ingest.
+//
+// By default, the global tracer provider, meter provider and propagator are
+// used.
+package otelaxiom
@@ -0,0 +1,327 @@
+package otelaxiom
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/global"
+	"go.opentelemetry.io/otel/metric/unit"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/axiomhq/axiom-go/axiom"
+)
+
+const instrumentationName = "github.com/axiomhq/axiom-go/axiom/otelaxiom"
+
+// Attribute keys set on the spans and metrics recorded by the
+// instrumentation.
+const (
+	// OperationKey is the name of the logical operation a request belongs to,
+	// e.g. "Datasets.Ingest".
+	OperationKey = attribute.Key("axiom.operation")
+	// DatasetKey is the name of the dataset a request targets.
+	DatasetKey = attribute.Key("axiom.dataset")
+	// AttemptKey is the number of the attempt to send a request, starting at
+	// one.
+	AttemptKey = attribute.Key("axiom.attempt")
+	// RowsExaminedKey is the amount of rows examined by a query.
+	RowsExaminedKey = attribute.Key("axiom.query.rows_examined")
+	// RowsMatchedKey is the amount of rows matched by a query.
+	RowsMatchedKey = attribute.Key("axiom.query.rows_matched")
+	// IngestedKey is the amount of events ingested.
+	IngestedKey = attribute.Key("axiom.ingest.ingested")
+	// FailedKey is the amount of events that failed to ingest.
+	FailedKey = attribute.Key("axiom.ingest.failed")
+)
+
+// An Option modifies the behaviour of the instrumentation.
+type Option func(*config) error
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+	propagator     propagation.TextMapPropagator
+}
+
+// SetMeterProvider specifies the meter provider used to create the metric
+// instruments. Defaults to the global meter provider.
+func SetMeterProvider(meterProvider metric.MeterProvider) Option {
+	return func(cfg *config) error {
+		cfg.meterProvider = meterProvider
+		return nil
+	}
+}
+
+// SetPropagator specifies the propagator used to propagate the trace context
+// to the server. Defaults to the global propagator.
+func SetPropagator(propagator propagation.TextMapPropagator) Option {
+	return func(cfg *config) error {
+		cfg.propagator = propagator
+		return nil
+	}
+}
+
+// SetTracerProvider specifies the tracer provider used to create the spans.
+// Defaults to the global tracer provider.
+func SetTracerProvider(tracerProvider trace.TracerProvider) Option {
+	return func(cfg *config) error {
+		cfg.tracerProvider = tracerProvider
+		return nil
+	}
+}
+
+// Instrument returns an `axiom.Option` which registers the instrumentation as
+// middleware of the client.
+func Instrument(options ...Option) axiom.Option {
+	return func(c *axiom.Client) error {
+		mw, err := Middleware(options...)
+		if err != nil {
+			return err
+		}
+		return c.Options(axiom.SetMiddleware(mw))
+	}
+}
+
+// Middleware returns the instrumentation as `axiom.Middleware`. Prefer
+// `Instrument()` unless the order of the middleware registered with the client
+// matters. Like any middleware, it is invoked for every attempt to send a
+// request and creates one span per attempt.
+func Middleware(options ...Option) (axiom.Middleware, error) {
+	cfg := config{
+		tracerProvider: otel.GetTracerProvider(),
+		meterProvider:  global.GetMeterProvider(),
+		propagator:     otel.GetTextMapPropagator(),
+	}
+	for _, option := range options {
+		if option == nil {
+			continue
+		} else if err := option(&cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	inst := &instrumentation{
+		tracer:     cfg.tracerProvider.Tracer(instrumentationName),
+		propagator: cfg.propagator,
+	}
+
+	var (
+		meter = cfg.meterProvider.Meter(instrumentationName)
+		err   error
+	)
+	if inst.duration, err = meter.NewFloat64Histogram("axiom.client.duration",
+		metric.WithDescription("Duration of a request attempt"),
+		metric.WithUnit(unit.Milliseconds),
+	); err != nil {
+		return nil, err
+	}
+	if inst.requestSize, err = meter.NewInt64Histogram("axiom.client.request.size",
+		metric.WithDescription("Size of a request payload"),
+		metric.WithUnit(unit.Bytes),
+	); err != nil {
+		return nil, err
+	}
+	if inst.retries, err = meter.NewInt64Counter("axiom.client.retries",
+		metric.WithDescription("Amount of retried request attempts"),
+	); err != nil {
+		return nil, err
+	}
+	if inst.ingested, err = meter.NewInt64Counter("axiom.client.events.ingested",
+		metric.WithDescription("Amount of events ingested"),
+	); err != nil {
+		return nil, err
+	}
+	if inst.failed, err = meter.NewInt64Counter("axiom.client.events.failed",
+		metric.WithDescription("Amount of events that failed to ingest"),
+	); err != nil {
+		return nil, err
+	}
+
+	return inst.middleware, nil
+}
+
+type instrumentation struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+
+	duration    metric.Float64Histogram
+	requestSize metric.Int64Histogram
+	retries     metric.Int64Counter
+	ingested    metric.Int64Counter
+	failed      metric.Int64Counter
+}
+
+func (inst *instrumentation) middleware(next axiom.RoundTripFunc) axiom.RoundTripFunc {
+	return func(req *http.Request) (*http.Response, error) {
+		ctx := req.Context()
+
+		op := axiom.OperationFromContext(ctx)
+		if op == "" {
+			op = "HTTP " + req.Method
+		}
+		attrs := []attribute.KeyValue{OperationKey.String(op)}
+		if dataset := datasetFromPath(req.URL.Path); dataset != "" {
+			attrs = append(attrs, DatasetKey.String(dataset))
+		}
+
+		attempt := axiom.AttemptFromContext(ctx)
+		ctx, span := inst.tracer.Start(ctx, op,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(attrs...),
+			trace.WithAttributes(
+				semconv.HTTPMethodKey.String(req.Method),
+				semconv.HTTPURLKey.String(req.URL.String()),
+				AttemptKey.Int(attempt),
+			),
+		)
+		defer span.End()
+
+		if attempt > 1 {
+			inst.retries.Add(ctx, 1, attrs...)
+		}
+
+		// Requests passed to the middleware must not be modified, so the trace
+		// context is injected into a copy.
+		r := req.WithContext(ctx)
+		r.Header = req.Header.Clone()
+		inst.propagator.Inject(ctx, propagation.HeaderCarrier(r.Header))
+
+		var body *countingReadCloser
+		if req.Body != nil && req.Body != http.NoBody {
+			body = &countingReadCloser{ReadCloser: req.Body}
+			r.Body = body
+		}
+
+		start := time.Now()
+		resp, err := next(r)
+		inst.duration.Record(ctx, float64(time.Since(start))/float64(time.Millisecond), attrs...)
+
+		if body != nil {
+			n := body.count()
+			inst.requestSize.Record(ctx, n, attrs...)
+			span.SetAttributes(semconv.HTTPRequestContentLengthKey.Int64(n))
+		}
+
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return nil, err
+		}
+
+		span.SetAttributes(semconv.HTTPStatusCodeKey.Int(resp.StatusCode))
+		if resp.StatusCode >= 400 {
+			span.SetStatus(codes.Error, http.StatusText(resp.StatusCode))
+			return resp, nil
+		}
+
+		if err = inst.recordResult(req, resp, span, attrs); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return nil, err
+		}
+
+		return resp, nil
+	}
+}
+
+// recordResult records the status of an ingestion or query reported by the
+// server. The response body is buffered and replaced, so it can still be read
+// by the client.
+func (inst *instrumentation) recordResult(req *http.Request, resp *http.Response, span trace.Span, attrs []attribute.KeyValue) error {
+	path := req.URL.Path
+	isIngest := strings.HasSuffix(path, "/ingest")
+	isQuery := strings.HasSuffix(path, "/query") || strings.HasSuffix(path, "/_apl")
+	if (!isIngest && !isQuery) || !strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		return nil
+	}
+
+	b, err := io.ReadAll(resp.Body)
+	if closeErr := resp.Body.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(b))
+
+	var res struct {
+		Ingested int64 `json:"ingested"`
+		Failed   int64 `json:"failed"`
+		Status   struct {
+			RowsExamined int64 `json:"rowsExamined"`
+			RowsMatched  int64 `json:"rowsMatched"`
+		} `json:"status"`
+	}
+	if json.Unmarshal(b, &res) != nil {
+		// The client reports malformed responses.
+		return nil
+	}
+
+	ctx := req.Context()
+	if isIngest {
+		inst.ingested.Add(ctx, res.Ingested, attrs...)
+		inst.failed.Add(ctx, res.Failed, attrs...)
+		span.SetAttributes(
+			IngestedKey.Int64(res.Ingested),
+			FailedKey.Int64(res.Failed),
+		)
+	} else {
+		span.SetAttributes(
+			RowsExaminedKey.Int64(res.Status.RowsExamined),
+			RowsMatchedKey.Int64(res.Status.RowsMatched),
+		)
+	}
+
+	return nil
+}
+
+const datasetsPath = "/api/v1/datasets/"
+
+// datasetFromPath returns the name of the dataset the given request path
+// targets. It returns an empty string, if the path doesn't target a specific
+// dataset.
+func datasetFromPath(path string) string {
+	i := strings.Index(path, datasetsPath)
+	if i < 0 {
+		return ""
+	}
+
+	dataset := path[i+len(datasetsPath):]
+	if j := strings.IndexByte(dataset, '/'); j >= 0 {
+		dataset = dataset[:j]
+	}
+	if strings.HasPrefix(dataset, "_") {
+		return ""
+	}
+	return dataset
+}
+
+// countingReadCloser counts the bytes read from the underlying reader. The
+// body of a request might be read by the transport after the response has been
+// returned, so the count is accessed atomically.
+type countingReadCloser struct {
+	io.ReadCloser
+
+	n int64
+}
+
+func (r *countingReadCloser) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	atomic.AddInt64(&r.n, int64(n))
+	return n, err
+}
+
+func (r *countingReadCloser) count() int64 {
+	return atomic.LoadInt64(&r.n)
+}
@@ -0,0 +1,201 @@
+package otelaxiom
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric/metrictest"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+
+	"github.com/axiomhq/axiom-go/axiom"
+	"github.com/axiomhq/axiom-go/axiom/axiomtest"
+	"github.com/axiomhq/axiom-go/axiom/query"
+)
+
+func TestInstrument(t *testing.T) {
+	srv := axiomtest.NewServer()
+	defer srv.Close()
+
+	srv.CreateDataset("test")
+
+	sr := tracetest.NewSpanRecorder()
+	mp := metrictest.NewMeterProvider()
+
+	client, err := srv.Client(Instrument(
+		SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))),
+		SetMeterProvider(mp),
+	))
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	status, err := client.Datasets.IngestEvents(ctx, "test", axiom.IngestOptions{},
+		axiom.Event{"foo": "bar"},
+		axiom.Event{"foo": "baz"},
+	)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, status.Ingested)
+
+	res, err := client.Datasets.Query(ctx, "test", query.Query{
+		StartTime: time.Now().Add(-time.Hour),
+		EndTime:   time.Now().Add(time.Hour),
+		Filter: query.Filter{
+			Op:    query.OpEqual,
+			Field: "foo",
+			Value: "bar",
+		},
+	}, query.Options{})
+	require.NoError(t, err)
+	assert.Len(t, res.Matches, 1)
+
+	spans := sr.Ended()
+	require.Len(t, spans, 2)
+
+	assert.Equal(t, "Datasets.IngestEvents", spans[0].Name())
+	assert.Equal(t, "Datasets.Query", spans[1].Name())
+	for _, span := range spans {
+		attrs := attribute.NewSet(span.Attributes()...)
+		assert.Equal(t, codes.Unset, span.Status().Code)
+		assertAttribute(t, attrs, DatasetKey, attribute.StringValue("test"))
+		assertAttribute(t, attrs, AttemptKey, attribute.IntValue(1))
+		assertAttribute(t, attrs, semconv.HTTPStatusCodeKey, attribute.IntValue(http.StatusOK))
+		assertAttribute(t, attrs, semconv.HTTPMethodKey, attribute.StringValue(http.MethodPost))
+	}
+
+	ingestAttrs := attribute.NewSet(spans[0].Attributes()...)
+	assertAttribute(t, ingestAttrs, IngestedKey, attribute.Int64Value(2))
+	assertAttribute(t, ingestAttrs, FailedKey, attribute.Int64Value(0))
+
+	queryAttrs := attribute.NewSet(spans[1].Attributes()...)
+	assertAttribute(t, queryAttrs, RowsExaminedKey, attribute.Int64Value(2))
+	assertAttribute(t, queryAttrs, RowsMatchedKey, attribute.Int64Value(1))
+
+	measurements := map[string][]metrictest.Measured{}
+	for _, m := range metrictest.AsStructs(mp.MeasurementBatches) {
+		measurements[m.Name] = append(measurements[m.Name], m)
+	}
+
+	assert.Len(t, measurements["axiom.client.duration"], 2)
+	assert.Empty(t, measurements["axiom.client.retries"])
+
+	if assert.Len(t, measurements["axiom.client.request.size"], 2) {
+		assert.Positive(t, measurements["axiom.client.request.size"][0].Number.AsInt64())
+	}
+
+	if assert.Len(t, measurements["axiom.client.events.ingested"], 1) {
+		m := measurements["axiom.client.events.ingested"][0]
+		assert.EqualValues(t, 2, m.Number.AsInt64())
+		assert.Equal(t, attribute.StringValue("Datasets.IngestEvents"), m.Labels[OperationKey])
+		assert.Equal(t, attribute.StringValue("test"), m.Labels[DatasetKey])
+	}
+	if assert.Len(t, measurements["axiom.client.events.failed"], 1) {
+		assert.Zero(t, measurements["axiom.client.events.failed"][0].Number.AsInt64())
+	}
+}
+
+func TestInstrument_Retry(t *testing.T) {
+	var hits uint64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NotEmpty(t, r.Header.Get("Traceparent"))
+
+		if atomic.AddUint64(&hits, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, "[]")
+	}))
+	defer srv.Close()
+
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+	mp := metrictest.NewMeterProvider()
+
+	client, err := axiom.NewClient(
+		axiom.SetNoEnv(),
+		axiom.SetURL(srv.URL),
+		axiom.SetAccessToken("xapt-123"),
+		axiom.SetRetryPolicy(axiom.RetryPolicy{
+			MaxAttempts: 3,
+			BaseBackoff: time.Millisecond,
+			MaxBackoff:  time.Millisecond,
+		}),
+		Instrument(
+			SetTracerProvider(tp),
+			SetMeterProvider(mp),
+			SetPropagator(propagation.TraceContext{}),
+		),
+	)
+	require.NoError(t, err)
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "parent")
+	defer parent.End()
+
+	_, err = client.Datasets.List(ctx)
+	require.NoError(t, err)
+
+	spans := sr.Ended()
+	require.Len(t, spans, 3)
+	for i, span := range spans {
+		attrs := attribute.NewSet(span.Attributes()...)
+		assert.Equal(t, "Datasets.List", span.Name())
+		assertAttribute(t, attrs, AttemptKey, attribute.IntValue(i+1))
+		assert.False(t, attrs.HasValue(DatasetKey))
+
+		// Attempts are siblings rather than children of each other.
+		assert.Equal(t, parent.SpanContext().SpanID(), span.Parent().SpanID())
+	}
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+	assert.Equal(t, codes.Unset, spans[2].Status().Code)
+
+	var retries int64
+	for _, m := range metrictest.AsStructs(mp.MeasurementBatches) {
+		if m.Name == "axiom.client.retries" {
+			retries += m.Number.AsInt64()
+		}
+	}
+	assert.EqualValues(t, 2, retries)
+}
+
+func TestDatasetFromPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/api/v1/datasets", ""},
+		{"/api/v1/datasets/", ""},
+		{"/api/v1/datasets/test", "test"},
+		{"/api/v1/datasets/test/ingest", "test"},
+		{"/api/v1/datasets/_apl", ""},
+		{"/api/v1/datasets/_stats", ""},
+		{"/prefix/api/v1/datasets/test/query", "test"},
+		{"/api/v1/users", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			assert.Equal(t, tt.want, datasetFromPath(tt.path))
+		})
+	}
+}
+
+func assertAttribute(t *testing.T, attrs attribute.Set, key attribute.Key, want attribute.Value) {
+	t.Helper()
+
+	got, ok := attrs.Value(key)
+	if assert.True(t, ok, "missing attribute %q", key) {
+		assert.Equal(t, want, got, "attribute %q", key)
+	}
+}
@@ -0,0 +1,372 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var aplIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ToAPL translates the given query on the given dataset into an equivalent
+// query in the Axiom Processing Language (APL) which can be executed using
+// `DatasetsService.APLQuery`. The time range of the query is part of the
+// returned APL, if specified.
+//
+// An error is returned for constructs that have no APL equivalent, like a
+// Cursor or ContinuationToken.
+func ToAPL(dataset string, q Query) (string, error) {
+	if dataset == "" {
+		return "", errors.New("dataset name must not be empty")
+	} else if q.Cursor != "" {
+		return "", errors.New("cursor has no APL equivalent")
+	} else if q.ContinuationToken != "" {
+		return "", errors.New("continuation token has no APL equivalent")
+	} else if len(q.Aggregations) > 0 && len(q.Projections) > 0 {
+		return "", errors.New("projections can't be translated alongside aggregations")
+	} else if len(q.GroupBy) > 0 && len(q.Aggregations) == 0 {
+		return "", errors.New("group by requires at least one aggregation")
+	}
+
+	var b strings.Builder
+	b.WriteString(aplQuote(dataset))
+
+	switch {
+	case !q.StartTime.IsZero() && !q.EndTime.IsZero():
+		fmt.Fprintf(&b, "\n| where _time between (%s .. %s)",
+			aplDatetime(q.StartTime), aplDatetime(q.EndTime))
+	case !q.StartTime.IsZero():
+		fmt.Fprintf(&b, "\n| where _time >= %s", aplDatetime(q.StartTime))
+	case !q.EndTime.IsZero():
+		fmt.Fprintf(&b, "\n| where _time <= %s", aplDatetime(q.EndTime))
+	}
+
+	if len(q.VirtualFields) > 0 {
+		exts := make([]string, len(q.VirtualFields))
+		for i, vf := range q.VirtualFields {
+			if vf.Alias == "" || vf.Expression == "" {
+				return "", fmt.Errorf("virtual field %d: alias and expression are required", i)
+			}
+			exts[i] = aplQuoteField(vf.Alias) + " = " + vf.Expression
+		}
+		b.WriteString("\n| extend " + strings.Join(exts, ", "))
+	}
+
+	if q.Filter.Op != emptyFilterOp {
+		expr, err := filterToAPL(q.Filter)
+		if err != nil {
+			return "", fmt.Errorf("filter: %w", err)
+		}
+		b.WriteString("\n| where " + expr)
+	}
+
+	if len(q.Aggregations) > 0 {
+		aggs := make([]string, len(q.Aggregations))
+		for i, agg := range q.Aggregations {
+			expr, err := aggregationToAPL(agg)
+			if err != nil {
+				return "", fmt.Errorf("aggregation %d: %w", i, err)
+			}
+			aggs[i] = expr
+		}
+
+		bin := "bin_auto(_time)"
+		if q.Resolution > 0 {
+			res, err := aplTimespan(q.Resolution)
+			if err != nil {
+				return "", fmt.Errorf("resolution: %w", err)
+			}
+			bin = "bin(_time, " + res + ")"
+		}
+
+		groups := make([]string, 0, len(q.GroupBy)+1)
+		for _, field := range q.GroupBy {
+			groups = append(groups, aplQuoteField(field))
+		}
+		groups = append(groups, bin)
+
+		fmt.Fprintf(&b, "\n| summarize %s by %s", strings.Join(aggs, ", "), strings.Join(groups, ", "))
+	}
+
+	if len(q.Order) > 0 {
+		orders := make([]string, len(q.Order))
+		for i, order := range q.Order {
+			if order.Field == "" {
+				return "", fmt.Errorf("order %d: field is required", i)
+			}
+			orders[i] = aplQuoteField(order.Field)
+			if order.Desc {
+				orders[i] += " desc"
+			} else {
+				orders[i] += " asc"
+			}
+		}
+		b.WriteString("\n| order by " + strings.Join(orders, ", "))
+	}
+
+	if len(q.Projections) > 0 {
+		projs := make([]string, len(q.Projections))
+		for i, p := range q.Projections {
+			if p.Field == "" {
+				return "", fmt.Errorf("projection %d: field is required", i)
+			}
+			projs[i] = aplQuoteField(p.Field)
+			if p.Alias != "" {
+				projs[i] = aplQuoteField(p.Alias) + " = " + projs[i]
+			}
+		}
+		b.WriteString("\n| project " + strings.Join(projs, ", "))
+	}
+
+	if q.Limit > 0 {
+		fmt.Fprintf(&b, "\n| limit %d", q.Limit)
+	}
+
+	return b.String(), nil
+}
+
+// filterToAPL translates the given filter into an APL expression.
+func filterToAPL(f Filter) (string, error) {
+	switch f.Op {
+	case OpAnd, OpOr:
+		expr, err := childrenToAPL(f)
+		if err != nil {
+			return "", err
+		} else if len(f.Children) == 1 {
+			return expr, nil
+		}
+		return "(" + expr + ")", nil
+	case OpNot:
+		if len(f.Children) == 0 {
+			return "", fmt.Errorf("%q filter without children", f.Op)
+		}
+		// Multiple children of a negation are implicitly combined using "and".
+		expr, err := childrenToAPL(Filter{Op: OpAnd, Children: f.Children})
+		if err != nil {
+			return "", err
+		}
+		return "not(" + expr + ")", nil
+	case emptyFilterOp:
+		return "", errors.New("filter operation is required")
+	}
+
+	if f.Field == "" {
+		return "", fmt.Errorf("%q filter without field", f.Op)
+	}
+	field := aplQuoteField(f.Field)
+
+	switch f.Op {
+	case OpExists:
+		return "isnotnull(" + field + ")", nil
+	case OpNotExists:
+		return "isnull(" + field + ")", nil
+	case OpRegexp, OpNotRegexp:
+		s, ok := f.Value.(string)
+		if !ok {
+			return "", fmt.Errorf("%q filter on field %q requires a string value", f.Op, f.Field)
+		}
+		expr := field + " matches regex " + strconv.Quote(s)
+		if f.Op == OpNotRegexp {
+			expr = "not(" + expr + ")"
+		}
+		return expr, nil
+	case OpContains, OpNotContains:
+		// A non-string value can only be contained in an array.
+		if _, ok := f.Value.(string); !ok {
+			value, err := aplValue(f.Value)
+			if err != nil {
+				return "", fmt.Errorf("%q filter on field %q: %w", f.Op, f.Field, err)
+			}
+			expr := "set_has_element(" + field + ", " + value + ")"
+			if f.Op == OpNotContains {
+				expr = "not(" + expr + ")"
+			}
+			return expr, nil
+		}
+	}
+
+	var op string
+	switch f.Op {
+	case OpEqual:
+		op = "=="
+	case OpNotEqual:
+		op = "!="
+	case OpGreaterThan:
+		op = ">"
+	case OpGreaterThanEqual:
+		op = ">="
+	case OpLessThan:
+		op = "<"
+	case OpLessThanEqual:
+		op = "<="
+	case OpStartsWith:
+		op = "startswith"
+	case OpNotStartsWith:
+		op = "!startswith"
+	case OpEndsWith:
+		op = "endswith"
+	case OpNotEndsWith:
+		op = "!endswith"
+	case OpContains:
+		op = "contains"
+	case OpNotContains:
+		op = "!contains"
+	default:
+		return "", fmt.Errorf("filter operation %q has no APL equivalent", f.Op)
+	}
+
+	if f.CaseSensitive {
+		switch f.Op {
+		case OpStartsWith, OpNotStartsWith, OpEndsWith, OpNotEndsWith, OpContains, OpNotContains:
+			op += "_cs"
+		default:
+			return "", fmt.Errorf("%q filter on field %q can't be case sensitive", f.Op, f.Field)
+		}
+	}
+
+	value, err := aplValue(f.Value)
+	if err != nil {
+		return "", fmt.Errorf("%q filter on field %q: %w", f.Op, f.Field, err)
+	}
+
+	return field + " " + op + " " + value, nil
+}
+
+// childrenToAPL translates the children of the given filter into APL
+// expressions combined using the filters logical operation.
+func childrenToAPL(f Filter) (string, error) {
+	if len(f.Children) == 0 {
+		return "", fmt.Errorf("%q filter without children", f.Op)
+	}
+
+	exprs := make([]string, len(f.Children))
+	for i, child := range f.Children {
+		expr, err := filterToAPL(child)
+		if err != nil {
+			return "", err
+		}
+		exprs[i] = expr
+	}
+
+	return strings.Join(exprs, " "+f.Op.String()+" "), nil
+}
+
+// aggregationToAPL translates the given aggregation into an APL aggregation
+// function call.
+func aggregationToAPL(a Aggregation) (string, error) {
+	field := aplQuoteField(a.Field)
+
+	var expr string
+	switch a.Op {
+	case OpCount:
+		expr = "count()"
+	case OpCountDistinct:
+		expr = "dcount(" + field + ")"
+	case OpSum, OpAvg, OpMin, OpMax, OpVariance:
+		expr = a.Op.String() + "(" + field + ")"
+	case OpStandardDeviation:
+		expr = "stdev(" + field + ")"
+	case OpTopk, OpHistogram:
+		n, err := aplValue(a.Argument)
+		if err != nil || !isNumber(a.Argument) {
+			return "", fmt.Errorf("%q aggregation requires a numeric argument", a.Op)
+		}
+		expr = a.Op.String() + "(" + field + ", " + n + ")"
+	case OpPercentiles:
+		ps, ok := a.Argument.([]float64)
+		if !ok {
+			args, isSlice := a.Argument.([]interface{})
+			if !isSlice {
+				return "", fmt.Errorf("%q aggregation requires a list of numbers as argument", a.Op)
+			}
+			for _, arg := range args {
+				if !isNumber(arg) {
+					return "", fmt.Errorf("%q aggregation requires a list of numbers as argument", a.Op)
+				}
+				ps = append(ps, toFloat(arg))
+			}
+		}
+		if len(ps) == 0 {
+			return "", fmt.Errorf("%q aggregation requires at least one percentile", a.Op)
+		}
+		args := make([]string, len(ps))
+		for i, p := range ps {
+			args[i] = strconv.FormatFloat(p, 'f', -1, 64)
+		}
+		expr = "percentiles_array(" + field + ", " + strings.Join(args, ", ") + ")"
+	default:
+		return "", fmt.Errorf("aggregation operation %q has no APL equivalent", a.Op)
+	}
+
+	if a.Alias != "" {
+		expr = aplQuoteField(a.Alias) + " = " + expr
+	}
+
+	return expr, nil
+}
+
+// aplQuoteField returns the given field name as an APL identifier, quoting it
+// if necessary.
+func aplQuoteField(name string) string {
+	if aplIdentifier.MatchString(name) {
+		return name
+	}
+	return aplQuote(name)
+}
+
+// aplQuote returns the given entity name quoted for use in APL.
+func aplQuote(name string) string {
+	r := strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+	return "['" + r.Replace(name) + "']"
+}
+
+// aplValue returns the given value as an APL literal.
+func aplValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return strconv.Quote(v), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case time.Time:
+		return aplDatetime(v), nil
+	}
+
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return fmt.Sprint(value), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(toFloat(value), 'f', -1, 64), nil
+	}
+
+	return "", fmt.Errorf("value of type %T has no APL equivalent", value)
+}
+
+// aplDatetime returns the given time as an APL datetime literal.
+func aplDatetime(t time.Time) string {
+	return "datetime(" + t.UTC().Format(time.RFC3339Nano) + ")"
+}
+
+// aplTimespan returns the given duration as an APL timespan literal.
+func aplTimespan(d time.Duration) (string, error) {
+	units := []struct {
+		d    time.Duration
+		name string
+	}{
+		{24 * time.Hour, "d"},
+		{time.Hour, "h"},
+		{time.Minute, "m"},
+		{time.Second, "s"},
+		{time.Millisecond, "ms"},
+	}
+	for _, u := range units {
+		if d%u.d == 0 {
+			return strconv.FormatInt(int64(d/u.d), 10) + u.name, nil
+		}
+	}
+	return "", fmt.Errorf("%s is not a whole number of milliseconds", d)
+}
@@ -0,0 +1,181 @@
+package query
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToAPL(t *testing.T) {
+	startTime := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	endTime := startTime.Add(time.Hour)
+
+	tests := []struct {
+		name  string
+		input Query
+		exp   string
+	}{
+		{
+			name:  "empty",
+			input: Query{},
+			exp:   "['test']",
+		},
+		{
+			name: "filter",
+			input: Query{
+				StartTime: startTime,
+				EndTime:   endTime,
+				Filter: Filter{
+					Op: OpAnd,
+					Children: []Filter{
+						{Op: OpGreaterThanEqual, Field: "status", Value: 500},
+						{Op: OpStartsWith, Field: "path", Value: "/api", CaseSensitive: true},
+						{
+							Op: OpOr,
+							Children: []Filter{
+								{Op: OpRegexp, Field: "method", Value: "^(GET|HEAD)$"},
+								{Op: OpNotContains, Field: "user.agent", Value: "bot"},
+								{Op: OpContains, Field: "tags", Value: 42},
+							},
+						},
+						{
+							Op: OpNot,
+							Children: []Filter{
+								{Op: OpExists, Field: "error"},
+							},
+						},
+						{Op: OpEqual, Field: "region", Value: `eu-"west"`},
+					},
+				},
+				Limit: 100,
+			},
+			exp: `['test']
+| where _time between (datetime(2022-01-01T00:00:00Z) .. datetime(2022-01-01T01:00:00Z))
+| where (status >= 500 and path startswith_cs "/api" and (method matches regex "^(GET|HEAD)$" or ['user.agent'] !contains "bot" or set_has_element(tags, 42)) and not(isnotnull(error)) and region == "eu-\"west\"")
+| limit 100`,
+		},
+		{
+			name: "aggregations",
+			input: Query{
+				Resolution: time.Minute,
+				VirtualFields: []VirtualField{
+					{Alias: "dur_ms", Expression: "duration / 1000"},
+				},
+				Aggregations: []Aggregation{
+					{Op: OpCount, Field: "*", Alias: "n"},
+					{Op: OpPercentiles, Field: "dur_ms", Argument: []interface{}{50.0, 95, 99.9}},
+					{Op: OpTopk, Field: "path", Argument: 10},
+					{Op: OpStandardDeviation, Field: "dur_ms"},
+				},
+				GroupBy: []string{"host", "service.name"},
+				Order: []Order{
+					{Field: "n", Desc: true},
+					{Field: "host"},
+				},
+			},
+			exp: `['test']
+| extend dur_ms = duration / 1000
+| summarize n = count(), percentiles_array(dur_ms, 50, 95, 99.9), topk(path, 10), stdev(dur_ms) by host, ['service.name'], bin(_time, 1m)
+| order by n desc, host asc`,
+		},
+		{
+			name: "auto resolution",
+			input: Query{
+				Aggregations: []Aggregation{{Op: OpAvg, Field: "duration"}},
+			},
+			exp: `['test']
+| summarize avg(duration) by bin_auto(_time)`,
+		},
+		{
+			name: "projections",
+			input: Query{
+				StartTime: startTime,
+				Projections: []Projection{
+					{Field: "status"},
+					{Field: "user.id", Alias: "user"},
+				},
+				Order: []Order{{Field: "_time", Desc: true}},
+			},
+			exp: `['test']
+| where _time >= datetime(2022-01-01T00:00:00Z)
+| order by _time desc
+| project status, user = ['user.id']`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			act, err := ToAPL("test", tt.input)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.exp, act)
+		})
+	}
+}
+
+func TestToAPL_Error(t *testing.T) {
+	tests := []struct {
+		name    string
+		dataset string
+		input   Query
+		err     string
+	}{
+		{
+			name:  "no dataset",
+			input: Query{},
+			err:   "dataset name must not be empty",
+		},
+		{
+			name:    "cursor",
+			dataset: "test",
+			input:   Query{Cursor: "abc"},
+			err:     "cursor has no APL equivalent",
+		},
+		{
+			name:    "read-only aggregation",
+			dataset: "test",
+			input:   Query{Aggregations: []Aggregation{{Op: OpCountIf, Field: "a"}}},
+			err:     `aggregation 0: aggregation operation "countif" has no APL equivalent`,
+		},
+		{
+			name:    "logical filter without children",
+			dataset: "test",
+			input:   Query{Filter: Filter{Op: OpNot}},
+			err:     `filter: "not" filter without children`,
+		},
+		{
+			name:    "case sensitive equality",
+			dataset: "test",
+			input:   Query{Filter: Filter{Op: OpEqual, Field: "a", Value: "b", CaseSensitive: true}},
+			err:     `filter: "==" filter on field "a" can't be case sensitive`,
+		},
+		{
+			name:    "unsupported value",
+			dataset: "test",
+			input:   Query{Filter: Filter{Op: OpEqual, Field: "a", Value: []string{"b"}}},
+			err:     `filter: "==" filter on field "a": value of type []string has no APL equivalent`,
+		},
+		{
+			name:    "sub-millisecond resolution",
+			dataset: "test",
+			input: Query{
+				Resolution:   time.Microsecond,
+				Aggregations: []Aggregation{{Op: OpCount, Field: "*"}},
+			},
+			err: "resolution: 1µs is not a whole number of milliseconds",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ToAPL(tt.dataset, tt.input)
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func TestAPLQuoteField(t *testing.T) {
+	assert.Equal(t, "status", aplQuoteField("status"))
+	assert.Equal(t, "['user.id']", aplQuoteField("user.id"))
+	assert.Equal(t, `['it\'s']`, aplQuoteField("it's"))
+}
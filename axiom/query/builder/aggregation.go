@@ -0,0 +1,125 @@
+package builder
+
+import (
+	"fmt"
+
+	"github.com/axiomhq/axiom-go/axiom/query"
+)
+
+// Aggregation is an aggregation performed as part of a query. Invalid
+// aggregations carry an error which is returned when the query is built.
+type Aggregation struct {
+	aggregation query.Aggregation
+	err         error
+}
+
+// Aggregation returns the aggregation or an error, if it is invalid.
+func (a Aggregation) Aggregation() (query.Aggregation, error) {
+	return a.aggregation, a.err
+}
+
+// As sets the alias the aggregation is referenced by in the query result and
+// in orders.
+func (a Aggregation) As(alias string) Aggregation {
+	a.aggregation.Alias = alias
+	return a
+}
+
+// Count counts all events.
+func Count() Aggregation {
+	return Aggregation{
+		aggregation: query.Aggregation{
+			Op:    query.OpCount,
+			Field: "*",
+		},
+	}
+}
+
+// CountDistinct counts the distinct values of the given field.
+func CountDistinct(field string) Aggregation {
+	return aggregation(query.OpCountDistinct, field, nil)
+}
+
+// Sum sums up the values of the given field.
+func Sum(field string) Aggregation {
+	return aggregation(query.OpSum, field, nil)
+}
+
+// Avg calculates the average of the values of the given field.
+func Avg(field string) Aggregation {
+	return aggregation(query.OpAvg, field, nil)
+}
+
+// Min determines the minimum value of the given field.
+func Min(field string) Aggregation {
+	return aggregation(query.OpMin, field, nil)
+}
+
+// Max determines the maximum value of the given field.
+func Max(field string) Aggregation {
+	return aggregation(query.OpMax, field, nil)
+}
+
+// Variance calculates the variance of the values of the given field.
+func Variance(field string) Aggregation {
+	return aggregation(query.OpVariance, field, nil)
+}
+
+// StandardDeviation calculates the standard deviation of the values of the
+// given field.
+func StandardDeviation(field string) Aggregation {
+	return aggregation(query.OpStandardDeviation, field, nil)
+}
+
+// Topk determines the k most frequent values of the given field.
+func Topk(field string, k uint) Aggregation {
+	a := aggregation(query.OpTopk, field, k)
+	if a.err == nil && k == 0 {
+		a.err = fmt.Errorf("field %q: %q aggregation: k must be greater than zero", field, query.OpTopk)
+	}
+	return a
+}
+
+// Percentiles calculates the given percentiles, in the range [0, 100], of the
+// values of the given field.
+func Percentiles(field string, percentiles ...float64) Aggregation {
+	a := aggregation(query.OpPercentiles, field, percentiles)
+	if a.err != nil {
+		return a
+	} else if len(percentiles) == 0 {
+		a.err = fmt.Errorf("field %q: %q aggregation: at least one percentile required", field, query.OpPercentiles)
+	}
+	for _, p := range percentiles {
+		if p < 0 || p > 100 {
+			a.err = fmt.Errorf("field %q: %q aggregation: percentile %g out of range [0, 100]", field, query.OpPercentiles, p)
+			break
+		}
+	}
+	return a
+}
+
+// Histogram distributes the values of the given field into the given number of
+// buckets.
+func Histogram(field string, buckets uint) Aggregation {
+	a := aggregation(query.OpHistogram, field, buckets)
+	if a.err == nil && buckets == 0 {
+		a.err = fmt.Errorf("field %q: %q aggregation: number of buckets must be greater than zero", field, query.OpHistogram)
+	}
+	return a
+}
+
+func aggregation(op query.AggregationOp, field string, argument interface{}) Aggregation {
+	var err error
+	if field == "" {
+		err = fmt.Errorf("%q aggregation: field name must not be empty", op)
+	}
+
+	return Aggregation{
+		aggregation: query.Aggregation{
+			Op:       op,
+			Field:    field,
+			Argument: argument,
+		},
+		err: err,
+	}
+}
@@ -0,0 +1,58 @@
+package builder
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/axiomhq/axiom-go/axiom/query"
+)
+
+func TestAggregation(t *testing.T) {
+	tests := []struct {
+		name string
+		agg  Aggregation
+		exp  query.Aggregation
+	}{
+		{"Count", Count().As("n"), query.Aggregation{Alias: "n", Op: query.OpCount, Field: "*"}},
+		{"CountDistinct", CountDistinct("a"), query.Aggregation{Op: query.OpCountDistinct, Field: "a"}},
+		{"Sum", Sum("a"), query.Aggregation{Op: query.OpSum, Field: "a"}},
+		{"Avg", Avg("a"), query.Aggregation{Op: query.OpAvg, Field: "a"}},
+		{"Min", Min("a"), query.Aggregation{Op: query.OpMin, Field: "a"}},
+		{"Max", Max("a"), query.Aggregation{Op: query.OpMax, Field: "a"}},
+		{"Variance", Variance("a"), query.Aggregation{Op: query.OpVariance, Field: "a"}},
+		{"StandardDeviation", StandardDeviation("a"), query.Aggregation{Op: query.OpStandardDeviation, Field: "a"}},
+		{"Topk", Topk("a", 10), query.Aggregation{Op: query.OpTopk, Field: "a", Argument: uint(10)}},
+		{"Percentiles", Percentiles("a", 50, 95, 99), query.Aggregation{Op: query.OpPercentiles, Field: "a", Argument: []float64{50, 95, 99}}},
+		{"Histogram", Histogram("a", 15), query.Aggregation{Op: query.OpHistogram, Field: "a", Argument: uint(15)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agg, err := tt.agg.Aggregation()
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.exp, agg)
+		})
+	}
+}
+
+func TestAggregation_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		agg  Aggregation
+		err  string
+	}{
+		{"empty field", Sum(""), `"sum" aggregation: field name must not be empty`},
+		{"zero k", Topk("a", 0), `field "a": "topk" aggregation: k must be greater than zero`},
+		{"no percentiles", Percentiles("a"), `field "a": "percentiles" aggregation: at least one percentile required`},
+		{"percentile out of range", Percentiles("a", 50, 101), `field "a": "percentiles" aggregation: percentile 101 out of range [0, 100]`},
+		{"zero buckets", Histogram("a", 0), `field "a": "histogram" aggregation: number of buckets must be greater than zero`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.agg.Aggregation()
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package builder
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/axiomhq/axiom-go/axiom/query"
+)
+
+// Builder builds a `query.Query`. Its methods return the Builder itself so
+// calls can be chained. Errors are collected and returned by `Build()`.
+type Builder struct {
+	q            query.Query
+	filter       *Condition
+	aggregations []Aggregation
+}
+
+// New returns a Builder for a query that spans the given time range.
+func New(startTime, endTime time.Time) *Builder {
+	return &Builder{
+		q: query.Query{
+			StartTime: startTime,
+			EndTime:   endTime,
+		},
+	}
+}
+
+// Where sets the filter of the query to the given conditions which all need to
+// be met. It replaces any previously set filter.
+func (b *Builder) Where(conds ...Condition) *Builder {
+	cond := And(conds...)
+	b.filter = &cond
+	return b
+}
+
+// And adds the given conditions to the filter of the query, requiring all of
+// them to be met in addition to the existing filter.
+func (b *Builder) And(conds ...Condition) *Builder {
+	if b.filter == nil {
+		return b.Where(conds...)
+	}
+	cond := b.filter.And(conds...)
+	b.filter = &cond
+	return b
+}
+
+// Or adds the given conditions to the filter of the query, requiring either the
+// existing filter or at least one of them to be met.
+func (b *Builder) Or(conds ...Condition) *Builder {
+	if b.filter == nil {
+		cond := Or(conds...)
+		b.filter = &cond
+		return b
+	}
+	cond := b.filter.Or(conds...)
+	b.filter = &cond
+	return b
+}
+
+// Aggregate adds the given aggregations to the query.
+func (b *Builder) Aggregate(aggs ...Aggregation) *Builder {
+	b.aggregations = append(b.aggregations, aggs...)
+	return b
+}
+
+// GroupBy adds the given fields to group the query result by. Only valid when
+// at least one aggregation is specified.
+func (b *Builder) GroupBy(fields ...string) *Builder {
+	b.q.GroupBy = append(b.q.GroupBy, fields...)
+	return b
+}
+
+// OrderBy adds an order rule for the given field to the query.
+func (b *Builder) OrderBy(field string, desc bool) *Builder {
+	b.q.Order = append(b.q.Order, query.Order{
+		Field: field,
+		Desc:  desc,
+	})
+	return b
+}
+
+// Resolution sets the resolution of the queries graph. It must be between the
+// queries time range / 1000 and / 100. Defaults to server-side auto-detection.
+func (b *Builder) Resolution(d time.Duration) *Builder {
+	b.q.Resolution = d
+	return b
+}
+
+// Limit limits the amount of results returned from the query.
+func (b *Builder) Limit(n uint32) *Builder {
+	b.q.Limit = n
+	return b
+}
+
+// VirtualField adds a virtual field with the given alias whose value is derived
+// from the given expression.
+func (b *Builder) VirtualField(alias, expr string) *Builder {
+	b.q.VirtualFields = append(b.q.VirtualFields, query.VirtualField{
+		Alias:      alias,
+		Expression: expr,
+	})
+	return b
+}
+
+// Project adds the given field to the projections of the query. The alias is
+// optional.
+func (b *Builder) Project(field, alias string) *Builder {
+	b.q.Projections = append(b.q.Projections, query.Projection{
+		Field: field,
+		Alias: alias,
+	})
+	return b
+}
+
+// Build validates and returns the query. The Builder can be used further after
+// building.
+func (b *Builder) Build() (query.Query, error) {
+	q := b.q
+
+	if q.StartTime.IsZero() || q.EndTime.IsZero() {
+		return query.Query{}, errors.New("start and end time are required")
+	} else if !q.StartTime.Before(q.EndTime) {
+		return query.Query{}, errors.New("start time must be before end time")
+	}
+
+	if q.Resolution < 0 {
+		return query.Query{}, errors.New("resolution must not be negative")
+	} else if r := q.EndTime.Sub(q.StartTime); q.Resolution > 0 && (q.Resolution < r/1000 || q.Resolution > r/100) {
+		return query.Query{}, fmt.Errorf("resolution %s out of range [%s, %s] for the time range of the query",
+			q.Resolution, r/1000, r/100)
+	}
+
+	if b.filter != nil {
+		filter, err := b.filter.Filter()
+		if err != nil {
+			return query.Query{}, fmt.Errorf("filter: %w", err)
+		}
+		q.Filter = filter
+	}
+
+	if len(b.aggregations) > 0 {
+		q.Aggregations = make([]query.Aggregation, len(b.aggregations))
+	}
+	for i, a := range b.aggregations {
+		aggregation, err := a.Aggregation()
+		if err != nil {
+			return query.Query{}, fmt.Errorf("aggregation %d: %w", i, err)
+		}
+		q.Aggregations[i] = aggregation
+	}
+
+	if len(q.GroupBy) > 0 && len(q.Aggregations) == 0 {
+		return query.Query{}, errors.New("group by requires at least one aggregation")
+	}
+
+	for i, order := range q.Order {
+		if order.Field == "" {
+			return query.Query{}, fmt.Errorf("order %d: field name must not be empty", i)
+		}
+	}
+
+	// Catch everything else the builder doesn't check by itself.
+	if err := q.Validate(); err != nil {
+		return query.Query{}, err
+	}
+
+	// Don't share slices with the Builder.
+	q.GroupBy = append([]string(nil), q.GroupBy...)
+	q.Order = append([]query.Order(nil), q.Order...)
+	q.VirtualFields = append([]query.VirtualField(nil), q.VirtualFields...)
+	q.Projections = append([]query.Projection(nil), q.Projections...)
+
+	return q, nil
+}
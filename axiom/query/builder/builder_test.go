@@ -0,0 +1,101 @@
+package builder
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/axiomhq/axiom-go/axiom/query"
+)
+
+func TestBuilder(t *testing.T) {
+	endTime := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	startTime := endTime.Add(-6 * time.Hour)
+
+	q, err := New(startTime, endTime).
+		Where(Field("status").Gte(500)).
+		And(Field("method").Eq("GET")).
+		Aggregate(
+			Count().As("n"),
+			Percentiles("duration", 50, 95, 99),
+		).
+		GroupBy("host").
+		OrderBy("n", true).
+		Resolution(time.Minute).
+		Limit(10).
+		VirtualField("dur_ms", "duration / 1000").
+		Project("host", "").
+		Build()
+	require.NoError(t, err)
+
+	exp := query.Query{
+		StartTime:  startTime,
+		EndTime:    endTime,
+		Resolution: time.Minute,
+		Aggregations: []query.Aggregation{
+			{Alias: "n", Op: query.OpCount, Field: "*"},
+			{Op: query.OpPercentiles, Field: "duration", Argument: []float64{50, 95, 99}},
+		},
+		GroupBy: []string{"host"},
+		Filter: query.Filter{
+			Op: query.OpAnd,
+			Children: []query.Filter{
+				{Op: query.OpGreaterThanEqual, Field: "status", Value: 500},
+				{Op: query.OpEqual, Field: "method", Value: "GET"},
+			},
+		},
+		Order:         []query.Order{{Field: "n", Desc: true}},
+		Limit:         10,
+		VirtualFields: []query.VirtualField{{Alias: "dur_ms", Expression: "duration / 1000"}},
+		Projections:   []query.Projection{{Field: "host"}},
+	}
+	assert.Equal(t, exp, q)
+}
+
+func TestBuilder_Or(t *testing.T) {
+	endTime := time.Now()
+	startTime := endTime.Add(-time.Hour)
+
+	q, err := New(startTime, endTime).
+		Or(Field("a").Exists()).
+		Or(Field("b").Exists()).
+		Build()
+	require.NoError(t, err)
+
+	assert.Equal(t, query.Filter{
+		Op: query.OpOr,
+		Children: []query.Filter{
+			{Op: query.OpExists, Field: "a"},
+			{Op: query.OpExists, Field: "b"},
+		},
+	}, q.Filter)
+}
+
+func TestBuilder_Invalid(t *testing.T) {
+	endTime := time.Now()
+	startTime := endTime.Add(-time.Hour)
+
+	tests := []struct {
+		name    string
+		builder *Builder
+		err     string
+	}{
+		{"no time range", New(time.Time{}, endTime), "start and end time are required"},
+		{"inverted time range", New(endTime, startTime), "start time must be before end time"},
+		{"negative resolution", New(startTime, endTime).Resolution(-time.Second), "resolution must not be negative"},
+		{"resolution too small", New(startTime, endTime).Resolution(time.Second), "resolution 1s out of range [3.6s, 36s] for the time range of the query"},
+		{"invalid filter", New(startTime, endTime).Where(Field("a").Gt("b")), `filter: field "a": ">" filter: value must be a number, got string`},
+		{"invalid aggregation", New(startTime, endTime).Aggregate(Count(), Topk("a", 0)), `aggregation 1: field "a": "topk" aggregation: k must be greater than zero`},
+		{"group by without aggregation", New(startTime, endTime).GroupBy("a"), "group by requires at least one aggregation"},
+		{"empty order field", New(startTime, endTime).OrderBy("", false), "order 0: field name must not be empty"},
+		{"order on unknown field", New(startTime, endTime).Aggregate(Count()).OrderBy("a", false), `invalid query: order[0].field: "a" is neither grouped by nor aggregated`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.builder.Build()
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
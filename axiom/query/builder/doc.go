@@ -0,0 +1,18 @@
+// Package builder provides a fluent API for constructing queries. It takes care
+// of nesting filters and shaping aggregation arguments the way the server
+// expects them and validates the query when it is built:
+//
+//	q, err := builder.New(startTime, endTime).
+//		Where(builder.Field("status").Gte(500)).
+//		And(builder.Field("method").Eq("GET")).
+//		Aggregate(
+//			builder.Count().As("n"),
+//			builder.Percentiles("duration", 50, 95, 99),
+//		).
+//		GroupBy("host").
+//		OrderBy("n", true).
+//		Resolution(time.Minute).
+//		Build()
+//
+// The resulting `query.Query` is ready to be passed to `Datasets.Query`.
+package builder
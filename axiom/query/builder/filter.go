@@ -0,0 +1,243 @@
+package builder
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+
+	"github.com/axiomhq/axiom-go/axiom/query"
+)
+
+// Condition is a filter condition. Conditions are created by applying an
+// operation on a field referenced using `Field()` and combined using `And()`,
+// `Or()` and `Not()`. Invalid conditions carry an error which is returned when
+// the query is built.
+type Condition struct {
+	filter query.Filter
+	err    error
+}
+
+// Filter returns the filter described by the condition or an error, if the
+// condition is invalid.
+func (c Condition) Filter() (query.Filter, error) {
+	return c.filter, c.err
+}
+
+// CaseSensitive makes the condition case sensitive. Only valid for the
+// `StartsWith`, `NotStartsWith`, `EndsWith`, `NotEndsWith`, `Contains` and
+// `NotContains` conditions.
+func (c Condition) CaseSensitive() Condition {
+	if c.err != nil {
+		return c
+	}
+
+	switch c.filter.Op {
+	case query.OpStartsWith, query.OpNotStartsWith,
+		query.OpEndsWith, query.OpNotEndsWith,
+		query.OpContains, query.OpNotContains:
+		c.filter.CaseSensitive = true
+	default:
+		c.err = fmt.Errorf("field %q: %q filter can't be case sensitive", c.filter.Field, c.filter.Op)
+	}
+
+	return c
+}
+
+// And combines the condition with the given ones, requiring all of them to be
+// met.
+func (c Condition) And(conds ...Condition) Condition {
+	return And(append([]Condition{c}, conds...)...)
+}
+
+// Or combines the condition with the given ones, requiring at least one of
+// them to be met.
+func (c Condition) Or(conds ...Condition) Condition {
+	return Or(append([]Condition{c}, conds...)...)
+}
+
+// And combines the given conditions, requiring all of them to be met.
+func And(conds ...Condition) Condition {
+	return combine(query.OpAnd, conds)
+}
+
+// Or combines the given conditions, requiring at least one of them to be met.
+func Or(conds ...Condition) Condition {
+	return combine(query.OpOr, conds)
+}
+
+// Not negates the given condition.
+func Not(cond Condition) Condition {
+	if cond.err != nil {
+		return cond
+	}
+
+	return Condition{
+		filter: query.Filter{
+			Op:       query.OpNot,
+			Children: []query.Filter{cond.filter},
+		},
+	}
+}
+
+// combine combines the given conditions using the given logical operation.
+// Conditions that use the same operation are merged.
+func combine(op query.FilterOp, conds []Condition) Condition {
+	if len(conds) == 0 {
+		return Condition{err: fmt.Errorf("%q filter requires at least one condition", op)}
+	} else if len(conds) == 1 {
+		return conds[0]
+	}
+
+	filter := query.Filter{Op: op}
+	for _, cond := range conds {
+		if cond.err != nil {
+			return cond
+		} else if cond.filter.Op == op {
+			filter.Children = append(filter.Children, cond.filter.Children...)
+		} else {
+			filter.Children = append(filter.Children, cond.filter)
+		}
+	}
+
+	return Condition{filter: filter}
+}
+
+// FieldRef references a field conditions can be applied on.
+type FieldRef struct {
+	name string
+}
+
+// Field references the field with the given name.
+func Field(name string) FieldRef {
+	return FieldRef{name: name}
+}
+
+// Eq requires the field to be equal to the given string, number or boolean.
+func (f FieldRef) Eq(value interface{}) Condition {
+	return f.condition(query.OpEqual, value, checkScalar)
+}
+
+// Neq requires the field to not be equal to the given string, number or
+// boolean.
+func (f FieldRef) Neq(value interface{}) Condition {
+	return f.condition(query.OpNotEqual, value, checkScalar)
+}
+
+// Exists requires the field to be present.
+func (f FieldRef) Exists() Condition {
+	return f.condition(query.OpExists, nil, nil)
+}
+
+// NotExists requires the field to be absent.
+func (f FieldRef) NotExists() Condition {
+	return f.condition(query.OpNotExists, nil, nil)
+}
+
+// Gt requires the field to be greater than the given number.
+func (f FieldRef) Gt(value interface{}) Condition {
+	return f.condition(query.OpGreaterThan, value, checkNumber)
+}
+
+// Gte requires the field to be greater than or equal to the given number.
+func (f FieldRef) Gte(value interface{}) Condition {
+	return f.condition(query.OpGreaterThanEqual, value, checkNumber)
+}
+
+// Lt requires the field to be less than the given number.
+func (f FieldRef) Lt(value interface{}) Condition {
+	return f.condition(query.OpLessThan, value, checkNumber)
+}
+
+// Lte requires the field to be less than or equal to the given number.
+func (f FieldRef) Lte(value interface{}) Condition {
+	return f.condition(query.OpLessThanEqual, value, checkNumber)
+}
+
+// StartsWith requires the field to start with the given prefix.
+func (f FieldRef) StartsWith(prefix string) Condition {
+	return f.condition(query.OpStartsWith, prefix, nil)
+}
+
+// NotStartsWith requires the field to not start with the given prefix.
+func (f FieldRef) NotStartsWith(prefix string) Condition {
+	return f.condition(query.OpNotStartsWith, prefix, nil)
+}
+
+// EndsWith requires the field to end with the given suffix.
+func (f FieldRef) EndsWith(suffix string) Condition {
+	return f.condition(query.OpEndsWith, suffix, nil)
+}
+
+// NotEndsWith requires the field to not end with the given suffix.
+func (f FieldRef) NotEndsWith(suffix string) Condition {
+	return f.condition(query.OpNotEndsWith, suffix, nil)
+}
+
+// Regexp requires the field to match the given regular expression.
+func (f FieldRef) Regexp(expr string) Condition {
+	return f.condition(query.OpRegexp, expr, checkRegexp)
+}
+
+// NotRegexp requires the field to not match the given regular expression.
+func (f FieldRef) NotRegexp(expr string) Condition {
+	return f.condition(query.OpNotRegexp, expr, checkRegexp)
+}
+
+// Contains requires the string field to contain the given substring or the
+// array field to contain the given element.
+func (f FieldRef) Contains(value interface{}) Condition {
+	return f.condition(query.OpContains, value, checkScalar)
+}
+
+// NotContains requires the string field to not contain the given substring or
+// the array field to not contain the given element.
+func (f FieldRef) NotContains(value interface{}) Condition {
+	return f.condition(query.OpNotContains, value, checkScalar)
+}
+
+func (f FieldRef) condition(op query.FilterOp, value interface{}, check func(interface{}) error) Condition {
+	var err error
+	if f.name == "" {
+		err = errors.New("field name must not be empty")
+	} else if check != nil {
+		if err = check(value); err != nil {
+			err = fmt.Errorf("field %q: %q filter: %w", f.name, op, err)
+		}
+	}
+
+	return Condition{
+		filter: query.Filter{
+			Op:    op,
+			Field: f.name,
+			Value: value,
+		},
+		err: err,
+	}
+}
+
+func checkScalar(value interface{}) error {
+	if value == nil {
+		return errors.New("value must not be nil")
+	} else if _, ok := value.(string); ok {
+		return nil
+	} else if _, ok := value.(bool); ok {
+		return nil
+	}
+	return checkNumber(value)
+}
+
+func checkNumber(value interface{}) error {
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return nil
+	}
+	return fmt.Errorf("value must be a number, got %T", value)
+}
+
+func checkRegexp(value interface{}) error {
+	_, err := regexp.Compile(value.(string))
+	return err
+}
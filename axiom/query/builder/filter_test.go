@@ -0,0 +1,101 @@
+package builder
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/axiomhq/axiom-go/axiom/query"
+)
+
+func TestField(t *testing.T) {
+	tests := []struct {
+		name string
+		cond Condition
+		exp  query.Filter
+	}{
+		{"Eq", Field("a").Eq("b"), query.Filter{Op: query.OpEqual, Field: "a", Value: "b"}},
+		{"Neq", Field("a").Neq(true), query.Filter{Op: query.OpNotEqual, Field: "a", Value: true}},
+		{"Exists", Field("a").Exists(), query.Filter{Op: query.OpExists, Field: "a"}},
+		{"NotExists", Field("a").NotExists(), query.Filter{Op: query.OpNotExists, Field: "a"}},
+		{"Gt", Field("a").Gt(1), query.Filter{Op: query.OpGreaterThan, Field: "a", Value: 1}},
+		{"Gte", Field("a").Gte(1.5), query.Filter{Op: query.OpGreaterThanEqual, Field: "a", Value: 1.5}},
+		{"Lt", Field("a").Lt(uint8(1)), query.Filter{Op: query.OpLessThan, Field: "a", Value: uint8(1)}},
+		{"Lte", Field("a").Lte(int64(1)), query.Filter{Op: query.OpLessThanEqual, Field: "a", Value: int64(1)}},
+		{"StartsWith", Field("a").StartsWith("b"), query.Filter{Op: query.OpStartsWith, Field: "a", Value: "b"}},
+		{"NotStartsWith", Field("a").NotStartsWith("b"), query.Filter{Op: query.OpNotStartsWith, Field: "a", Value: "b"}},
+		{"EndsWith", Field("a").EndsWith("b"), query.Filter{Op: query.OpEndsWith, Field: "a", Value: "b"}},
+		{"NotEndsWith", Field("a").NotEndsWith("b"), query.Filter{Op: query.OpNotEndsWith, Field: "a", Value: "b"}},
+		{"Regexp", Field("a").Regexp("^b$"), query.Filter{Op: query.OpRegexp, Field: "a", Value: "^b$"}},
+		{"NotRegexp", Field("a").NotRegexp("^b$"), query.Filter{Op: query.OpNotRegexp, Field: "a", Value: "^b$"}},
+		{"Contains", Field("a").Contains("b"), query.Filter{Op: query.OpContains, Field: "a", Value: "b"}},
+		{"NotContains", Field("a").NotContains(1), query.Filter{Op: query.OpNotContains, Field: "a", Value: 1}},
+		{"CaseSensitive", Field("a").Contains("b").CaseSensitive(), query.Filter{Op: query.OpContains, Field: "a", Value: "b", CaseSensitive: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := tt.cond.Filter()
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.exp, filter)
+		})
+	}
+}
+
+func TestField_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		cond Condition
+		err  string
+	}{
+		{"empty field", Field("").Eq("b"), "field name must not be empty"},
+		{"nil value", Field("a").Eq(nil), `field "a": "==" filter: value must not be nil`},
+		{"non-number", Field("a").Gte("500"), `field "a": ">=" filter: value must be a number, got string`},
+		{"invalid regexp", Field("a").Regexp("("), "error parsing regexp: missing closing ): `(`"},
+		{"case sensitive", Field("a").Eq("b").CaseSensitive(), `field "a": "==" filter can't be case sensitive`},
+		{"empty and", And(), `"and" filter requires at least one condition`},
+		{"nested", Not(Or(Field("a").Exists(), Field("b").Gt("c"))), `field "b": ">" filter: value must be a number, got string`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.cond.Filter()
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), tt.err)
+			}
+		})
+	}
+}
+
+func TestCombine(t *testing.T) {
+	a, b, c := Field("a").Exists(), Field("b").Exists(), Field("c").Exists()
+
+	filter, err := a.And(b).And(c).Or(Not(a)).Filter()
+	require.NoError(t, err)
+
+	exp := query.Filter{
+		Op: query.OpOr,
+		Children: []query.Filter{
+			{
+				Op: query.OpAnd,
+				Children: []query.Filter{
+					{Op: query.OpExists, Field: "a"},
+					{Op: query.OpExists, Field: "b"},
+					{Op: query.OpExists, Field: "c"},
+				},
+			},
+			{
+				Op: query.OpNot,
+				Children: []query.Filter{
+					{Op: query.OpExists, Field: "a"},
+				},
+			},
+		},
+	}
+	assert.Equal(t, exp, filter)
+
+	// A single condition is not wrapped.
+	filter, err = And(a).Filter()
+	require.NoError(t, err)
+	assert.Equal(t, query.Filter{Op: query.OpExists, Field: "a"}, filter)
+}
@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"time"
+
+	"github.com/axiomhq/axiom-go/axiom/internal/scan"
 )
 
 //go:generate go run -mod=mod golang.org/x/tools/cmd/stringer -type=MessageCode,MessagePriority -linecomment -output=result_string.go
@@ -144,6 +146,12 @@ type Status struct {
 	MaxBlockTime time.Time `json:"maxBlockTime"`
 	// Messages associated with the query.
 	Messages []Message `json:"messages"`
+	// MinCursor is the cursor of the oldest event of the result. It is only
+	// populated if the query was sent with IncludeCursor set to true.
+	MinCursor string `json:"minCursor"`
+	// MaxCursor is the cursor of the newest event of the result. It is only
+	// populated if the query was sent with IncludeCursor set to true.
+	MaxCursor string `json:"maxCursor"`
 }
 
 // MarshalJSON implements `json.Marshaler`. It is in place to marshal the
@@ -187,6 +195,15 @@ type Message struct {
 	Text string `json:"msg"`
 }
 
+// ScanMatches scans the matches of the result into the slice pointed to by
+// dest. The elements of the slice must be structs or pointers to structs.
+// Refer to `Entry.Scan()` for how values are scanned.
+func (r Result) ScanMatches(dest interface{}) error {
+	return scan.Slice(dest, len(r.Matches), func(i int) map[string]interface{} {
+		return r.Matches[i].values()
+	})
+}
+
 // Entry is an event that matched a query and is thus part of the result set.
 type Entry struct {
 	// Time is the time the event occurred. Matches SysTime if not specified
@@ -201,6 +218,32 @@ type Entry struct {
 	Data map[string]interface{} `json:"data"`
 }
 
+// Scan scans the entry into the struct pointed to by dest. Struct fields are
+// matched to the fields of the entry by the name given in their `axiom` tag
+// (e.g. `axiom:"status"`) or, if not tagged, by their name. Nested fields are
+// referenced using dotted paths (e.g. `axiom:"request.method"`). The `_time`,
+// `_sysTime` and `_rowId` fields are available as well.
+//
+// Numbers are converted into any numeric type, as long as they fit, and into
+// `time.Duration` values (as nanoseconds). Strings are parsed into `time.Time`
+// (RFC 3339) and `time.Duration` values. Pointer fields are allocated as
+// needed and left nil if the field is absent or null.
+func (e Entry) Scan(dest interface{}) error {
+	return scan.Into(dest, e.values())
+}
+
+// values returns the data of the entry alongside its system fields.
+func (e Entry) values() map[string]interface{} {
+	values := make(map[string]interface{}, len(e.Data)+3)
+	for k, v := range e.Data {
+		values[k] = v
+	}
+	values["_time"] = e.Time
+	values["_sysTime"] = e.SysTime
+	values["_rowId"] = e.RowID
+	return values
+}
+
 // Timeseries are queried time series.
 type Timeseries struct {
 	// Series are the intervals that build a time series.
@@ -209,6 +252,15 @@ type Timeseries struct {
 	Totals []EntryGroup `json:"totals"`
 }
 
+// ScanTotals scans the totals of the time series into the slice pointed to by
+// dest. The elements of the slice must be structs or pointers to structs.
+// Refer to `EntryGroup.Scan()` for how values are scanned.
+func (t Timeseries) ScanTotals(dest interface{}) error {
+	return scan.Slice(dest, len(t.Totals), func(i int) map[string]interface{} {
+		return t.Totals[i].values()
+	})
+}
+
 // Interval is the interval of queried time series.
 type Interval struct {
 	// StartTime of the interval.
@@ -229,6 +281,25 @@ type EntryGroup struct {
 	Aggregations []EntryGroupAgg `json:"aggregations"`
 }
 
+// Scan scans the group into the struct pointed to by dest. Struct fields are
+// matched to the fields the group is made of and to the aliases of its
+// aggregations. Refer to `Entry.Scan()` for how values are scanned.
+func (g EntryGroup) Scan(dest interface{}) error {
+	return scan.Into(dest, g.values())
+}
+
+// values returns the group fields and aggregation values of the group.
+func (g EntryGroup) values() map[string]interface{} {
+	values := make(map[string]interface{}, len(g.Group)+len(g.Aggregations))
+	for k, v := range g.Group {
+		values[k] = v
+	}
+	for _, agg := range g.Aggregations {
+		values[agg.Alias] = agg.Value
+	}
+	return values
+}
+
 // EntryGroupAgg is an aggregation which is part of a group of queried events.
 type EntryGroupAgg struct {
 	// Alias is the aggregations alias. If it wasn't specified at query time, it
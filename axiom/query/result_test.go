@@ -41,7 +41,9 @@ func TestStatus_MarshalJSON(t *testing.T) {
 		"isEstimate": false,
 		"minBlockTime": "0001-01-01T00:00:00Z",
 		"maxBlockTime": "0001-01-01T00:00:00Z",
-		"messages": null
+		"messages": null,
+		"minCursor": "",
+		"maxCursor": ""
 	}`
 
 	act, err := Status{
@@ -136,3 +138,101 @@ func TestMessagePriorityFromString(t *testing.T) {
 		assert.Equal(t, mp, parsedMP)
 	}
 }
+
+func TestResult_ScanMatches(t *testing.T) {
+	now := time.Now().UTC()
+
+	res := Result{
+		Matches: []Entry{
+			{
+				Time:  now,
+				RowID: "1",
+				Data: map[string]interface{}{
+					"status":   float64(500),
+					"duration": float64(1500000),
+					"request": map[string]interface{}{
+						"method": "GET",
+					},
+					"started": "2022-01-01T00:00:00Z",
+				},
+			},
+			{
+				Time:  now.Add(time.Second),
+				RowID: "2",
+				Data: map[string]interface{}{
+					"status":    float64(200),
+					"user.name": "john",
+				},
+			},
+		},
+	}
+
+	type event struct {
+		Time     time.Time     `axiom:"_time"`
+		RowID    string        `axiom:"_rowId"`
+		Status   int64         `axiom:"status"`
+		Duration time.Duration `axiom:"duration"`
+		Method   string        `axiom:"request.method"`
+		Started  *time.Time    `axiom:"started"`
+		User     *string       `axiom:"user.name"`
+	}
+
+	var events []event
+	require.NoError(t, res.ScanMatches(&events))
+
+	started := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	user := "john"
+	assert.Equal(t, []event{
+		{
+			Time:     now,
+			RowID:    "1",
+			Status:   500,
+			Duration: 1500 * time.Microsecond,
+			Method:   "GET",
+			Started:  &started,
+		},
+		{
+			Time:   now.Add(time.Second),
+			RowID:  "2",
+			Status: 200,
+			User:   &user,
+		},
+	}, events)
+
+	var invalid []struct {
+		Status string `axiom:"status"`
+	}
+	err := res.ScanMatches(&invalid)
+	assert.EqualError(t, err, `row 0: field "status": cannot scan float64 value 500 into string`)
+}
+
+func TestTimeseries_ScanTotals(t *testing.T) {
+	ts := Timeseries{
+		Totals: []EntryGroup{
+			{
+				Group: map[string]interface{}{
+					"host": "a",
+				},
+				Aggregations: []EntryGroupAgg{
+					{Alias: "n", Value: float64(42)},
+					{Alias: "p95", Value: 0.95},
+				},
+			},
+		},
+	}
+
+	type total struct {
+		Host  string  `axiom:"host"`
+		Count uint    `axiom:"n"`
+		P95   float64 `axiom:"p95"`
+	}
+
+	var totals []*total
+	require.NoError(t, ts.ScanTotals(&totals))
+
+	assert.Equal(t, []*total{{Host: "a", Count: 42, P95: 0.95}}, totals)
+
+	var single total
+	require.NoError(t, ts.Totals[0].Scan(&single))
+	assert.Equal(t, *totals[0], single)
+}
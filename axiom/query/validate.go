@@ -0,0 +1,298 @@
+package query
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// ValidationError describes a single problem with a query. Path points to the
+// invalid part of the query using the field names of its JSON representation,
+// e.g. `filter.children[1].value`.
+type ValidationError struct {
+	// Path to the invalid part of the query.
+	Path string
+	// Message describing the problem.
+	Message string
+}
+
+// Error implements the error interface.
+func (e ValidationError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return e.Path + ": " + e.Message
+}
+
+// ValidationErrors are all problems found while validating a query. It is the
+// error returned by the `Validate()` methods.
+type ValidationErrors []ValidationError
+
+// Error implements the error interface.
+func (e ValidationErrors) Error() string {
+	s := make([]string, len(e))
+	for i, err := range e {
+		s[i] = err.Error()
+	}
+	return "invalid query: " + strings.Join(s, "; ")
+}
+
+// validator collects validation errors.
+type validator struct {
+	errs ValidationErrors
+}
+
+func (v *validator) addf(path, format string, a ...interface{}) {
+	v.errs = append(v.errs, ValidationError{
+		Path:    path,
+		Message: fmt.Sprintf(format, a...),
+	})
+}
+
+func (v *validator) err() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return v.errs
+}
+
+// Validate validates the query and returns `ValidationErrors` if it is
+// invalid. It catches mistakes that would otherwise only be reported by the
+// server.
+func (q Query) Validate() error {
+	var v validator
+	q.validate(&v)
+	return v.err()
+}
+
+func (q Query) validate(v *validator) {
+	if q.StartTime.IsZero() {
+		v.addf("startTime", "required")
+	}
+	if q.EndTime.IsZero() {
+		v.addf("endTime", "required")
+	}
+	if !q.StartTime.IsZero() && !q.EndTime.IsZero() && q.EndTime.Before(q.StartTime) {
+		v.addf("endTime", "must not be before startTime")
+	}
+
+	if q.Resolution < 0 {
+		v.addf("resolution", "must not be negative")
+	} else if r := q.EndTime.Sub(q.StartTime); q.Resolution > 0 && r > 0 &&
+		(q.Resolution < r/1000 || q.Resolution > r/100) {
+		v.addf("resolution", "%s out of range [%s, %s] for the time range of the query",
+			q.Resolution, r/1000, r/100)
+	}
+
+	for i, agg := range q.Aggregations {
+		agg.validate(v, fmt.Sprintf("aggregations[%d]", i))
+	}
+
+	if len(q.GroupBy) > 0 && len(q.Aggregations) == 0 {
+		v.addf("groupBy", "requires at least one aggregation")
+	}
+	for i, field := range q.GroupBy {
+		if field == "" {
+			v.addf(fmt.Sprintf("groupBy[%d]", i), "must not be empty")
+		}
+	}
+
+	if !reflect.DeepEqual(q.Filter, Filter{}) {
+		q.Filter.validate(v, "filter")
+	}
+
+	for i, order := range q.Order {
+		path := fmt.Sprintf("order[%d].field", i)
+		if order.Field == "" {
+			v.addf(path, "required")
+		} else if len(q.Aggregations) > 0 && !q.isGroupedOrAggregated(order.Field) {
+			v.addf(path, "%q is neither grouped by nor aggregated", order.Field)
+		}
+	}
+
+	for i, vf := range q.VirtualFields {
+		if vf.Alias == "" {
+			v.addf(fmt.Sprintf("virtualFields[%d].alias", i), "required")
+		}
+		if vf.Expression == "" {
+			v.addf(fmt.Sprintf("virtualFields[%d].expr", i), "required")
+		}
+	}
+
+	for i, p := range q.Projections {
+		if p.Field == "" {
+			v.addf(fmt.Sprintf("project[%d].field", i), "required")
+		}
+	}
+}
+
+// isGroupedOrAggregated returns true if the given field is grouped by or is
+// the field or alias of an aggregation.
+func (q Query) isGroupedOrAggregated(field string) bool {
+	for _, f := range q.GroupBy {
+		if f == field {
+			return true
+		}
+	}
+	for _, agg := range q.Aggregations {
+		if agg.Field == field || agg.Alias == field {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate validates the filter and its children and returns
+// `ValidationErrors` if it is invalid.
+func (f Filter) Validate() error {
+	var v validator
+	f.validate(&v, "")
+	return v.err()
+}
+
+func (f Filter) validate(v *validator, path string) {
+	switch f.Op {
+	case emptyFilterOp:
+		v.addf(join(path, "op"), "required")
+		return
+	case OpAnd, OpOr, OpNot:
+		if len(f.Children) == 0 {
+			v.addf(join(path, "children"), "%q filter requires at least one child", f.Op)
+		}
+		if f.Field != "" {
+			v.addf(join(path, "field"), "not valid for %q filter", f.Op)
+		}
+		if f.Value != nil {
+			v.addf(join(path, "value"), "not valid for %q filter", f.Op)
+		}
+		for i, child := range f.Children {
+			child.validate(v, fmt.Sprintf("%s[%d]", join(path, "children"), i))
+		}
+		return
+	}
+
+	if f.Field == "" {
+		v.addf(join(path, "field"), "required")
+	}
+	if len(f.Children) > 0 {
+		v.addf(join(path, "children"), "not valid for %q filter", f.Op)
+	}
+
+	valuePath := join(path, "value")
+	switch f.Op {
+	case OpExists, OpNotExists:
+		if f.Value != nil {
+			v.addf(valuePath, "not valid for %q filter", f.Op)
+		}
+	case OpEqual, OpNotEqual, OpContains, OpNotContains:
+		if f.Value == nil {
+			v.addf(valuePath, "required")
+		}
+	case OpGreaterThan, OpGreaterThanEqual, OpLessThan, OpLessThanEqual:
+		if !isNumber(f.Value) {
+			v.addf(valuePath, "%q filter requires a number, got %T", f.Op, f.Value)
+		}
+	case OpStartsWith, OpNotStartsWith, OpEndsWith, OpNotEndsWith:
+		if _, ok := f.Value.(string); !ok {
+			v.addf(valuePath, "%q filter requires a string, got %T", f.Op, f.Value)
+		}
+	case OpRegexp, OpNotRegexp:
+		if s, ok := f.Value.(string); !ok {
+			v.addf(valuePath, "%q filter requires a string, got %T", f.Op, f.Value)
+		} else if _, err := regexp.Compile(s); err != nil {
+			v.addf(valuePath, "invalid regular expression: %s", err)
+		}
+	default:
+		v.addf(join(path, "op"), "unknown filter operation %d", f.Op)
+	}
+
+	if f.CaseSensitive {
+		switch f.Op {
+		case OpStartsWith, OpNotStartsWith, OpEndsWith, OpNotEndsWith, OpContains, OpNotContains:
+		default:
+			v.addf(join(path, "caseSensitive"), "not valid for %q filter", f.Op)
+		}
+	}
+}
+
+// Validate validates the aggregation and returns `ValidationErrors` if it is
+// invalid.
+func (a Aggregation) Validate() error {
+	var v validator
+	a.validate(&v, "")
+	return v.err()
+}
+
+func (a Aggregation) validate(v *validator, path string) {
+	if a.Field == "" {
+		v.addf(join(path, "field"), "required")
+	}
+
+	argPath := join(path, "argument")
+	switch a.Op {
+	case emptyAggregationOp:
+		v.addf(join(path, "op"), "required")
+	case OpCount, OpCountDistinct, OpSum, OpAvg, OpMin, OpMax, OpVariance, OpStandardDeviation:
+		if a.Argument != nil {
+			v.addf(argPath, "not valid for %q aggregation", a.Op)
+		}
+	case OpTopk, OpHistogram:
+		if !isNumber(a.Argument) {
+			v.addf(argPath, "%q aggregation requires a number, got %T", a.Op, a.Argument)
+		} else if toFloat(a.Argument) <= 0 {
+			v.addf(argPath, "%q aggregation requires a number greater than zero", a.Op)
+		}
+	case OpPercentiles:
+		a.validatePercentiles(v, argPath)
+	case OpCountIf, OpCountDistinctIf:
+		v.addf(join(path, "op"), "%q aggregation is not valid for query requests", a.Op)
+	default:
+		v.addf(join(path, "op"), "unknown aggregation operation %d", a.Op)
+	}
+}
+
+func (a Aggregation) validatePercentiles(v *validator, path string) {
+	rv := reflect.ValueOf(a.Argument)
+	if k := rv.Kind(); k != reflect.Slice && k != reflect.Array {
+		v.addf(path, "%q aggregation requires a list of numbers, got %T", a.Op, a.Argument)
+		return
+	} else if rv.Len() == 0 {
+		v.addf(path, "%q aggregation requires at least one percentile", a.Op)
+		return
+	}
+
+	for i := 0; i < rv.Len(); i++ {
+		elem := rv.Index(i).Interface()
+		elemPath := fmt.Sprintf("%s[%d]", path, i)
+		if !isNumber(elem) {
+			v.addf(elemPath, "must be a number, got %T", elem)
+		} else if p := toFloat(elem); p < 0 || p > 100 {
+			v.addf(elemPath, "percentile %g out of range [0, 100]", p)
+		}
+	}
+}
+
+// join joins the given path with the given field name.
+func join(path, field string) string {
+	if path == "" {
+		return field
+	}
+	return path + "." + field
+}
+
+// isNumber returns true if the given value is of a numeric type.
+func isNumber(value interface{}) bool {
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// toFloat converts the given numeric value to a float64.
+func toFloat(value interface{}) float64 {
+	return reflect.ValueOf(value).Convert(reflect.TypeOf(float64(0))).Float()
+}
@@ -0,0 +1,190 @@
+package query
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuery_Validate(t *testing.T) {
+	endTime := time.Now()
+	startTime := endTime.Add(-time.Hour)
+
+	tests := []struct {
+		name  string
+		input Query
+		exp   ValidationErrors
+	}{
+		{
+			name: "valid",
+			input: Query{
+				StartTime: startTime,
+				EndTime:   endTime,
+				Aggregations: []Aggregation{
+					{Op: OpCount, Field: "*", Alias: "n"},
+					{Op: OpTopk, Field: "path", Argument: 10},
+				},
+				GroupBy: []string{"host"},
+				Filter: Filter{
+					Op: OpAnd,
+					Children: []Filter{
+						{Op: OpGreaterThanEqual, Field: "status", Value: 500},
+						{Op: OpExists, Field: "host"},
+					},
+				},
+				Order: []Order{{Field: "n", Desc: true}, {Field: "host"}},
+			},
+		},
+		{
+			name:  "missing time range",
+			input: Query{},
+			exp: ValidationErrors{
+				{Path: "startTime", Message: "required"},
+				{Path: "endTime", Message: "required"},
+			},
+		},
+		{
+			name: "inverted time range",
+			input: Query{
+				StartTime: endTime,
+				EndTime:   startTime,
+			},
+			exp: ValidationErrors{
+				{Path: "endTime", Message: "must not be before startTime"},
+			},
+		},
+		{
+			name: "resolution out of range",
+			input: Query{
+				StartTime:  startTime,
+				EndTime:    endTime,
+				Resolution: time.Hour,
+			},
+			exp: ValidationErrors{
+				{Path: "resolution", Message: "1h0m0s out of range [3.6s, 36s] for the time range of the query"},
+			},
+		},
+		{
+			name: "group by without aggregation",
+			input: Query{
+				StartTime: startTime,
+				EndTime:   endTime,
+				GroupBy:   []string{"host"},
+			},
+			exp: ValidationErrors{
+				{Path: "groupBy", Message: "requires at least one aggregation"},
+			},
+		},
+		{
+			name: "order on unknown field",
+			input: Query{
+				StartTime:    startTime,
+				EndTime:      endTime,
+				Aggregations: []Aggregation{{Op: OpCount, Field: "*"}},
+				Order:        []Order{{Field: "status"}},
+			},
+			exp: ValidationErrors{
+				{Path: "order[0].field", Message: `"status" is neither grouped by nor aggregated`},
+			},
+		},
+		{
+			name: "nested errors",
+			input: Query{
+				StartTime: startTime,
+				EndTime:   endTime,
+				Aggregations: []Aggregation{
+					{Op: OpCount, Field: "*"},
+					{Op: OpTopk, Field: "path"},
+				},
+				Filter: Filter{
+					Op: OpOr,
+					Children: []Filter{
+						{Op: OpAnd},
+						{Op: OpExists, Field: "host", Value: "foo"},
+					},
+				},
+				VirtualFields: []VirtualField{{Alias: "a"}},
+				Projections:   []Projection{{Alias: "b"}},
+			},
+			exp: ValidationErrors{
+				{Path: "aggregations[1].argument", Message: `"topk" aggregation requires a number, got <nil>`},
+				{Path: "filter.children[0].children", Message: `"and" filter requires at least one child`},
+				{Path: "filter.children[1].value", Message: `not valid for "exists" filter`},
+				{Path: "virtualFields[0].expr", Message: "required"},
+				{Path: "project[0].field", Message: "required"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.input.Validate()
+			if tt.exp == nil {
+				assert.NoError(t, err)
+				return
+			}
+
+			var act ValidationErrors
+			require.True(t, errors.As(err, &act))
+			assert.Equal(t, tt.exp, act)
+		})
+	}
+}
+
+func TestFilter_Validate(t *testing.T) {
+	tests := []struct {
+		name  string
+		input Filter
+		err   string
+	}{
+		{"valid", Filter{Op: OpStartsWith, Field: "a", Value: "b", CaseSensitive: true}, ""},
+		{"missing op", Filter{Field: "a"}, "invalid query: op: required"},
+		{"missing field", Filter{Op: OpEqual, Value: "b"}, "invalid query: field: required"},
+		{"missing value", Filter{Op: OpEqual, Field: "a"}, "invalid query: value: required"},
+		{"non-number", Filter{Op: OpLessThan, Field: "a", Value: "1"}, `invalid query: value: "<" filter requires a number, got string`},
+		{"non-string", Filter{Op: OpEndsWith, Field: "a", Value: 1}, `invalid query: value: "ends-with" filter requires a string, got int`},
+		{"invalid regexp", Filter{Op: OpRegexp, Field: "a", Value: "("}, "invalid query: value: invalid regular expression: error parsing regexp: missing closing ): `(`"},
+		{"case sensitive", Filter{Op: OpEqual, Field: "a", Value: "b", CaseSensitive: true}, `invalid query: caseSensitive: not valid for "==" filter`},
+		{"children", Filter{Op: OpEqual, Field: "a", Value: "b", Children: []Filter{{}}}, `invalid query: children: not valid for "==" filter`},
+		{"logical with field", Filter{Op: OpNot, Field: "a", Children: []Filter{{Op: OpExists, Field: "a"}}}, `invalid query: field: not valid for "not" filter`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.input.Validate()
+			if tt.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestAggregation_Validate(t *testing.T) {
+	tests := []struct {
+		name  string
+		input Aggregation
+		err   string
+	}{
+		{"valid", Aggregation{Op: OpPercentiles, Field: "a", Argument: []interface{}{50.0, 99}}, ""},
+		{"missing op", Aggregation{Field: "a"}, "invalid query: op: required"},
+		{"missing field", Aggregation{Op: OpSum}, "invalid query: field: required"},
+		{"unexpected argument", Aggregation{Op: OpSum, Field: "a", Argument: 1}, `invalid query: argument: not valid for "sum" aggregation`},
+		{"zero argument", Aggregation{Op: OpHistogram, Field: "a", Argument: 0}, `invalid query: argument: "histogram" aggregation requires a number greater than zero`},
+		{"no percentiles", Aggregation{Op: OpPercentiles, Field: "a", Argument: []float64{}}, `invalid query: argument: "percentiles" aggregation requires at least one percentile`},
+		{"percentile out of range", Aggregation{Op: OpPercentiles, Field: "a", Argument: []float64{50, 101}}, "invalid query: argument[1]: percentile 101 out of range [0, 100]"},
+		{"read-only op", Aggregation{Op: OpCountIf, Field: "a"}, `invalid query: op: "countif" aggregation is not valid for query requests`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.input.Validate()
+			if tt.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package axiom
+
+import (
+	"context"
+
+	"github.com/axiomhq/axiom-go/axiom/query"
+)
+
+// A QueryIterOption modifies the behaviour of a QueryIter.
+type QueryIterOption func(*QueryIter)
+
+// SetMaxRows caps the total number of entries returned by a QueryIter. No
+// further requests are issued once the cap is reached. Unlimited by default.
+func SetMaxRows(n uint64) QueryIterOption {
+	return func(it *QueryIter) {
+		it.maxRows = n
+	}
+}
+
+// QueryIter iterates over the entries matched by a query, transparently
+// issuing follow-up requests until the result is complete. Partial results are
+// completed by passing on the continuation token returned by the server. If the
+// query specifies a Limit, it is treated as the page size and subsequent pages
+// are requested using the cursor of the last entry of a full page. This
+// requires the query to be ordered by the `_time` field (the default).
+//
+// Only the matches of a query are iterated over. Use `DatasetsService.Query`
+// for aggregations.
+//
+// A QueryIter is not safe for concurrent use. Use it like this:
+//
+//	it := client.Datasets.QueryIter(ctx, "my-dataset", q, query.Options{})
+//	for it.Next() {
+//		entry := it.Entry()
+//		// ...
+//	}
+//	if err := it.Err(); err != nil {
+//		// ...
+//	}
+type QueryIter struct {
+	ctx      context.Context
+	datasets *DatasetsService
+	id       string
+	q        query.Query
+	opts     query.Options
+	maxRows  uint64
+
+	page   []query.Entry
+	pos    int
+	entry  query.Entry
+	status query.Status
+	rows   uint64
+	done   bool
+	err    error
+}
+
+// QueryIter returns an iterator over the entries matched by the given query
+// executed on the dataset identified by its id. No request is made before the
+// first call to `Next()`. The context is used for all requests issued by the
+// iterator.
+func (s *DatasetsService) QueryIter(ctx context.Context, id string, q query.Query, opts query.Options, options ...QueryIterOption) *QueryIter {
+	ctx = withOperation(ctx, "Datasets.QueryIter")
+
+	it := &QueryIter{
+		ctx:      ctx,
+		datasets: s,
+		id:       id,
+		q:        q,
+		opts:     opts,
+	}
+
+	// The cursors are needed to request subsequent pages.
+	it.q.IncludeCursor = true
+
+	// Apply supplied options.
+	for _, option := range options {
+		option(it)
+	}
+
+	return it
+}
+
+// Next advances the iterator to the next entry which is then available
+// through `Entry()`. It returns false when there are no more entries or an
+// error occurred, which is returned by `Err()`.
+func (it *QueryIter) Next() bool {
+	if it.err != nil || (it.maxRows > 0 && it.rows >= it.maxRows) {
+		return false
+	}
+
+	if it.err = it.ctx.Err(); it.err != nil {
+		return false
+	}
+
+	for it.pos >= len(it.page) {
+		if it.done {
+			return false
+		} else if it.err = it.fetch(); it.err != nil {
+			return false
+		}
+	}
+
+	it.entry = it.page[it.pos]
+	it.pos++
+	it.rows++
+
+	return true
+}
+
+// Entry returns the current entry.
+func (it *QueryIter) Entry() query.Entry {
+	return it.entry
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (it *QueryIter) Err() error {
+	return it.err
+}
+
+// Status returns the status of the most recently requested result page.
+func (it *QueryIter) Status() query.Status {
+	return it.status
+}
+
+// fetch requests the next result page and prepares the query for the one
+// after.
+func (it *QueryIter) fetch() error {
+	q := it.q
+
+	// Don't request more entries than needed to reach the row cap.
+	if remaining := it.maxRows - it.rows; it.maxRows > 0 && remaining < uint64(q.Limit) {
+		q.Limit = uint32(remaining)
+	}
+
+	res, err := it.datasets.Query(it.ctx, it.id, q, it.opts)
+	if err != nil {
+		return err
+	}
+
+	// Only save the query once.
+	it.opts.SaveKind = 0
+
+	it.status = res.Status
+	it.page, it.pos = res.Matches, 0
+
+	if res.Status.IsPartial && res.Status.ContinuationToken != "" &&
+		res.Status.ContinuationToken != q.ContinuationToken {
+		it.q.ContinuationToken = res.Status.ContinuationToken
+		return nil
+	}
+
+	cursor := nextCursor(q, res.Status)
+	if q.Limit == 0 || len(res.Matches) < int(q.Limit) || cursor == "" || cursor == q.Cursor {
+		it.done = true
+		return nil
+	}
+
+	it.q.Cursor = cursor
+	it.q.ContinuationToken = ""
+
+	return nil
+}
+
+// nextCursor returns the cursor to request the page following the given result
+// with. It is empty if the query is not ordered by time.
+func nextCursor(q query.Query, status query.Status) string {
+	if len(q.Order) == 0 {
+		// Results are ordered by descending time by default.
+		return status.MinCursor
+	} else if q.Order[0].Field != "_time" {
+		return ""
+	} else if q.Order[0].Desc {
+		return status.MinCursor
+	}
+	return status.MaxCursor
+}
@@ -0,0 +1,184 @@
+package axiom
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/axiomhq/axiom-go/axiom/query"
+)
+
+// queryPageHandler returns a handler that serves the given number of entries,
+// ordered by descending row ID, in pages of the size given by the query limit.
+// The row ID of an entry doubles as its cursor.
+func queryPageHandler(t *testing.T, total int, requests *uint64) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddUint64(requests, 1)
+
+		var q query.Query
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&q))
+		assert.True(t, q.IncludeCursor)
+
+		start := total
+		if q.Cursor != "" {
+			var err error
+			start, err = strconv.Atoi(q.Cursor)
+			require.NoError(t, err)
+		}
+
+		var res query.Result
+		for i := start - 1; i >= 0 && (q.Limit == 0 || len(res.Matches) < int(q.Limit)); i-- {
+			res.Matches = append(res.Matches, query.Entry{
+				RowID: strconv.Itoa(i),
+			})
+		}
+		if n := len(res.Matches); n > 0 {
+			res.Status.MaxCursor = res.Matches[0].RowID
+			res.Status.MinCursor = res.Matches[n-1].RowID
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		require.NoError(t, json.NewEncoder(w).Encode(res))
+	}
+}
+
+func TestDatasetsService_QueryIter(t *testing.T) {
+	var requests uint64
+	client, teardown := setup(t, "/api/v1/datasets/test/query", queryPageHandler(t, 5, &requests))
+	defer teardown()
+
+	it := client.Datasets.QueryIter(context.Background(), "test", query.Query{
+		Limit: 2,
+	}, query.Options{})
+
+	var rowIDs []string
+	for it.Next() {
+		rowIDs = append(rowIDs, it.Entry().RowID)
+	}
+	require.NoError(t, it.Err())
+
+	assert.Equal(t, []string{"4", "3", "2", "1", "0"}, rowIDs)
+	assert.EqualValues(t, 3, atomic.LoadUint64(&requests))
+	assert.False(t, it.Next())
+}
+
+func TestDatasetsService_QueryIter_MaxRows(t *testing.T) {
+	var requests uint64
+	client, teardown := setup(t, "/api/v1/datasets/test/query", queryPageHandler(t, 10, &requests))
+	defer teardown()
+
+	it := client.Datasets.QueryIter(context.Background(), "test", query.Query{
+		Limit: 2,
+	}, query.Options{}, SetMaxRows(3))
+
+	var rowIDs []string
+	for it.Next() {
+		rowIDs = append(rowIDs, it.Entry().RowID)
+	}
+	require.NoError(t, it.Err())
+
+	assert.Equal(t, []string{"9", "8", "7"}, rowIDs)
+	assert.EqualValues(t, 2, atomic.LoadUint64(&requests))
+}
+
+func TestDatasetsService_QueryIter_ContinuationToken(t *testing.T) {
+	var requests uint64
+	hf := func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddUint64(&requests, 1)
+
+		var q query.Query
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&q))
+
+		res := query.Result{
+			Matches: []query.Entry{{RowID: strconv.FormatUint(n, 10)}},
+		}
+		switch n {
+		case 1:
+			assert.Empty(t, q.ContinuationToken)
+			res.Status.IsPartial = true
+			res.Status.ContinuationToken = "abc"
+		case 2:
+			assert.Equal(t, "abc", q.ContinuationToken)
+		default:
+			t.Fatalf("unexpected request %d", n)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		require.NoError(t, json.NewEncoder(w).Encode(res))
+	}
+
+	client, teardown := setup(t, "/api/v1/datasets/test/query", hf)
+	defer teardown()
+
+	it := client.Datasets.QueryIter(context.Background(), "test", query.Query{}, query.Options{})
+
+	var rowIDs []string
+	for it.Next() {
+		rowIDs = append(rowIDs, it.Entry().RowID)
+	}
+	require.NoError(t, it.Err())
+
+	assert.Equal(t, []string{"1", "2"}, rowIDs)
+}
+
+func TestDatasetsService_QueryIter_Canceled(t *testing.T) {
+	var requests uint64
+	client, teardown := setup(t, "/api/v1/datasets/test/query", queryPageHandler(t, 10, &requests))
+	defer teardown()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	it := client.Datasets.QueryIter(ctx, "test", query.Query{
+		Limit: 2,
+	}, query.Options{})
+
+	require.True(t, it.Next())
+	cancel()
+	assert.False(t, it.Next())
+	assert.ErrorIs(t, it.Err(), context.Canceled)
+	assert.EqualValues(t, 1, atomic.LoadUint64(&requests))
+}
+
+func TestDatasetsService_QueryIter_Error(t *testing.T) {
+	hf := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}
+
+	client, teardown := setup(t, "/api/v1/datasets/test/query", hf)
+	defer teardown()
+
+	it := client.Datasets.QueryIter(context.Background(), "test", query.Query{}, query.Options{})
+
+	assert.False(t, it.Next())
+	assert.EqualError(t, it.Err(), "API error 404: Not Found")
+}
+
+func TestNextCursor(t *testing.T) {
+	status := query.Status{
+		MinCursor: "min",
+		MaxCursor: "max",
+	}
+
+	tests := []struct {
+		name  string
+		order []query.Order
+		exp   string
+	}{
+		{"default", nil, "min"},
+		{"time descending", []query.Order{{Field: "_time", Desc: true}}, "min"},
+		{"time ascending", []query.Order{{Field: "_time"}}, "max"},
+		{"other field", []query.Order{{Field: "status"}}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.exp, nextCursor(query.Query{Order: tt.order}, status))
+		})
+	}
+}
@@ -0,0 +1,219 @@
+package axiom
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ErrNotRetryable is returned alongside the original error when a request
+// qualified for a retry but its body could not be replayed. Enable
+// `RetryPolicy.BufferBody` to make such requests retryable.
+var ErrNotRetryable = errors.New("request body is not replayable")
+
+// RetryPolicy configures how the client retries failed requests. Requests are
+// retried when the server responds with one of the status codes 429, 502, 503
+// or 504 or when an idempotent request (GET, HEAD, OPTIONS, PUT, DELETE) fails
+// on the transport level. A `Retry-After` header sent by the server takes
+// precedence over the computed backoff. Without it, a request that exceeded a
+// limit is retried once the limit resets.
+//
+// The zero value disables retries.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts made for a single request,
+	// including the initial one. A value of zero or one disables retries.
+	MaxAttempts int
+	// BaseBackoff is the backoff before the first retry. It doubles with every
+	// subsequent retry.
+	BaseBackoff time.Duration
+	// MaxBackoff caps the backoff between two attempts. It does not cap the
+	// delay requested by the server using the `Retry-After` header.
+	MaxBackoff time.Duration
+	// Jitter is the fraction of the backoff, in the range [0, 1], that is
+	// randomly subtracted from it to spread retries of concurrent requests.
+	Jitter float64
+	// BufferBody buffers request bodies that can't be replayed (e.g. the
+	// `io.Reader` returned by `GzipEncoder`) in memory before sending them, so
+	// they can be retried. Without it, such requests are not retried and
+	// `ErrNotRetryable` is returned alongside the original error.
+	BufferBody bool
+}
+
+// DefaultRetryPolicy returns a sensible retry policy which makes up to three
+// attempts with a backoff between 250ms and 5s.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		BaseBackoff: 250 * time.Millisecond,
+		MaxBackoff:  5 * time.Second,
+		Jitter:      0.2,
+	}
+}
+
+// enabled returns true if the policy allows for at least one retry.
+func (p RetryPolicy) enabled() bool {
+	return p.MaxAttempts > 1
+}
+
+// shouldRetry returns true if the outcome of the given attempt qualifies for a
+// retry.
+func (p RetryPolicy) shouldRetry(req *http.Request, resp *response, err error, attempt int) bool {
+	if attempt >= p.MaxAttempts || req.Context().Err() != nil {
+		return false
+	}
+
+	// A transport level error without a response.
+	if resp == nil {
+		return err != nil && isIdempotent(req.Method)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// backoff returns the duration to wait before the next attempt.
+func (p RetryPolicy) backoff(resp *response, attempt int) time.Duration {
+	if resp != nil {
+		if d, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			return d
+		}
+
+		// Without an explicit delay, wait for an exceeded limit to reset.
+		if resp.StatusCode == http.StatusTooManyRequests && !resp.Limit.Reset.IsZero() {
+			if d := time.Until(resp.Limit.Reset); d > 0 {
+				return d
+			}
+		}
+	}
+
+	d := p.BaseBackoff
+	for i := 1; i < attempt && (p.MaxBackoff <= 0 || d < p.MaxBackoff); i++ {
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+
+	if p.Jitter > 0 {
+		jitter := p.Jitter
+		if jitter > 1 {
+			jitter = 1
+		}
+		//nolint:gosec // No need for a cryptographically secure source here.
+		d -= time.Duration(jitter * rand.Float64() * float64(d))
+	}
+
+	return d
+}
+
+// parseRetryAfter parses the value of a `Retry-After` header which is either
+// given in seconds or as an HTTP date.
+func parseRetryAfter(s string, now time.Time) (time.Duration, bool) {
+	if s == "" {
+		return 0, false
+	}
+
+	if secs, err := strconv.ParseUint(s, 10, 32); err == nil {
+		return time.Duration(secs) * time.Second, true
+	}
+
+	if t, err := http.ParseTime(s); err == nil {
+		if d := t.Sub(now); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+
+	return 0, false
+}
+
+// isIdempotent returns true if the given HTTP method is idempotent as defined
+// by RFC 7231.
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// bufferBody reads the body of the given request into memory and makes it
+// replayable.
+func bufferBody(req *http.Request) error {
+	if req.Body == nil || req.Body == http.NoBody || req.GetBody != nil {
+		return nil
+	}
+
+	b, err := io.ReadAll(req.Body)
+	if closeErr := req.Body.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	req.ContentLength = int64(len(b))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(b)), nil
+	}
+	req.Body, _ = req.GetBody()
+
+	return nil
+}
+
+// rewindBody resets the body of the given request so it can be sent again.
+func rewindBody(req *http.Request) (err error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil
+	} else if req.GetBody == nil {
+		return ErrNotRetryable
+	}
+	req.Body, err = req.GetBody()
+	return err
+}
+
+// sleep waits for the given duration or until the context is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// notRetryableError wraps an error that qualified for a retry which couldn't
+// be performed because the request body is not replayable. It matches both,
+// the original error and `ErrNotRetryable`, when used with `errors.Is()`.
+type notRetryableError struct {
+	err error
+}
+
+// Error implements the error interface.
+func (e notRetryableError) Error() string {
+	return e.err.Error() + ": " + ErrNotRetryable.Error()
+}
+
+// Unwrap returns the original error.
+func (e notRetryableError) Unwrap() error {
+	return e.err
+}
+
+// Is reports whether target is `ErrNotRetryable`.
+func (e notRetryableError) Is(target error) bool {
+	return target == ErrNotRetryable
+}
@@ -0,0 +1,248 @@
+package axiom
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseBackoff: time.Millisecond,
+	MaxBackoff:  10 * time.Millisecond,
+}
+
+func TestClient_Options_SetRetryPolicy(t *testing.T) {
+	client := newClient(t)
+
+	exp := DefaultRetryPolicy()
+	opt := SetRetryPolicy(exp)
+
+	err := client.Options(opt)
+	assert.NoError(t, err)
+
+	assert.Equal(t, exp, client.retryPolicy)
+}
+
+func TestClient_do_Retry(t *testing.T) {
+	tests := []struct {
+		code     int
+		attempts uint64
+	}{
+		{http.StatusTooManyRequests, 3},
+		{http.StatusBadGateway, 3},
+		{http.StatusServiceUnavailable, 3},
+		{http.StatusGatewayTimeout, 3},
+		{http.StatusInternalServerError, 1},
+		{http.StatusBadRequest, 1},
+	}
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.code), func(t *testing.T) {
+			var attempts uint64
+			hf := func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddUint64(&attempts, 1)
+				w.WriteHeader(tt.code)
+			}
+
+			client, teardown := setup(t, "/", hf)
+			defer teardown()
+
+			err := client.Options(SetRetryPolicy(testRetryPolicy))
+			require.NoError(t, err)
+
+			req, err := client.newRequest(context.Background(), http.MethodGet, "/", nil)
+			require.NoError(t, err)
+
+			_, err = client.do(req, nil)
+			require.Error(t, err)
+
+			assert.Equal(t, tt.attempts, atomic.LoadUint64(&attempts))
+		})
+	}
+}
+
+func TestClient_do_RetrySucceeds(t *testing.T) {
+	var attempts uint64
+	hf := func(w http.ResponseWriter, r *http.Request) {
+		b, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		assert.Equal(t, "{\"A\":\"a\"}\n", string(b))
+
+		if atomic.AddUint64(&attempts, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = fmt.Fprint(w, `{"A":"b"}`)
+	}
+
+	client, teardown := setup(t, "/", hf)
+	defer teardown()
+
+	err := client.Options(SetRetryPolicy(testRetryPolicy))
+	require.NoError(t, err)
+
+	type foo struct {
+		A string
+	}
+
+	req, err := client.newRequest(context.Background(), http.MethodPost, "/", foo{"a"})
+	require.NoError(t, err)
+
+	var body foo
+	_, err = client.do(req, &body)
+	require.NoError(t, err)
+
+	assert.Equal(t, foo{"b"}, body)
+	assert.EqualValues(t, 3, atomic.LoadUint64(&attempts))
+}
+
+func TestClient_do_RetryAfter(t *testing.T) {
+	var attempts uint64
+	hf := func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddUint64(&attempts, 1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+
+	client, teardown := setup(t, "/", hf)
+	defer teardown()
+
+	err := client.Options(SetRetryPolicy(testRetryPolicy))
+	require.NoError(t, err)
+
+	req, err := client.newRequest(context.Background(), http.MethodGet, "/", nil)
+	require.NoError(t, err)
+
+	start := time.Now()
+	_, err = client.do(req, nil)
+	require.NoError(t, err)
+
+	assert.GreaterOrEqual(t, time.Since(start), time.Second)
+	assert.EqualValues(t, 2, atomic.LoadUint64(&attempts))
+}
+
+func TestClient_do_RetryNotReplayable(t *testing.T) {
+	var attempts uint64
+	hf := func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		atomic.AddUint64(&attempts, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	client, teardown := setup(t, "/", hf)
+	defer teardown()
+
+	err := client.Options(SetRetryPolicy(testRetryPolicy))
+	require.NoError(t, err)
+
+	r, err := GzipEncoder(strings.NewReader(`{"a":"b"}`))
+	require.NoError(t, err)
+
+	req, err := client.newRequest(context.Background(), http.MethodPost, "/", r)
+	require.NoError(t, err)
+
+	_, err = client.do(req, nil)
+	require.ErrorIs(t, err, ErrNotRetryable)
+
+	var apiErr Error
+	if assert.True(t, errors.As(err, &apiErr)) {
+		assert.Equal(t, http.StatusServiceUnavailable, apiErr.Status)
+	}
+	assert.EqualValues(t, 1, atomic.LoadUint64(&attempts))
+}
+
+func TestClient_do_RetryBufferBody(t *testing.T) {
+	var attempts uint64
+	hf := func(w http.ResponseWriter, r *http.Request) {
+		b, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		assert.Equal(t, `{"a":"b"}`, string(b))
+
+		if atomic.AddUint64(&attempts, 1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+
+	client, teardown := setup(t, "/", hf)
+	defer teardown()
+
+	policy := testRetryPolicy
+	policy.BufferBody = true
+
+	err := client.Options(SetRetryPolicy(policy))
+	require.NoError(t, err)
+
+	pr, pw := io.Pipe()
+	go func() {
+		_, _ = pw.Write([]byte(`{"a":"b"}`))
+		_ = pw.Close()
+	}()
+
+	req, err := client.newRequest(context.Background(), http.MethodPost, "/", pr)
+	require.NoError(t, err)
+
+	_, err = client.do(req, nil)
+	require.NoError(t, err)
+
+	assert.EqualValues(t, 2, atomic.LoadUint64(&attempts))
+}
+
+func TestRetryPolicy_backoff(t *testing.T) {
+	policy := RetryPolicy{
+		MaxAttempts: 10,
+		BaseBackoff: 100 * time.Millisecond,
+		MaxBackoff:  time.Second,
+	}
+
+	assert.Equal(t, 100*time.Millisecond, policy.backoff(nil, 1))
+	assert.Equal(t, 200*time.Millisecond, policy.backoff(nil, 2))
+	assert.Equal(t, 400*time.Millisecond, policy.backoff(nil, 3))
+	assert.Equal(t, time.Second, policy.backoff(nil, 5))
+	assert.Equal(t, time.Second, policy.backoff(nil, 9))
+
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		d := policy.backoff(nil, 1)
+		assert.GreaterOrEqual(t, d, 50*time.Millisecond)
+		assert.LessOrEqual(t, d, 100*time.Millisecond)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2022, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		input string
+		exp   time.Duration
+		ok    bool
+	}{
+		{"", 0, false},
+		{"abc", 0, false},
+		{"-1", 0, false},
+		{"0", 0, true},
+		{"120", 2 * time.Minute, true},
+		{now.Add(30 * time.Second).Format(http.TimeFormat), 30 * time.Second, true},
+		{now.Add(-30 * time.Second).Format(http.TimeFormat), 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			d, ok := parseRetryAfter(tt.input, now)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.exp, d)
+		})
+	}
+}
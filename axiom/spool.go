@@ -0,0 +1,516 @@
+package axiom
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	segmentSuffix = ".ndjson.gz"
+	failedSuffix  = ".failed"
+
+	defaultMaxSegmentSize = 16 << 20 // 16 MiB
+	defaultMaxSegmentAge  = time.Minute
+)
+
+// A SpoolOption modifies the behaviour of a Spool.
+type SpoolOption func(*Spool) error
+
+// SetMaxSegmentSize specifies the size in bytes a segment file can grow to
+// before a new one is started. Defaults to 16 MiB.
+func SetMaxSegmentSize(n int64) SpoolOption {
+	return func(s *Spool) error {
+		if n <= 0 {
+			return errors.New("max segment size must be greater than zero")
+		}
+		s.maxSegmentSize = n
+		return nil
+	}
+}
+
+// SetMaxSegmentAge specifies the age a segment file can reach before a new one
+// is started. Defaults to one minute.
+func SetMaxSegmentAge(d time.Duration) SpoolOption {
+	return func(s *Spool) error {
+		if d <= 0 {
+			return errors.New("max segment age must be greater than zero")
+		}
+		s.maxSegmentAge = d
+		return nil
+	}
+}
+
+// SetMaxSpoolSize specifies the total size in bytes of all segment files. When
+// it is exceeded, the oldest segments are discarded. Unlimited by default.
+func SetMaxSpoolSize(n int64) SpoolOption {
+	return func(s *Spool) error {
+		s.maxSize = n
+		return nil
+	}
+}
+
+// SetMaxSpoolAge specifies the age after which segment files are discarded
+// without being replayed. Unlimited by default.
+func SetMaxSpoolAge(d time.Duration) SpoolOption {
+	return func(s *Spool) error {
+		s.maxAge = d
+		return nil
+	}
+}
+
+var _ error = QuarantineError{}
+
+// QuarantineError is returned by `Spool.Replay()` when segments were rejected
+// by the server with a permanent error and have been moved aside. The
+// quarantined segment files are kept in the spool directory for inspection but
+// are neither replayed nor picked up again by `OpenSpool()`.
+type QuarantineError struct {
+	// Paths of the quarantined segment files.
+	Paths []string
+	// Err is the error the first quarantined segment was rejected with.
+	Err error
+}
+
+// Error implements the error interface.
+func (e QuarantineError) Error() string {
+	return fmt.Sprintf("quarantined %d spool segment(s) rejected by the server: %s", len(e.Paths), e.Err)
+}
+
+// Unwrap returns the error the first quarantined segment was rejected with.
+func (e QuarantineError) Unwrap() error {
+	return e.Err
+}
+
+// segment is a spool segment file. Its name is the time it was created at in
+// nanoseconds since the Unix epoch.
+type segment struct {
+	path    string
+	created time.Time
+	size    int64
+}
+
+// Spool is a durable, on-disk write-ahead spool for events that couldn't be
+// ingested. Events are stored as gzip compressed NDJSON in segment files
+// inside a directory and can be replayed in the order they were written once
+// the Axiom API is reachable again. It is safe for concurrent use.
+//
+// Pass a Spool to an Ingester using the `SetSpool` option to have it spool
+// batches that failed to ingest and replay them automatically.
+type Spool struct {
+	dir string
+
+	maxSegmentSize int64
+	maxSegmentAge  time.Duration
+	maxSize        int64
+	maxAge         time.Duration
+
+	segments []*segment
+	current  *os.File
+	mtx      sync.Mutex
+
+	replayMtx sync.Mutex
+}
+
+// OpenSpool opens the spool in the given directory. The directory is created,
+// if it doesn't exist. Segments left behind by a previous spool in the same
+// directory are picked up and replayed first, unless they exceed the age limit
+// configured by `SetMaxSpoolAge()`.
+func OpenSpool(dir string, options ...SpoolOption) (*Spool, error) {
+	s := &Spool{
+		dir: dir,
+
+		maxSegmentSize: defaultMaxSegmentSize,
+		maxSegmentAge:  defaultMaxSegmentAge,
+	}
+
+	// Apply supplied options.
+	for _, option := range options {
+		if err := option(s); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, segmentSuffix) {
+			continue
+		}
+
+		nanos, err := strconv.ParseInt(strings.TrimSuffix(name, segmentSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+
+		s.segments = append(s.segments, &segment{
+			path:    filepath.Join(dir, name),
+			created: time.Unix(0, nanos),
+			size:    info.Size(),
+		})
+	}
+
+	sort.Slice(s.segments, func(i, j int) bool {
+		return s.segments[i].created.Before(s.segments[j].created)
+	})
+
+	if err = s.expire(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Dir returns the directory of the spool.
+func (s *Spool) Dir() string {
+	return s.dir
+}
+
+// Pending returns true if the spool holds data that awaits replay.
+func (s *Spool) Pending() bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return len(s.segments) > 0
+}
+
+// Size returns the total size in bytes of all segment files.
+func (s *Spool) Size() int64 {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	var size int64
+	for _, seg := range s.segments {
+		size += seg.size
+	}
+	return size
+}
+
+// AppendEvents writes the given events to the spool.
+func (s *Spool) AppendEvents(events ...Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		var (
+			enc    = json.NewEncoder(pw)
+			encErr error
+		)
+		for _, event := range events {
+			if encErr = enc.Encode(event); encErr != nil {
+				break
+			}
+		}
+		_ = pw.CloseWithError(encErr)
+	}()
+
+	return s.Append(pr)
+}
+
+// Append writes the uncompressed NDJSON data read from the given reader to the
+// spool.
+func (s *Spool) Append(r io.Reader) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	seg, err := s.currentSegment()
+	if err != nil {
+		return err
+	}
+
+	// A failed append is rolled back to where it started. Otherwise, a partial
+	// NDJSON line would break the replay of the whole segment.
+	info, err := s.current.Stat()
+	if err != nil {
+		return err
+	}
+	offset := info.Size()
+
+	if err = s.writeMember(r); err != nil {
+		if truncErr := s.current.Truncate(offset); truncErr != nil {
+			return fmt.Errorf("%w (failed to roll back partial write: %s)", err, truncErr)
+		}
+		return err
+	}
+
+	if info, err = s.current.Stat(); err != nil {
+		return err
+	}
+	seg.size = info.Size()
+
+	if seg.size >= s.maxSegmentSize || time.Since(seg.created) >= s.maxSegmentAge {
+		if err = s.rotate(); err != nil {
+			return err
+		}
+	}
+
+	return s.enforceLimits()
+}
+
+// Replay ingests all spooled data into the dataset identified by its id in the
+// order it was written. Segments are removed after they have been ingested.
+//
+// Segments rejected by the server with a permanent error (e.g. a 4xx status
+// code) are moved aside by renaming them to carry a `.failed` suffix, so they
+// don't block the replay of subsequent segments. They are reported by a
+// QuarantineError which is returned once all other segments have been
+// replayed. Replay stops at the first segment that fails to ingest because of
+// a temporary error, leaving it and all subsequent segments in place for the
+// next replay. The returned status is the aggregate of all ingested segments.
+func (s *Spool) Replay(ctx context.Context, client *Client, id string, opts IngestOptions) (*IngestStatus, error) {
+	s.replayMtx.Lock()
+	defer s.replayMtx.Unlock()
+
+	s.mtx.Lock()
+	err := s.rotate()
+	if err == nil {
+		err = s.expire()
+	}
+	segments := make([]*segment, len(s.segments))
+	copy(segments, s.segments)
+	s.mtx.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		status      IngestStatus
+		quarantined QuarantineError
+	)
+	for _, seg := range segments {
+		if seg.size == 0 {
+			if err = s.remove(seg); err != nil {
+				return &status, err
+			}
+			continue
+		}
+
+		res, err := s.replaySegment(ctx, client, id, opts, seg)
+		if err != nil && IsTemporary(err) {
+			return &status, fmt.Errorf("replay segment %s: %w", filepath.Base(seg.path), err)
+		} else if err != nil {
+			path, quarantineErr := s.quarantine(seg)
+			if quarantineErr != nil {
+				return &status, fmt.Errorf("replay segment %s: %w (failed to quarantine: %s)",
+					filepath.Base(seg.path), err, quarantineErr)
+			}
+			if quarantined.Err == nil {
+				quarantined.Err = err
+			}
+			quarantined.Paths = append(quarantined.Paths, path)
+			continue
+		}
+
+		status.Ingested += res.Ingested
+		status.Failed += res.Failed
+		status.Failures = append(status.Failures, res.Failures...)
+		status.ProcessedBytes += res.ProcessedBytes
+		status.BlocksCreated += res.BlocksCreated
+		status.WALLength = res.WALLength
+
+		if err = s.remove(seg); err != nil {
+			return &status, err
+		}
+	}
+
+	if len(quarantined.Paths) > 0 {
+		return &status, quarantined
+	}
+
+	return &status, nil
+}
+
+// Close the spool. Data that has not been replayed remains on disk and is
+// picked up by the next spool opened in the same directory.
+func (s *Spool) Close() error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return s.rotate()
+}
+
+func (s *Spool) replaySegment(ctx context.Context, client *Client, id string, opts IngestOptions, seg *segment) (*IngestStatus, error) {
+	f, err := os.Open(seg.path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return client.Datasets.Ingest(ctx, id, f, NDJSON, Gzip, opts)
+}
+
+// writeMember writes the data read from the given reader to the segment
+// currently written to. Every append is written as a separate gzip member.
+// Concatenated members form a valid gzip stream. The lock must be held by the
+// caller.
+func (s *Spool) writeMember(r io.Reader) error {
+	gzw, err := gzip.NewWriterLevel(s.current, gzip.BestSpeed)
+	if err != nil {
+		return err
+	}
+
+	if _, err = io.Copy(gzw, r); err != nil {
+		return err
+	} else if err = gzw.Close(); err != nil {
+		return err
+	}
+
+	return s.current.Sync()
+}
+
+// currentSegment returns the segment currently written to and creates it, if
+// necessary. The lock must be held by the caller.
+func (s *Spool) currentSegment() (*segment, error) {
+	if s.current != nil {
+		return s.segments[len(s.segments)-1], nil
+	}
+
+	created := time.Now()
+	if n := len(s.segments); n > 0 && !created.After(s.segments[n-1].created) {
+		created = s.segments[n-1].created.Add(time.Nanosecond)
+	}
+
+	seg := &segment{
+		path:    filepath.Join(s.dir, strconv.FormatInt(created.UnixNano(), 10)+segmentSuffix),
+		created: created,
+	}
+
+	f, err := os.OpenFile(seg.path, os.O_CREATE|os.O_EXCL|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return nil, err
+	}
+
+	s.current = f
+	s.segments = append(s.segments, seg)
+
+	return seg, nil
+}
+
+// rotate closes the segment currently written to. The lock must be held by the
+// caller.
+func (s *Spool) rotate() error {
+	if s.current == nil {
+		return nil
+	}
+
+	err := s.current.Close()
+	s.current = nil
+
+	return err
+}
+
+// enforceLimits discards the oldest segments that exceed the configured size
+// and age limits. The newest segment is never discarded. The lock must be held
+// by the caller.
+func (s *Spool) enforceLimits() error {
+	var total int64
+	for _, seg := range s.segments {
+		total += seg.size
+	}
+
+	for len(s.segments) > 1 {
+		oldest := s.segments[0]
+
+		tooBig := s.maxSize > 0 && total > s.maxSize
+		tooOld := s.maxAge > 0 && time.Since(oldest.created) > s.maxAge
+		if !tooBig && !tooOld {
+			break
+		}
+
+		if err := os.Remove(oldest.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		total -= oldest.size
+		s.segments = s.segments[1:]
+	}
+
+	return nil
+}
+
+// expire discards all segments that exceed the configured age limit. Unlike
+// enforceLimits, it doesn't spare the newest segment, so it must only be
+// called when no segment is written to. The lock must be held by the caller.
+func (s *Spool) expire() error {
+	if s.maxAge <= 0 {
+		return nil
+	}
+
+	for len(s.segments) > 0 {
+		oldest := s.segments[0]
+		if time.Since(oldest.created) <= s.maxAge {
+			break
+		}
+
+		if err := os.Remove(oldest.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		s.segments = s.segments[1:]
+	}
+
+	return nil
+}
+
+// remove deletes the given segment from the spool.
+func (s *Spool) remove(seg *segment) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.forget(seg)
+
+	if err := os.Remove(seg.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// quarantine moves the given segment out of the spool by renaming its file. It
+// returns the new path of the file.
+func (s *Spool) quarantine(seg *segment) (string, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	path := seg.path + failedSuffix
+	if err := os.Rename(seg.path, path); err != nil {
+		return "", err
+	}
+
+	s.forget(seg)
+
+	return path, nil
+}
+
+// forget removes the given segment from the list of segments. The lock must be
+// held by the caller.
+func (s *Spool) forget(seg *segment) {
+	for i, other := range s.segments {
+		if other == seg {
+			s.segments = append(s.segments[:i], s.segments[i+1:]...)
+			break
+		}
+	}
+}
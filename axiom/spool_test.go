@@ -0,0 +1,392 @@
+package axiom
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenSpool(t *testing.T) {
+	dir := t.TempDir()
+
+	spool, err := OpenSpool(dir)
+	require.NoError(t, err)
+
+	assert.Equal(t, dir, spool.Dir())
+	assert.False(t, spool.Pending())
+	assert.Zero(t, spool.Size())
+
+	require.NoError(t, spool.AppendEvents(Event{"a": "b"}))
+	require.NoError(t, spool.Close())
+
+	// A stray file must be ignored.
+	require.NoError(t, os.WriteFile(dir+"/foo.txt", []byte("bar"), 0o640))
+
+	spool, err = OpenSpool(dir)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, spool.Close())
+	}()
+
+	assert.True(t, spool.Pending())
+	assert.NotZero(t, spool.Size())
+	assert.Len(t, spool.segments, 1)
+
+	_, err = OpenSpool(dir, SetMaxSegmentSize(0))
+	assert.Error(t, err)
+}
+
+func TestSpool_Rotation(t *testing.T) {
+	spool, err := OpenSpool(t.TempDir(), SetMaxSegmentSize(1))
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, spool.Close())
+	}()
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, spool.AppendEvents(Event{"i": i}))
+	}
+
+	// Every append exceeds the segment size and starts a new segment.
+	assert.Len(t, spool.segments, 3)
+	assert.Nil(t, spool.current)
+}
+
+func TestSpool_Limits(t *testing.T) {
+	spool, err := OpenSpool(t.TempDir(),
+		SetMaxSegmentSize(1),
+		SetMaxSpoolSize(1),
+	)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, spool.Close())
+	}()
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, spool.AppendEvents(Event{"i": i}))
+	}
+
+	// Only the last segment is kept.
+	require.Len(t, spool.segments, 1)
+
+	events := readSegment(t, spool.segments[0].path)
+	assert.Equal(t, []Event{{"i": float64(2)}}, events)
+}
+
+func TestSpool_AppendFailure(t *testing.T) {
+	spool, err := OpenSpool(t.TempDir())
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, spool.Close())
+	}()
+
+	require.NoError(t, spool.AppendEvents(Event{"i": 0}))
+
+	// The reader fails in the middle of a line, which must not end up in the
+	// segment.
+	r := io.MultiReader(strings.NewReader(`{"i":1}`+"\n"+`{"i":`), iotest.ErrReader(io.ErrUnexpectedEOF))
+	require.ErrorIs(t, spool.Append(r), io.ErrUnexpectedEOF)
+
+	require.NoError(t, spool.AppendEvents(Event{"i": 2}))
+
+	require.Len(t, spool.segments, 1)
+	events := readSegment(t, spool.segments[0].path)
+	assert.Equal(t, []Event{{"i": float64(0)}, {"i": float64(2)}}, events)
+}
+
+func TestSpool_Replay(t *testing.T) {
+	var (
+		received []interface{}
+		mtx      sync.Mutex
+		fail     uint64 = 1
+	)
+	hf := func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadUint64(&fail) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		assert.Equal(t, "gzip", r.Header.Get("Content-Encoding"))
+
+		gzr, err := gzip.NewReader(r.Body)
+		require.NoError(t, err)
+
+		events := decodeEvents(t, gzr)
+		mtx.Lock()
+		for _, event := range events {
+			received = append(received, event["i"])
+		}
+		mtx.Unlock()
+
+		_, _ = fmt.Fprintf(w, `{"ingested": %d}`, len(events))
+	}
+
+	client, teardown := setup(t, "/api/v1/datasets/test/ingest", hf)
+	defer teardown()
+
+	spool, err := OpenSpool(t.TempDir(), SetMaxSegmentSize(1))
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, spool.Close())
+	}()
+
+	require.NoError(t, spool.AppendEvents(Event{"i": 0}, Event{"i": 1}))
+	require.NoError(t, spool.AppendEvents(Event{"i": 2}))
+
+	_, err = spool.Replay(context.Background(), client, "test", IngestOptions{})
+	require.Error(t, err)
+	assert.True(t, spool.Pending(), "failed segments must remain in place")
+
+	atomic.StoreUint64(&fail, 0)
+
+	res, err := spool.Replay(context.Background(), client, "test", IngestOptions{})
+	require.NoError(t, err)
+
+	assert.EqualValues(t, 3, res.Ingested)
+	assert.False(t, spool.Pending())
+	assert.Equal(t, []interface{}{float64(0), float64(1), float64(2)}, received)
+}
+
+func TestSpool_ReplayMaxAge(t *testing.T) {
+	var received []interface{}
+	hf := func(w http.ResponseWriter, r *http.Request) {
+		gzr, err := gzip.NewReader(r.Body)
+		require.NoError(t, err)
+
+		events := decodeEvents(t, gzr)
+		for _, event := range events {
+			received = append(received, event["i"])
+		}
+
+		_, _ = fmt.Fprintf(w, `{"ingested": %d}`, len(events))
+	}
+
+	client, teardown := setup(t, "/api/v1/datasets/test/ingest", hf)
+	defer teardown()
+
+	dir := t.TempDir()
+
+	// Leave behind a segment that is older than the age limit.
+	spool, err := OpenSpool(dir)
+	require.NoError(t, err)
+	require.NoError(t, spool.AppendEvents(Event{"i": 0}))
+	require.NoError(t, spool.Close())
+
+	old := fmt.Sprintf("%s/%d%s", dir, time.Now().Add(-time.Hour).UnixNano(), segmentSuffix)
+	require.NoError(t, os.Rename(spool.segments[0].path, old))
+
+	spool, err = OpenSpool(dir, SetMaxSpoolAge(time.Minute))
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, spool.Close())
+	}()
+
+	assert.False(t, spool.Pending(), "expired segments must be discarded")
+	assert.NoFileExists(t, old)
+
+	require.NoError(t, spool.AppendEvents(Event{"i": 1}))
+	require.NoError(t, spool.AppendEvents(Event{"i": 2}))
+
+	// Let the segment expire while the spool is open.
+	spool.segments[0].created = time.Now().Add(-time.Hour)
+
+	res, err := spool.Replay(context.Background(), client, "test", IngestOptions{})
+	require.NoError(t, err)
+
+	assert.Zero(t, res.Ingested)
+	assert.False(t, spool.Pending())
+	assert.Empty(t, received)
+}
+
+func TestSpool_ReplayQuarantine(t *testing.T) {
+	var (
+		received []interface{}
+		requests uint64
+	)
+	hf := func(w http.ResponseWriter, r *http.Request) {
+		gzr, err := gzip.NewReader(r.Body)
+		require.NoError(t, err)
+		events := decodeEvents(t, gzr)
+
+		// The first segment is rejected permanently.
+		if atomic.AddUint64(&requests, 1) == 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		for _, event := range events {
+			received = append(received, event["i"])
+		}
+
+		_, _ = fmt.Fprintf(w, `{"ingested": %d}`, len(events))
+	}
+
+	client, teardown := setup(t, "/api/v1/datasets/test/ingest", hf)
+	defer teardown()
+
+	dir := t.TempDir()
+	spool, err := OpenSpool(dir, SetMaxSegmentSize(1))
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, spool.Close())
+	}()
+
+	require.NoError(t, spool.AppendEvents(Event{"i": 0}))
+	require.NoError(t, spool.AppendEvents(Event{"i": 1}))
+
+	quarantined := spool.segments[0].path + ".failed"
+
+	res, err := spool.Replay(context.Background(), client, "test", IngestOptions{})
+	var quarantineErr QuarantineError
+	if assert.True(t, errors.As(err, &quarantineErr)) {
+		assert.Equal(t, []string{quarantined}, quarantineErr.Paths)
+
+		var apiErr Error
+		if assert.True(t, errors.As(err, &apiErr)) {
+			assert.Equal(t, http.StatusBadRequest, apiErr.Status)
+		}
+	}
+
+	assert.EqualValues(t, 1, res.Ingested)
+	assert.Equal(t, []interface{}{float64(1)}, received)
+	assert.False(t, spool.Pending())
+	assert.FileExists(t, quarantined)
+
+	// Quarantined segments are not picked up again.
+	reopened, err := OpenSpool(dir)
+	require.NoError(t, err)
+	assert.False(t, reopened.Pending())
+}
+
+func TestIngester_Spool(t *testing.T) {
+	var (
+		received = make(map[float64]int)
+		mtx      sync.Mutex
+		requests uint64
+	)
+	hf := func(w http.ResponseWriter, r *http.Request) {
+		// Every other request fails.
+		if atomic.AddUint64(&requests, 1)%2 == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		gzr, err := gzip.NewReader(r.Body)
+		require.NoError(t, err)
+
+		events := decodeEvents(t, gzr)
+		mtx.Lock()
+		for _, event := range events {
+			received[event["i"].(float64)]++
+		}
+		mtx.Unlock()
+
+		_, _ = fmt.Fprintf(w, `{"ingested": %d}`, len(events))
+	}
+
+	client, teardown := setup(t, "/api/v1/datasets/test/ingest", hf)
+	defer teardown()
+
+	spool, err := OpenSpool(t.TempDir())
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, spool.Close())
+	}()
+
+	var handlerCalled uint64
+	ingester, err := NewIngester(client, "test",
+		SetBatchSize(5),
+		SetFlushInterval(20*time.Millisecond),
+		SetSpool(spool),
+		SetErrorHandler(func(error, *IngestStatus, []Event) {
+			atomic.AddUint64(&handlerCalled, 1)
+		}),
+	)
+	require.NoError(t, err)
+
+	for i := 0; i < 50; i++ {
+		require.NoError(t, ingester.Ingest(context.Background(), Event{"i": i}))
+	}
+
+	// Wait for the spool to be drained.
+	assert.Eventually(t, func() bool {
+		mtx.Lock()
+		defer mtx.Unlock()
+		return len(received) == 50 && !spool.Pending()
+	}, 5*time.Second, 20*time.Millisecond)
+
+	require.NoError(t, ingester.Close(context.Background()))
+
+	mtx.Lock()
+	defer mtx.Unlock()
+	for i := 0; i < 50; i++ {
+		assert.Equal(t, 1, received[float64(i)], "event %d", i)
+	}
+	assert.Zero(t, atomic.LoadUint64(&handlerCalled))
+}
+
+func TestIngester_SpoolFailure(t *testing.T) {
+	hf := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}
+
+	client, teardown := setup(t, "/api/v1/datasets/test/ingest", hf)
+	defer teardown()
+
+	dir := t.TempDir()
+	spool, err := OpenSpool(dir)
+	require.NoError(t, err)
+
+	// Remove the spool directory to make spooling fail.
+	require.NoError(t, os.RemoveAll(dir))
+
+	errCh := make(chan error, 1)
+	ingester, err := NewIngester(client, "test",
+		SetSpool(spool),
+		SetErrorHandler(func(err error, _ *IngestStatus, _ []Event) {
+			select {
+			case errCh <- err:
+			default:
+			}
+		}),
+	)
+	require.NoError(t, err)
+
+	require.NoError(t, ingester.Ingest(context.Background(), Event{"i": 0}))
+	require.NoError(t, ingester.Close(context.Background()))
+
+	select {
+	case err = <-errCh:
+	default:
+		t.Fatal("error handler not called")
+	}
+	assert.ErrorIs(t, err, ErrRateLimitExceeded)
+	assert.True(t, errors.As(err, &LimitError{}))
+	assert.Contains(t, err.Error(), "failed to spool batch")
+}
+
+func readSegment(t *testing.T, path string) []Event {
+	t.Helper()
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	gzr, err := gzip.NewReader(f)
+	require.NoError(t, err)
+
+	return decodeEvents(t, gzr)
+}
@@ -69,6 +69,8 @@ type StarredQueriesService service
 
 // List all available starred queries.
 func (s *StarredQueriesService) List(ctx context.Context, opts StarredQueriesListOptions) ([]*StarredQuery, error) {
+	ctx = withOperation(ctx, "StarredQueries.List")
+
 	path, err := addOptions(s.basePath, opts)
 	if err != nil {
 		return nil, err
@@ -84,6 +86,8 @@ func (s *StarredQueriesService) List(ctx context.Context, opts StarredQueriesLis
 
 // Get a starred query by id.
 func (s *StarredQueriesService) Get(ctx context.Context, id string) (*StarredQuery, error) {
+	ctx = withOperation(ctx, "StarredQueries.Get")
+
 	path := s.basePath + "/" + id
 
 	var res StarredQuery
@@ -96,6 +100,8 @@ func (s *StarredQueriesService) Get(ctx context.Context, id string) (*StarredQue
 
 // Create a starred query with the given properties.
 func (s *StarredQueriesService) Create(ctx context.Context, req StarredQuery) (*StarredQuery, error) {
+	ctx = withOperation(ctx, "StarredQueries.Create")
+
 	var res StarredQuery
 	if err := s.client.call(ctx, http.MethodPost, s.basePath, req, &res); err != nil {
 		return nil, err
@@ -106,6 +112,8 @@ func (s *StarredQueriesService) Create(ctx context.Context, req StarredQuery) (*
 
 // Update the starred query identified by the given id with the given properties.
 func (s *StarredQueriesService) Update(ctx context.Context, id string, req StarredQuery) (*StarredQuery, error) {
+	ctx = withOperation(ctx, "StarredQueries.Update")
+
 	path := s.basePath + "/" + id
 
 	var res StarredQuery
@@ -118,5 +126,7 @@ func (s *StarredQueriesService) Update(ctx context.Context, id string, req Starr
 
 // Delete the starred query identified by the given id.
 func (s *StarredQueriesService) Delete(ctx context.Context, id string) error {
+	ctx = withOperation(ctx, "StarredQueries.Delete")
+
 	return s.client.call(ctx, http.MethodDelete, s.basePath+"/"+id, nil, nil)
 }
@@ -0,0 +1,367 @@
+package axiom
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StructTagName is the name of the struct tag that controls how struct fields
+// are encoded by `DatasetsService.IngestStructs`. The tag value is the name of
+// the field, optionally followed by a comma-separated list of options:
+//
+//   - omitempty: The field is omitted if it has an empty value, that is false,
+//     0, a nil pointer, a nil interface, an empty array, slice, map or string
+//     or a zero `time.Time`.
+//   - time: The field is the timestamp of the event and is encoded as the
+//     `TimestampField`. It must be of type `time.Time` or `*time.Time`. At most
+//     one field of a struct can carry this option. A field named `_time` is
+//     treated the same way.
+//   - flatten: The fields of a nested struct are encoded into the parent
+//     object, prefixed with the name of the field and a dot (e.g.
+//     `http.status`). By default, nested structs are preserved as nested
+//     objects.
+//
+// A field with a tag of "-" is skipped. Fields without a name in their tag are
+// encoded by their Go field name. Embedded structs without a tag have their
+// fields promoted into the parent object.
+const StructTagName = "axiom"
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// structEncoder encodes structs of a specific type into JSON objects.
+type structEncoder struct {
+	fields []structField
+}
+
+// structField is a struct field encoded by a structEncoder.
+type structField struct {
+	// name is the key of the field, including the prefixes of flattened
+	// parents.
+	name string
+	// index is the path to the field, possibly through flattened or embedded
+	// structs.
+	index     []int
+	omitEmpty bool
+	isTime    bool
+}
+
+// structEncoderCache caches the encoders of struct types.
+var structEncoderCache sync.Map // map[reflect.Type]*structEncoderCacheEntry
+
+type structEncoderCacheEntry struct {
+	enc *structEncoder
+	err error
+}
+
+// structEncoderFor returns the cached encoder for the given struct type,
+// compiling it on first use.
+func structEncoderFor(t reflect.Type) (*structEncoder, error) {
+	if cached, ok := structEncoderCache.Load(t); ok {
+		entry := cached.(*structEncoderCacheEntry)
+		return entry.enc, entry.err
+	}
+
+	enc := new(structEncoder)
+	err := enc.compile(t, nil, "", map[reflect.Type]bool{t: true})
+	if err == nil {
+		err = enc.check(t)
+	}
+	if err != nil {
+		enc = nil
+	}
+
+	cached, _ := structEncoderCache.LoadOrStore(t, &structEncoderCacheEntry{enc: enc, err: err})
+	entry := cached.(*structEncoderCacheEntry)
+
+	return entry.enc, entry.err
+}
+
+// compile adds the fields of the given struct type to the encoder. The index
+// and prefix are those of the parent field when compiling a flattened or
+// embedded struct. Visited holds the struct types currently being compiled to
+// reject recursive flattening.
+func (e *structEncoder) compile(t reflect.Type, index []int, prefix string, visited map[reflect.Type]bool) error {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+
+		tag, hasTag := sf.Tag.Lookup(StructTagName)
+		parts := strings.Split(tag, ",")
+		name := parts[0]
+		if name == "-" && len(parts) == 1 {
+			continue
+		}
+
+		var omitEmpty, isTime, flatten bool
+		for _, opt := range parts[1:] {
+			switch opt {
+			case "omitempty":
+				omitEmpty = true
+			case "time":
+				isTime = true
+			case "flatten":
+				flatten = true
+			default:
+				return fmt.Errorf("field %q of %s: unknown tag option %q", sf.Name, t, opt)
+			}
+		}
+
+		ft := sf.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+
+		fieldIndex := make([]int, len(index)+1)
+		copy(fieldIndex, index)
+		fieldIndex[len(index)] = i
+
+		// Embedded structs without a tag have their fields promoted.
+		if sf.Anonymous && !hasTag && ft.Kind() == reflect.Struct {
+			if visited[ft] {
+				return fmt.Errorf("field %q of %s: recursive embedding of %s", sf.Name, t, ft)
+			}
+			visited[ft] = true
+			if err := e.compile(ft, fieldIndex, prefix, visited); err != nil {
+				return err
+			}
+			delete(visited, ft)
+			continue
+		} else if sf.PkgPath != "" {
+			// Unexported.
+			continue
+		}
+
+		if name == "" {
+			name = sf.Name
+		}
+
+		if name == TimestampField && prefix == "" {
+			isTime = true
+		}
+		if isTime {
+			if prefix != "" {
+				return fmt.Errorf("field %q of %s: time field must not be part of a flattened struct", sf.Name, t)
+			} else if ft != timeType {
+				return fmt.Errorf("field %q of %s: time field must be of type time.Time, got %s", sf.Name, t, sf.Type)
+			}
+		}
+
+		if flatten {
+			if ft.Kind() != reflect.Struct || ft == timeType || isMarshaler(sf.Type) {
+				return fmt.Errorf("field %q of %s: only plain structs can be flattened, got %s", sf.Name, t, sf.Type)
+			} else if visited[ft] {
+				return fmt.Errorf("field %q of %s: recursive flattening of %s", sf.Name, t, ft)
+			}
+			visited[ft] = true
+			if err := e.compile(ft, fieldIndex, prefix+name+".", visited); err != nil {
+				return err
+			}
+			delete(visited, ft)
+			continue
+		}
+
+		e.fields = append(e.fields, structField{
+			name:      prefix + name,
+			index:     fieldIndex,
+			omitEmpty: omitEmpty,
+			isTime:    isTime,
+		})
+	}
+
+	return nil
+}
+
+// check makes sure the compiled encoder produces unique keys and has at most
+// one time field.
+func (e *structEncoder) check(t reflect.Type) error {
+	var (
+		names     = make(map[string]bool, len(e.fields))
+		timeField string
+	)
+	for _, f := range e.fields {
+		if names[f.name] {
+			return fmt.Errorf("%s: duplicate field %q", t, f.name)
+		}
+		names[f.name] = true
+
+		if f.isTime {
+			if timeField != "" {
+				return fmt.Errorf("%s: multiple time fields %q and %q", t, timeField, f.name)
+			}
+			timeField = f.name
+		}
+	}
+	return nil
+}
+
+// encode appends the given struct value as a JSON object to the buffer. The
+// time field, if any, is written as the given timestamp field.
+func (e *structEncoder) encode(buf []byte, v reflect.Value, timestampField string) ([]byte, error) {
+	buf = append(buf, '{')
+
+	first := true
+	for _, f := range e.fields {
+		fv, ok := fieldByIndex(v, f.index)
+		if !ok || (f.omitEmpty && isEmptyValue(fv)) {
+			continue
+		}
+
+		name := f.name
+		if f.isTime {
+			// Leave it to the server to set the ingestion time if the event
+			// has none.
+			if isEmptyValue(fv) || (fv.Kind() == reflect.Ptr && isEmptyValue(fv.Elem())) {
+				continue
+			}
+			name = timestampField
+		}
+
+		if !first {
+			buf = append(buf, ',')
+		}
+		first = false
+
+		buf = appendString(buf, name)
+		buf = append(buf, ':')
+
+		var err error
+		if buf, err = appendStructValue(buf, fv); err != nil {
+			return buf, fmt.Errorf("field %q: %w", f.name, err)
+		}
+	}
+
+	return append(buf, '}'), nil
+}
+
+// timestamp returns the value of the time field of the given struct value, if
+// it has one which is set.
+func (e *structEncoder) timestamp(v reflect.Value) (time.Time, bool) {
+	for _, f := range e.fields {
+		if !f.isTime {
+			continue
+		}
+		if fv, ok := fieldByIndex(v, f.index); ok && !isEmptyValue(fv) {
+			return parseTimestamp(fv.Interface(), "")
+		}
+		break
+	}
+	return time.Time{}, false
+}
+
+// appendStructValue appends the given struct field value as JSON to the
+// buffer. Structs that don't implement a marshaler interface are encoded using
+// their own struct encoder.
+func appendStructValue(buf []byte, v reflect.Value) ([]byte, error) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return append(buf, "null"...), nil
+		}
+		if isMarshaler(v.Type()) {
+			break
+		}
+		v = v.Elem()
+	}
+
+	if v.Kind() == reflect.Struct && !isMarshaler(v.Type()) && !isMarshaler(reflect.PtrTo(v.Type())) {
+		enc, err := structEncoderFor(v.Type())
+		if err != nil {
+			return buf, err
+		}
+		// Nested structs keep their time field by name, only the top-level
+		// struct is mapped to the timestamp field.
+		return enc.encode(buf, v, timestampFieldOf(enc))
+	}
+
+	return appendValue(buf, v.Interface())
+}
+
+// timestampFieldOf returns the name of the time field of the given encoder or
+// the default timestamp field, if it has none.
+func timestampFieldOf(e *structEncoder) string {
+	for _, f := range e.fields {
+		if f.isTime {
+			return f.name
+		}
+	}
+	return TimestampField
+}
+
+// fieldByIndex returns the nested field of the given struct. It returns false
+// if a nil pointer to an embedded or flattened struct is encountered on the
+// way.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+func isMarshaler(t reflect.Type) bool {
+	return t.Implements(jsonMarshalerType) || t.Implements(textMarshalerType)
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	case reflect.Struct:
+		if v.Type() == timeType {
+			return v.Interface().(time.Time).IsZero()
+		}
+	}
+	return false
+}
+
+// structItems returns the elements of the given slice or array of structs or
+// pointers to structs alongside the encoder for the struct type.
+func structItems(items interface{}) (reflect.Value, *structEncoder, error) {
+	rv := reflect.ValueOf(items)
+	if k := rv.Kind(); k != reflect.Slice && k != reflect.Array {
+		return reflect.Value{}, nil, fmt.Errorf("items must be a slice of structs, got %T", items)
+	}
+
+	elemType := rv.Type().Elem()
+	if elemType.Kind() == reflect.Ptr {
+		elemType = elemType.Elem()
+	}
+	if elemType.Kind() != reflect.Struct {
+		return reflect.Value{}, nil, fmt.Errorf("items must be a slice of structs or pointers to structs, got %T", items)
+	}
+
+	enc, err := structEncoderFor(elemType)
+	if err != nil {
+		return reflect.Value{}, nil, err
+	}
+
+	for i := 0; i < rv.Len(); i++ {
+		if elem := rv.Index(i); elem.Kind() == reflect.Ptr && elem.IsNil() {
+			return reflect.Value{}, nil, fmt.Errorf("item %d is nil", i)
+		}
+	}
+
+	return rv, enc, nil
+}
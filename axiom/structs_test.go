@@ -0,0 +1,200 @@
+package axiom
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testStructMeta struct {
+	Region string `axiom:"region"`
+	Zone   string `axiom:"zone,omitempty"`
+}
+
+type testStructEmbedded struct {
+	Service string `axiom:"service"`
+}
+
+type testStructEvent struct {
+	testStructEmbedded
+
+	Timestamp  time.Time      `axiom:"ts,time"`
+	Message    string         `axiom:"msg"`
+	Status     int            `axiom:"status,omitempty"`
+	Tags       []string       `axiom:"tags,omitempty"`
+	Meta       testStructMeta `axiom:"meta,flatten"`
+	Nested     testStructMeta `axiom:"nested"`
+	Ptr        *testStructMeta
+	Skipped    string `axiom:"-"`
+	unexported string
+}
+
+func encodeStruct(t *testing.T, v interface{}, timestampField string) string {
+	t.Helper()
+
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	enc, err := structEncoderFor(rv.Type())
+	require.NoError(t, err)
+
+	buf, err := enc.encode(nil, rv, timestampField)
+	require.NoError(t, err)
+
+	return string(buf)
+}
+
+func TestStructEncoder(t *testing.T) {
+	ts := time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		input          interface{}
+		timestampField string
+		want           string
+	}{
+		{
+			name: "full",
+			input: testStructEvent{
+				testStructEmbedded: testStructEmbedded{Service: "api"},
+				Timestamp:          ts,
+				Message:            "hello",
+				Status:             200,
+				Tags:               []string{"a"},
+				Meta:               testStructMeta{Region: "eu", Zone: "a"},
+				Nested:             testStructMeta{Region: "us"},
+				Ptr:                &testStructMeta{Region: "ap"},
+				Skipped:            "skip",
+				unexported:         "skip",
+			},
+			timestampField: TimestampField,
+			want: `{"service":"api","_time":"2022-01-02T03:04:05Z","msg":"hello","status":200,"tags":["a"],` +
+				`"meta.region":"eu","meta.zone":"a","nested":{"region":"us"},"Ptr":{"region":"ap"}}`,
+		},
+		{
+			name:           "omit empty",
+			input:          &testStructEvent{Message: "hello"},
+			timestampField: TimestampField,
+			want:           `{"service":"","msg":"hello","meta.region":"","nested":{"region":""},"Ptr":null}`,
+		},
+		{
+			name:           "custom timestamp field",
+			input:          testStructEvent{Timestamp: ts},
+			timestampField: "timestamp",
+			want:           `{"service":"","timestamp":"2022-01-02T03:04:05Z","msg":"","meta.region":"","nested":{"region":""},"Ptr":null}`,
+		},
+		{
+			name: "implicit time field",
+			input: struct {
+				Time *time.Time `axiom:"_time"`
+			}{&ts},
+			timestampField: TimestampField,
+			want:           `{"_time":"2022-01-02T03:04:05Z"}`,
+		},
+		{
+			name: "flattened pointer",
+			input: struct {
+				A *testStructMeta `axiom:"a,flatten"`
+				B *testStructMeta `axiom:"b,flatten"`
+			}{A: &testStructMeta{Region: "eu"}},
+			timestampField: TimestampField,
+			want:           `{"a.region":"eu"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.JSONEq(t, tt.want, encodeStruct(t, tt.input, tt.timestampField))
+		})
+	}
+}
+
+func TestStructEncoder_Invalid(t *testing.T) {
+	type recursive struct {
+		Child *recursive `axiom:"child,flatten"`
+	}
+
+	tests := []struct {
+		name  string
+		input interface{}
+		err   string
+	}{
+		{
+			name: "unknown option",
+			input: struct {
+				A string `axiom:"a,bogus"`
+			}{},
+			err: `unknown tag option "bogus"`,
+		},
+		{
+			name: "non-time time field",
+			input: struct {
+				A string `axiom:"a,time"`
+			}{},
+			err: "time field must be of type time.Time",
+		},
+		{
+			name: "multiple time fields",
+			input: struct {
+				A time.Time `axiom:"a,time"`
+				B time.Time `axiom:"_time"`
+			}{},
+			err: "multiple time fields",
+		},
+		{
+			name: "duplicate field",
+			input: struct {
+				A string `axiom:"a"`
+				B string `axiom:"a"`
+			}{},
+			err: `duplicate field "a"`,
+		},
+		{
+			name: "flatten non-struct",
+			input: struct {
+				A string `axiom:"a,flatten"`
+			}{},
+			err: "only plain structs can be flattened",
+		},
+		{
+			name:  "recursive flatten",
+			input: recursive{},
+			err:   "recursive flattening",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := structEncoderFor(reflect.TypeOf(tt.input))
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), tt.err)
+			}
+		})
+	}
+}
+
+func TestStructEncoder_Cache(t *testing.T) {
+	typ := reflect.TypeOf(testStructEvent{})
+
+	enc1, err := structEncoderFor(typ)
+	require.NoError(t, err)
+	enc2, err := structEncoderFor(typ)
+	require.NoError(t, err)
+
+	assert.Same(t, enc1, enc2)
+}
+
+func TestStructItems(t *testing.T) {
+	_, _, err := structItems("foo")
+	assert.Error(t, err)
+
+	_, _, err = structItems([]int{1})
+	assert.Error(t, err)
+
+	_, _, err = structItems([]*testStructMeta{nil})
+	assert.EqualError(t, err, "item 0 is nil")
+
+	rv, enc, err := structItems([]*testStructMeta{{Region: "eu"}})
+	require.NoError(t, err)
+	assert.Equal(t, 1, rv.Len())
+	assert.NotNil(t, enc)
+}
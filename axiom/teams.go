@@ -35,6 +35,8 @@ type TeamsService service
 
 // List all available teams.
 func (s *TeamsService) List(ctx context.Context) ([]*Team, error) {
+	ctx = withOperation(ctx, "Teams.List")
+
 	var res []*Team
 	if err := s.client.call(ctx, http.MethodGet, s.basePath, nil, &res); err != nil {
 		return nil, err
@@ -45,6 +47,8 @@ func (s *TeamsService) List(ctx context.Context) ([]*Team, error) {
 
 // Get a team by id.
 func (s *TeamsService) Get(ctx context.Context, id string) (*Team, error) {
+	ctx = withOperation(ctx, "Teams.Get")
+
 	path := s.basePath + "/" + id
 
 	var res Team
@@ -57,6 +61,8 @@ func (s *TeamsService) Get(ctx context.Context, id string) (*Team, error) {
 
 // Create a team with the given properties.
 func (s *TeamsService) Create(ctx context.Context, req TeamCreateUpdateRequest) (*Team, error) {
+	ctx = withOperation(ctx, "Teams.Create")
+
 	var res Team
 	if err := s.client.call(ctx, http.MethodPost, s.basePath, req, &res); err != nil {
 		return nil, err
@@ -67,6 +73,8 @@ func (s *TeamsService) Create(ctx context.Context, req TeamCreateUpdateRequest)
 
 // Update the team identified by the given id with the given properties.
 func (s *TeamsService) Update(ctx context.Context, id string, req TeamCreateUpdateRequest) (*Team, error) {
+	ctx = withOperation(ctx, "Teams.Update")
+
 	path := s.basePath + "/" + id
 
 	var res Team
@@ -79,5 +87,7 @@ func (s *TeamsService) Update(ctx context.Context, id string, req TeamCreateUpda
 
 // Delete the team identified by the given id.
 func (s *TeamsService) Delete(ctx context.Context, id string) error {
+	ctx = withOperation(ctx, "Teams.Delete")
+
 	return s.client.call(ctx, http.MethodDelete, s.basePath+"/"+id, nil, nil)
 }
@@ -110,6 +110,8 @@ type tokensService service
 
 // List all available tokens.
 func (s *tokensService) List(ctx context.Context) ([]*Token, error) {
+	ctx = withOperation(ctx, s.operation("List"))
+
 	var res []*Token
 	if err := s.client.call(ctx, http.MethodGet, s.basePath, nil, &res); err != nil {
 		return nil, err
@@ -124,6 +126,8 @@ func (s *tokensService) List(ctx context.Context) ([]*Token, error) {
 
 // Get a token by id.
 func (s *tokensService) Get(ctx context.Context, id string) (*Token, error) {
+	ctx = withOperation(ctx, s.operation("Get"))
+
 	path := s.basePath + "/" + id
 
 	var res Token
@@ -138,6 +142,8 @@ func (s *tokensService) Get(ctx context.Context, id string) (*Token, error) {
 
 // View a raw token secret by id.
 func (s *tokensService) View(ctx context.Context, id string) (*RawToken, error) {
+	ctx = withOperation(ctx, s.operation("View"))
+
 	path := s.basePath + "/" + id + "/token"
 
 	var res RawToken
@@ -152,6 +158,8 @@ func (s *tokensService) View(ctx context.Context, id string) (*RawToken, error)
 
 // Create a token with the given properties.
 func (s *tokensService) Create(ctx context.Context, req TokenCreateUpdateRequest) (*Token, error) {
+	ctx = withOperation(ctx, s.operation("Create"))
+
 	prepareTokenCreateUpdateRequest(s.basePath, &req)
 
 	var res Token
@@ -166,6 +174,8 @@ func (s *tokensService) Create(ctx context.Context, req TokenCreateUpdateRequest
 
 // Update the token identified by the given id with the given properties.
 func (s *tokensService) Update(ctx context.Context, id string, req TokenCreateUpdateRequest) (*Token, error) {
+	ctx = withOperation(ctx, s.operation("Update"))
+
 	prepareTokenCreateUpdateRequest(s.basePath, &req)
 
 	path := s.basePath + "/" + id
@@ -182,6 +192,8 @@ func (s *tokensService) Update(ctx context.Context, id string, req TokenCreateUp
 
 // Delete the token identified by the given id.
 func (s *tokensService) Delete(ctx context.Context, id string) error {
+	ctx = withOperation(ctx, s.operation("Delete"))
+
 	return s.client.call(ctx, http.MethodDelete, s.basePath+"/"+id, nil, nil)
 }
 
@@ -115,6 +115,8 @@ type UsersService service
 
 // Current retrieves the authenticated user.
 func (s *UsersService) Current(ctx context.Context) (*AuthenticatedUser, error) {
+	ctx = withOperation(ctx, "Users.Current")
+
 	path := "/api/v1/user"
 
 	var res AuthenticatedUser
@@ -127,6 +129,8 @@ func (s *UsersService) Current(ctx context.Context) (*AuthenticatedUser, error)
 
 // List all available users.
 func (s *UsersService) List(ctx context.Context) ([]*User, error) {
+	ctx = withOperation(ctx, "Users.List")
+
 	var res []*User
 	if err := s.client.call(ctx, http.MethodGet, s.basePath, nil, &res); err != nil {
 		return nil, err
@@ -137,6 +141,8 @@ func (s *UsersService) List(ctx context.Context) ([]*User, error) {
 
 // Get a user by id.
 func (s *UsersService) Get(ctx context.Context, id string) (*User, error) {
+	ctx = withOperation(ctx, "Users.Get")
+
 	path := s.basePath + "/" + id
 
 	var res User
@@ -149,6 +155,8 @@ func (s *UsersService) Get(ctx context.Context, id string) (*User, error) {
 
 // Create a user with the given properties.
 func (s *UsersService) Create(ctx context.Context, req UserCreateRequest) (*User, error) {
+	ctx = withOperation(ctx, "Users.Create")
+
 	var res User
 	if err := s.client.call(ctx, http.MethodPost, s.basePath, req, &res); err != nil {
 		return nil, err
@@ -159,6 +167,8 @@ func (s *UsersService) Create(ctx context.Context, req UserCreateRequest) (*User
 
 // Update the user identified by the given id with the given properties.
 func (s *UsersService) Update(ctx context.Context, id string, req UserUpdateRequest) (*User, error) {
+	ctx = withOperation(ctx, "Users.Update")
+
 	path := s.basePath + "/" + id
 
 	var res User
@@ -172,6 +182,8 @@ func (s *UsersService) Update(ctx context.Context, id string, req UserUpdateRequ
 // UpdateRole updates the role of the user identified by the given id with the
 // given properties.
 func (s *UsersService) UpdateRole(ctx context.Context, id string, role UserRole) (*User, error) {
+	ctx = withOperation(ctx, "Users.UpdateRole")
+
 	path := s.basePath + "/" + id + "/role"
 
 	var res User
@@ -184,5 +196,7 @@ func (s *UsersService) UpdateRole(ctx context.Context, id string, role UserRole)
 
 // Delete the user identified by the given id.
 func (s *UsersService) Delete(ctx context.Context, id string) error {
+	ctx = withOperation(ctx, "Users.Delete")
+
 	return s.client.call(ctx, http.MethodDelete, s.basePath+"/"+id, nil, nil)
 }
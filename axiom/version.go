@@ -18,6 +18,8 @@ type VersionService service
 
 // Get the version of a deployment.
 func (s *VersionService) Get(ctx context.Context) (string, error) {
+	ctx = withOperation(ctx, "Version.Get")
+
 	var res version
 	if err := s.client.call(ctx, http.MethodGet, s.basePath, nil, &res); err != nil {
 		return "", err
@@ -36,6 +36,8 @@ type VirtualFieldsService service
 
 // List all available virtual fields.
 func (s *VirtualFieldsService) List(ctx context.Context, opts VirtualFieldListOptions) ([]*VirtualField, error) {
+	ctx = withOperation(ctx, "VirtualFields.List")
+
 	path, err := addOptions(s.basePath, opts)
 	if err != nil {
 		return nil, err
@@ -51,6 +53,8 @@ func (s *VirtualFieldsService) List(ctx context.Context, opts VirtualFieldListOp
 
 // Get a virtual field by id.
 func (s *VirtualFieldsService) Get(ctx context.Context, id string) (*VirtualField, error) {
+	ctx = withOperation(ctx, "VirtualFields.Get")
+
 	path := s.basePath + "/" + id
 
 	var res VirtualField
@@ -63,6 +67,8 @@ func (s *VirtualFieldsService) Get(ctx context.Context, id string) (*VirtualFiel
 
 // Create a virtual field with the given properties.
 func (s *VirtualFieldsService) Create(ctx context.Context, req VirtualField) (*VirtualField, error) {
+	ctx = withOperation(ctx, "VirtualFields.Create")
+
 	var res VirtualField
 	if err := s.client.call(ctx, http.MethodPost, s.basePath, req, &res); err != nil {
 		return nil, err
@@ -73,6 +79,8 @@ func (s *VirtualFieldsService) Create(ctx context.Context, req VirtualField) (*V
 
 // Update the virtual field identified by the given id with the given properties.
 func (s *VirtualFieldsService) Update(ctx context.Context, id string, req VirtualField) (*VirtualField, error) {
+	ctx = withOperation(ctx, "VirtualFields.Update")
+
 	path := s.basePath + "/" + id
 
 	var res VirtualField
@@ -85,5 +93,7 @@ func (s *VirtualFieldsService) Update(ctx context.Context, id string, req Virtua
 
 // Delete the virtual field identified by the given id.
 func (s *VirtualFieldsService) Delete(ctx context.Context, id string) error {
+	ctx = withOperation(ctx, "VirtualFields.Delete")
+
 	return s.client.call(ctx, http.MethodDelete, s.basePath+"/"+id, nil, nil)
 }
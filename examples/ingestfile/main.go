@@ -1,5 +1,6 @@
-// The purpose of this example is to show how to stream the contents of a JSON
-// logfile and gzip them on the fly.
+// The purpose of this example is to show how to stream the contents of a
+// logfile and gzip them on the fly. The format and compression of the file are
+// detected automatically.
 package main
 
 import (
@@ -27,27 +28,22 @@ func main() {
 	}
 	defer f.Close()
 
-	// 2. Wrap it in a gzip enabled reader.
-	r, err := axiom.GzipEncoder(f)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	// 3. Initialize the Axiom API client.
+	// 2. Initialize the Axiom API client.
 	client, err := axiom.NewClient()
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	// 4. Ingest ⚡
-	// Note the JSON content type and Gzip content encoding being set because
-	// the client does not auto sense them.
-	res, err := client.Datasets.Ingest(context.Background(), dataset, r, axiom.JSON, axiom.Gzip, axiom.IngestOptions{})
+	// 3. Ingest ⚡
+	// Note that the content type (JSON, NDJSON or CSV) and encoding (gzip or
+	// zstd, if already compressed) of the file are sensed by the client.
+	// Uncompressed files are gzip compressed on the fly.
+	res, err := client.Datasets.IngestReader(context.Background(), dataset, f, axiom.IngestOptions{})
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	// 5. Make sure everything went smoothly.
+	// 4. Make sure everything went smoothly.
 	for _, fail := range res.Failures {
 		log.Print(fail.Error)
 	}